@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// defaultInventoryTimeout is the timeout of inventory requests.
+const defaultInventoryTimeout = 30 * time.Second
+
+// inventoryCommand is an Ansible inventory script fetching the inventory from
+// a cfg8er server. Ansible runs inventory scripts with --list or --host, so
+// it's used from a script such as:
+//
+//	#!/bin/sh
+//	exec cfg8er-server inventory --url https://cfg8er.example.com/ansible/repo/v1 "$@"
+var inventoryCommand = cli.Command{
+	Name:  "inventory",
+	Usage: "Ansible inventory script for an inventory served by cfg8er",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:   "url, u",
+			Usage:  "URL of the inventory, eg. http://127.0.0.1:8080/ansible/repo/v1",
+			EnvVar: "CFG8ER_INVENTORY_URL",
+		},
+		cli.BoolFlag{
+			Name:  "list",
+			Usage: "Output every group and host",
+		},
+		cli.StringFlag{
+			Name:  "host",
+			Usage: "Output the variables of a host",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Value: defaultInventoryTimeout,
+			Usage: "Timeout of the request to the server",
+		},
+	},
+	Action: runInventory,
+}
+
+// runInventory writes the inventory fetched from the server to stdout.
+func runInventory(c *cli.Context) error {
+	inventoryURL := c.String("url")
+	if inventoryURL == "" {
+		return cli.NewExitError("The --url flag or CFG8ER_INVENTORY_URL is required", 1)
+	}
+
+	u, err := url.Parse(inventoryURL)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Invalid inventory URL: %v", err), 1)
+	}
+
+	switch {
+	case c.String("host") != "":
+		query := u.Query()
+		query.Set("host", c.String("host"))
+		u.RawQuery = query.Encode()
+	case !c.Bool("list"):
+		return cli.NewExitError("Either --list or --host is required", 1)
+	}
+
+	client := &http.Client{Timeout: c.Duration("timeout")}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return cli.NewExitError(fmt.Sprintf("Fetching inventory %s: %s", u, resp.Status), 1)
+	}
+
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	return nil
+}
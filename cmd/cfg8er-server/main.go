@@ -38,6 +38,7 @@ func main() {
 			},
 			Action: serve.Run,
 		},
+		inventoryCommand,
 	}
 
 	err := app.Run(os.Args)
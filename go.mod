@@ -1,7 +1,7 @@
 module github.com/cfg8er/cfg8er
 
 require (
-	github.com/BurntSushi/toml v0.3.0 // indirect
+	github.com/BurntSushi/toml v0.3.0
 	github.com/Masterminds/semver v1.4.2
 	github.com/alcortesm/tgz v0.0.0-20161220082320-9c5fe88206d7 // indirect
 	github.com/anmitsu/go-shlex v0.0.0-20161002113705-648efa622239 // indirect
@@ -35,7 +35,7 @@ require (
 	github.com/stretchr/testify v1.2.2 // indirect
 	github.com/ugorji/go v1.1.1 // indirect
 	github.com/xanzy/ssh-agent v0.2.0 // indirect
-	golang.org/x/crypto v0.0.0-20180830192347-182538f80094 // indirect
+	golang.org/x/crypto v0.0.0-20180830192347-182538f80094
 	golang.org/x/net v0.0.0-20180826012351-8a410e7b638d // indirect
 	golang.org/x/sync v0.0.0-20180314180146-1d60e4601c6f // indirect
 	golang.org/x/sys v0.0.0-20180831094639-fa5fdf94c789 // indirect
@@ -48,5 +48,5 @@ require (
 	gopkg.in/src-d/go-git.v4 v4.6.0
 	gopkg.in/urfave/cli.v1 v1.20.0
 	gopkg.in/warnings.v0 v0.1.2 // indirect
-	gopkg.in/yaml.v2 v2.2.1 // indirect
+	gopkg.in/yaml.v2 v2.2.1
 )
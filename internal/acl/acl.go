@@ -0,0 +1,138 @@
+// Package acl implements client IP address access lists, including finding
+// the client address of requests passed on by trusted proxies.
+package acl
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// List is a list of IPv4 and IPv6 networks.
+type List []*net.IPNet
+
+// Parse parses CIDRs, eg. 127.0.0.1/8 or 2001:db8::/32, into a List. Host bits
+// set in a CIDR are ignored. A plain IP address is taken as a single host.
+func Parse(cidrs []string) (List, error) {
+	l := List{}
+
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("Invalid IP address %s", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			l = append(l, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CIDR %s: %s", cidr, err)
+		}
+		l = append(l, network)
+	}
+
+	return l, nil
+}
+
+// Contains reports whether ip is in one of the networks of the List.
+func (l List) Contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range l {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP address of the client that made r. If the request
+// comes from a trusted proxy the client address is taken from the Forwarded
+// header, or failing that the X-Forwarded-For header. The forwarded addresses
+// are walked from the closest proxy back, skipping trusted proxies, so a client
+// can't spoof its address by sending the headers itself. Returns nil if the
+// address can't be determined.
+func ClientIP(r *http.Request, trustedProxies List) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+
+	if !trustedProxies.Contains(ip) {
+		return ip
+	}
+
+	forwarded := forwardedFor(r.Header)
+	if len(forwarded) == 0 {
+		forwarded = xForwardedFor(r.Header)
+	}
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip = net.ParseIP(forwarded[i])
+		if ip == nil || !trustedProxies.Contains(ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+// forwardedFor returns the for= addresses of the RFC 7239 Forwarded headers
+// in the order the proxies added them.
+func forwardedFor(header http.Header) []string {
+	addrs := []string{}
+
+	for _, value := range header["Forwarded"] {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(kv[0], "for") {
+					continue
+				}
+				addrs = append(addrs, forwardedNode(kv[1]))
+			}
+		}
+	}
+	return addrs
+}
+
+// forwardedNode strips the quotes, IPv6 brackets and port from a Forwarded
+// node, eg. "[2001:db8:cafe::17]:4711".
+func forwardedNode(node string) string {
+	node = strings.Trim(node, `"`)
+
+	if strings.HasPrefix(node, "[") {
+		if i := strings.Index(node, "]"); i > 0 {
+			return node[1:i]
+		}
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
+
+// xForwardedFor returns the addresses of the X-Forwarded-For headers in the
+// order the proxies added them.
+func xForwardedFor(header http.Header) []string {
+	addrs := []string{}
+
+	for _, value := range header["X-Forwarded-For"] {
+		for _, addr := range strings.Split(value, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
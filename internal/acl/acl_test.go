@@ -0,0 +1,124 @@
+package acl
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidrs   []string
+		wantLen int
+		wantErr bool
+	}{
+		{name: "IPv4 CIDR with host bits", cidrs: []string{"127.0.0.1/8"}, wantLen: 1},
+		{name: "IPv6 CIDR", cidrs: []string{"2001:db8::/32"}, wantLen: 1},
+		{name: "Plain addresses", cidrs: []string{"10.0.0.5", "::1"}, wantLen: 2},
+		{name: "Empty", cidrs: []string{}, wantLen: 0},
+		{name: "Invalid CIDR", cidrs: []string{"10.0.0.0/33"}, wantErr: true},
+		{name: "Invalid address", cidrs: []string{"example.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.cidrs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("Parse() = %v, want %v networks", got, tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestList_Contains(t *testing.T) {
+	l, _ := Parse([]string{"127.0.0.1/8", "2001:db8::/32", "192.0.2.10"})
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "127.0.0.1", want: true},
+		{ip: "127.255.0.9", want: true},
+		{ip: "128.0.0.1", want: false},
+		{ip: "2001:db8:1::1", want: true},
+		{ip: "2001:db9::1", want: false},
+		{ip: "192.0.2.10", want: true},
+		{ip: "192.0.2.11", want: false},
+		{ip: "::ffff:127.0.0.1", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := l.Contains(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("List.Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if l.Contains(nil) {
+		t.Errorf("List.Contains(nil) = true, want false")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, _ := Parse([]string{"10.0.0.0/8", "fd00::/8"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		want       string
+	}{
+		{
+			name:       "Direct client",
+			remoteAddr: "192.0.2.1:51000",
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "Untrusted client sending X-Forwarded-For",
+			remoteAddr: "192.0.2.1:51000",
+			header:     http.Header{"X-Forwarded-For": {"127.0.0.1"}},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "Trusted proxy with X-Forwarded-For",
+			remoteAddr: "10.0.0.2:51000",
+			header:     http.Header{"X-Forwarded-For": {"127.0.0.1, 192.0.2.7, 10.0.0.3"}},
+			want:       "192.0.2.7",
+		},
+		{
+			name:       "Trusted proxy with Forwarded",
+			remoteAddr: "10.0.0.2:51000",
+			header:     http.Header{"Forwarded": {`for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"`}},
+			want:       "2001:db8:cafe::17",
+		},
+		{
+			name:       "Forwarded takes precedence over X-Forwarded-For",
+			remoteAddr: "[fd00::1]:51000",
+			header:     http.Header{"Forwarded": {"for=192.0.2.60"}, "X-Forwarded-For": {"192.0.2.61"}},
+			want:       "192.0.2.60",
+		},
+		{
+			name:       "Trusted proxy without forwarding headers",
+			remoteAddr: "10.0.0.2:51000",
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "Trusted proxy forwarding an obfuscated client",
+			remoteAddr: "10.0.0.2:51000",
+			header:     http.Header{"Forwarded": {"for=unknown"}},
+			want:       "<nil>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &http.Request{RemoteAddr: tt.remoteAddr, Header: tt.header}
+			if got := ClientIP(r, trusted); got.String() != tt.want {
+				t.Errorf("ClientIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
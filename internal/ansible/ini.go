@@ -0,0 +1,308 @@
+package ansible
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+)
+
+// portVar is the host variable set by the port of a host pattern.
+const portVar = "ansible_port"
+
+// maxPatternHosts is the most hosts a host pattern may expand to.
+const maxPatternHosts = 10000
+
+// hostRange matches the first range in a host pattern, eg. [01:10] or [a:f:2].
+var hostRange = regexp.MustCompile(`\[([0-9A-Za-z]*):([0-9A-Za-z]+)(?::([0-9]+))?\]`)
+
+// numberValue matches INI values that are Python integer or float literals.
+var numberValue = regexp.MustCompile(`^-?[0-9]+(\.[0-9]*)?([eE][-+]?[0-9]+)?$`)
+
+// ParseINI parses an inventory in Ansible's INI format. Host lines before the
+// first section add hosts to the ungrouped group, [group] sections add hosts
+// to the group, [group:children] sections add child groups and [group:vars]
+// sections set group variables. Host patterns with ranges, eg. web[01:10], are
+// expanded. Like Ansible does, host variable values are parsed as Python
+// literals and group variable values are strings.
+func ParseINI(data []byte) (*Inventory, error) {
+	inv := newInventory()
+
+	section, kind := ungroupedGroup, "hosts"
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.Index(line, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("Line %d: Unterminated section %s", n, line)
+			}
+
+			section, kind = line[1:end], "hosts"
+			if i := strings.LastIndex(section, ":"); i >= 0 {
+				section, kind = section[:i], section[i+1:]
+			}
+
+			switch kind {
+			case "hosts", "children", "vars":
+			default:
+				return nil, fmt.Errorf("Line %d: Invalid section type %s", n, kind)
+			}
+
+			inv.addGroup(section)
+			continue
+		}
+
+		switch kind {
+		case "vars":
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("Line %d: Expected key=value group variable, got %s", n, line)
+			}
+			inv.groups[section].vars[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		case "children":
+			fields, err := splitINILine(line)
+			if err != nil {
+				return nil, fmt.Errorf("Line %d: %v", n, err)
+			}
+			if len(fields) > 0 {
+				inv.addChild(section, fields[0])
+			}
+		default:
+			if err := inv.parseINIHost(line, section); err != nil {
+				return nil, fmt.Errorf("Line %d: %v", n, err)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := inv.finish(); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// parseINIHost adds the hosts of a host line, a host pattern followed by
+// key=value variables, to a group.
+func (inv *Inventory) parseINIHost(line string, groupName string) error {
+	fields, err := splitINILine(line)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+
+	pattern, port := splitPort(fields[0])
+	names, err := expandHostPattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	vars := map[string]interface{}{}
+	if port != "" {
+		vars[portVar], _ = strconv.ParseInt(port, 10, 64)
+	}
+	for _, field := range fields[1:] {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("Expected key=value host variable, got %s", field)
+		}
+		vars[parts[0]] = parseINIValue(parts[1])
+	}
+
+	for _, name := range names {
+		h := inv.addHost(name, groupName)
+		for key, value := range vars {
+			h.vars[key] = value
+		}
+	}
+	return nil
+}
+
+// splitINILine splits a line into fields separated by whitespace, as by
+// Python's shlex. Quotes group characters into a field and a # starts a
+// comment.
+func splitINILine(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField := false
+	var quote rune
+	escaped := false
+
+	for _, c := range line {
+		switch {
+		case escaped:
+			field.WriteRune(c)
+			escaped = false
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				escaped = true
+			} else {
+				field.WriteRune(c)
+			}
+		case c == '\\':
+			escaped, inField = true, true
+		case c == '"' || c == '\'':
+			quote, inField = c, true
+		case c == ' ' || c == '\t':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		case c == '#' && !inField:
+			return fields, nil
+		default:
+			field.WriteRune(c)
+			inField = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("Unterminated quote in %s", line)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// splitPort splits the port off a host pattern, eg. web1:2222. Patterns that
+// are IPv6 addresses are returned unchanged.
+func splitPort(pattern string) (string, string) {
+	i := strings.LastIndex(pattern, ":")
+	if i < 0 {
+		return pattern, ""
+	}
+
+	host, port := pattern[:i], pattern[i+1:]
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return pattern, ""
+	}
+	// Colons outside of ranges are those of IPv6 addresses
+	if strings.Contains(hostRange.ReplaceAllString(host, ""), ":") {
+		return pattern, ""
+	}
+	return host, port
+}
+
+// expandHostPattern expands the numeric and alphabetic ranges in a host
+// pattern, eg. web[01:03] to web01, web02 and web03. Ranges starting with a
+// zero are padded to the length of the start. Alphabetic ranges are between
+// two letters of the same case. Returns an error if the pattern expands to
+// more than maxPatternHosts hosts.
+func expandHostPattern(pattern string) ([]string, error) {
+	m := hostRange.FindStringSubmatchIndex(pattern)
+	if m == nil {
+		return []string{pattern}, nil
+	}
+
+	prefix, suffix := pattern[:m[0]], pattern[m[1]:]
+	start, end := pattern[m[2]:m[3]], pattern[m[4]:m[5]]
+	step := 1
+	if m[6] >= 0 {
+		step, _ = strconv.Atoi(pattern[m[6]:m[7]])
+	}
+	if step < 1 {
+		return nil, fmt.Errorf("Invalid host range step in %s", pattern)
+	}
+
+	var items []string
+	if startN, err := strconv.Atoi(orZero(start)); err == nil {
+		endN, err := strconv.Atoi(end)
+		if err != nil || endN < startN {
+			return nil, fmt.Errorf("Invalid host range in %s", pattern)
+		}
+
+		width := 0
+		if len(start) > 1 && start[0] == '0' {
+			width = len(start)
+		}
+		if (endN-startN)/step >= maxPatternHosts {
+			return nil, fmt.Errorf("Host range in %s has more than %d hosts", pattern, maxPatternHosts)
+		}
+		for i := startN; i <= endN; i += step {
+			items = append(items, fmt.Sprintf("%0*d", width, i))
+		}
+	} else {
+		if len(start) != 1 || len(end) != 1 || !sameCaseLetters(start[0], end[0]) || end < start {
+			return nil, fmt.Errorf("Invalid host range in %s", pattern)
+		}
+		for c := int(start[0]); c <= int(end[0]); c += step {
+			items = append(items, string(rune(c)))
+		}
+	}
+
+	// Expand the remaining ranges in the rest of the pattern
+	rest, err := expandHostPattern(suffix)
+	if err != nil {
+		return nil, err
+	}
+	if len(items)*len(rest) > maxPatternHosts {
+		return nil, fmt.Errorf("Host pattern %s has more than %d hosts", pattern, maxPatternHosts)
+	}
+
+	var names []string
+	for _, item := range items {
+		for _, r := range rest {
+			names = append(names, prefix+item+r)
+		}
+	}
+	return names, nil
+}
+
+// sameCaseLetters reports whether a and b are both lower case or both upper
+// case ASCII letters.
+func sameCaseLetters(a byte, b byte) bool {
+	isLower := func(c byte) bool { return c >= 'a' && c <= 'z' }
+	isUpper := func(c byte) bool { return c >= 'A' && c <= 'Z' }
+	return (isLower(a) && isLower(b)) || (isUpper(a) && isUpper(b))
+}
+
+// orZero returns s, or 0 if it's empty, as ranges may leave out their start.
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+// parseINIValue parses a value in an INI inventory as a Python literal, as
+// Ansible does. Numbers, True, False and None, quoted strings, and lists and
+// dicts in flow style are decoded. Other values are strings.
+func parseINIValue(value string) interface{} {
+	switch {
+	case value == "True":
+		return true
+	case value == "False":
+		return false
+	case value == "None":
+		return nil
+	case numberValue.MatchString(value):
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0]:
+		return value[1 : len(value)-1]
+	case strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{"):
+		if doc, err := format.Decode(format.YAML, []byte(value)); err == nil {
+			return doc
+		}
+	}
+	return value
+}
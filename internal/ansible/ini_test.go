@@ -0,0 +1,176 @@
+package ansible
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseINI(t *testing.T) {
+	data := `# Comment
+bastion ansible_host=192.0.2.1
+
+[web]
+web[01:02].example.com http_port=8080 motd="Hello world"
+db1.example.com:2222
+
+[db]
+db1.example.com
+
+[web:vars]
+proxy=proxy.example.com
+workers=4
+debug=True
+
+[prod:children]
+web
+db
+`
+
+	inv, err := ParseINI([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseINI() error = %v", err)
+	}
+
+	groups := map[string][]string{}
+	for name, g := range inv.groups {
+		groups[name] = g.hosts
+	}
+	wantGroups := map[string][]string{
+		"all":       nil,
+		"ungrouped": {"bastion"},
+		"web":       {"web01.example.com", "web02.example.com", "db1.example.com"},
+		"db":        {"db1.example.com"},
+		"prod":      nil,
+	}
+	if !reflect.DeepEqual(groups, wantGroups) {
+		t.Errorf("ParseINI() groups = %v, want %v", groups, wantGroups)
+	}
+
+	if got, want := inv.groups["all"].children, []string{"prod", "ungrouped"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseINI() all children = %v, want %v", got, want)
+	}
+	if got, want := inv.groups["prod"].children, []string{"web", "db"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseINI() prod children = %v, want %v", got, want)
+	}
+
+	wantVars := map[string]interface{}{"proxy": "proxy.example.com", "workers": "4", "debug": "True"}
+	if got := inv.groups["web"].vars; !reflect.DeepEqual(got, wantVars) {
+		t.Errorf("ParseINI() web vars = %v, want %v", got, wantVars)
+	}
+
+	wantHostVars := map[string]map[string]interface{}{
+		"bastion":           {"ansible_host": "192.0.2.1"},
+		"web01.example.com": {"http_port": int64(8080), "motd": "Hello world"},
+		"web02.example.com": {"http_port": int64(8080), "motd": "Hello world"},
+		"db1.example.com":   {"ansible_port": int64(2222)},
+	}
+	for name, want := range wantHostVars {
+		if got := inv.hosts[name].vars; !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseINI() %s vars = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParseINI_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "Unterminated section", data: "[web\n"},
+		{name: "Invalid section type", data: "[web:hosts2]\n"},
+		{name: "Invalid group variable", data: "[web:vars]\nproxy\n"},
+		{name: "Invalid group priority", data: "[web:vars]\nansible_group_priority=high\n"},
+		{name: "Invalid host variable", data: "[web]\nweb1 proxy\n"},
+		{name: "Unterminated quote", data: "[web]\nweb1 motd=\"hello\n"},
+		{name: "Invalid host range", data: "[web]\nweb[3:1]\n"},
+		{name: "Host range of digits and letters", data: "[web]\nweb[1:z]\n"},
+		{name: "Host range of lower and upper case letters", data: "[web]\nweb[A:z]\n"},
+		{name: "Host range of too many hosts", data: "[web]\nweb[0:99999999999]\n"},
+		{name: "Host pattern of too many hosts", data: "[web]\nweb[0:999]-[0:999]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseINI([]byte(tt.data)); err == nil {
+				t.Errorf("ParseINI() error = %v, wantErr true", err)
+			}
+		})
+	}
+}
+
+func Test_expandHostPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+	}{
+		{name: "No range", pattern: "web1", want: []string{"web1"}},
+		{name: "Numeric", pattern: "web[1:3]", want: []string{"web1", "web2", "web3"}},
+		{name: "Padded", pattern: "web[08:10].lan", want: []string{"web08.lan", "web09.lan", "web10.lan"}},
+		{name: "Step", pattern: "web[0:6:3]", want: []string{"web0", "web3", "web6"}},
+		{name: "Without start", pattern: "web[:1]", want: []string{"web0", "web1"}},
+		{name: "Alphabetic", pattern: "db-[a:c]", want: []string{"db-a", "db-b", "db-c"}},
+		{name: "Alphabetic step past the end", pattern: "db-[x:z:200]", want: []string{"db-x"}},
+		{name: "Upper case alphabetic", pattern: "db-[Y:Z]", want: []string{"db-Y", "db-Z"}},
+		{name: "Several ranges", pattern: "r[1:2]-[a:b]", want: []string{"r1-a", "r1-b", "r2-a", "r2-b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandHostPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("expandHostPattern() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandHostPattern() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_splitPort(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		wantHost string
+		wantPort string
+	}{
+		{pattern: "web1", wantHost: "web1"},
+		{pattern: "web1:2222", wantHost: "web1", wantPort: "2222"},
+		{pattern: "web[1:3]:22", wantHost: "web[1:3]", wantPort: "22"},
+		{pattern: "web[1:3]", wantHost: "web[1:3]"},
+		{pattern: "2001:db8::1", wantHost: "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			host, port := splitPort(tt.pattern)
+			if host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("splitPort() = %v, %v, want %v, %v", host, port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func Test_parseINIValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  interface{}
+	}{
+		{value: "web", want: "web"},
+		{value: "42", want: int64(42)},
+		{value: "-1.5", want: -1.5},
+		{value: "True", want: true},
+		{value: "False", want: false},
+		{value: "true", want: "true"},
+		{value: "None", want: nil},
+		{value: `"42"`, want: "42"},
+		{value: "'a b'", want: "a b"},
+		{value: "['a', 1]", want: []interface{}{"a", 1}},
+		{value: "{'a': 1}", want: map[string]interface{}{"a": 1}},
+		{value: "[unterminated", want: "[unterminated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseINIValue(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseINIValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
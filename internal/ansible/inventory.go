@@ -0,0 +1,320 @@
+// Package ansible builds Ansible dynamic inventories from an inventory file and
+// the group_vars and host_vars directories next to it in a repo.
+package ansible
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/cfg8er/cfg8er/internal/merge"
+)
+
+// Names of the groups every inventory has.
+const (
+	allGroup       = "all"
+	ungroupedGroup = "ungrouped"
+)
+
+// Values of a repo's ansible_hash_behaviour, as Ansible's hash_behaviour
+// setting.
+const (
+	HashReplace = "replace"
+	HashMerge   = "merge"
+)
+
+// groupPriorityVar is the group variable ordering groups of the same depth.
+const groupPriorityVar = "ansible_group_priority"
+
+// Inventory is the groups and hosts of an Ansible inventory with their
+// variables.
+type Inventory struct {
+	groups map[string]*group
+	hosts  map[string]*host
+	opts   *merge.Options
+}
+
+// group is a group of hosts and other groups.
+type group struct {
+	name     string
+	hosts    []string
+	children []string
+	parents  []string
+	// vars are the variables set in the inventory file and fileVars those
+	// in group_vars.
+	vars     map[string]interface{}
+	fileVars map[string]interface{}
+	// priority is the group's ansible_group_priority.
+	priority int64
+}
+
+// host is a host in one or more groups.
+type host struct {
+	name   string
+	groups []string
+	// vars are the variables set in the inventory file and fileVars those
+	// in host_vars.
+	vars     map[string]interface{}
+	fileVars map[string]interface{}
+}
+
+// MergeOptions returns the merge.Options combining variables for an Ansible
+// hash_behaviour, replace if it's empty.
+func MergeOptions(hashBehaviour string) (*merge.Options, error) {
+	switch hashBehaviour {
+	case "", HashReplace:
+		return merge.NewOptions(merge.Override, merge.ListsReplace)
+	case HashMerge:
+		return merge.NewOptions(merge.Deep, merge.ListsReplace)
+	default:
+		return nil, fmt.Errorf("Invalid hash behaviour %s", hashBehaviour)
+	}
+}
+
+// newInventory returns an Inventory with only the all and ungrouped groups.
+func newInventory() *Inventory {
+	inv := &Inventory{groups: map[string]*group{}, hosts: map[string]*host{}}
+	inv.addGroup(allGroup)
+	inv.addGroup(ungroupedGroup)
+	return inv
+}
+
+// addGroup returns the group with a given name, adding it if it doesn't exist.
+func (inv *Inventory) addGroup(name string) *group {
+	g, ok := inv.groups[name]
+	if !ok {
+		g = &group{name: name, vars: map[string]interface{}{}}
+		inv.groups[name] = g
+	}
+	return g
+}
+
+// addHost returns the host with a given name, adding it if it doesn't exist,
+// and adds it to a group.
+func (inv *Inventory) addHost(name string, groupName string) *host {
+	h, ok := inv.hosts[name]
+	if !ok {
+		h = &host{name: name, vars: map[string]interface{}{}}
+		inv.hosts[name] = h
+	}
+
+	if !containsString(h.groups, groupName) {
+		h.groups = append(h.groups, groupName)
+		g := inv.addGroup(groupName)
+		g.hosts = append(g.hosts, name)
+	}
+	return h
+}
+
+// addChild makes a group the child of another, adding either if it doesn't
+// exist.
+func (inv *Inventory) addChild(parentName string, childName string) {
+	parent := inv.addGroup(parentName)
+	child := inv.addGroup(childName)
+
+	if !containsString(parent.children, childName) {
+		parent.children = append(parent.children, childName)
+		child.parents = append(child.parents, parentName)
+	}
+}
+
+// finish adds the hosts that aren't in any group but the all group to the
+// ungrouped group and the groups without a parent to the all group, as Ansible
+// does, and parses the ansible_group_priority of every group.
+func (inv *Inventory) finish() error {
+	for _, name := range inv.hostNames() {
+		if h := inv.hosts[name]; len(h.groups) == 1 && h.groups[0] == allGroup {
+			inv.addHost(name, ungroupedGroup)
+		}
+	}
+
+	for _, name := range inv.groupNames() {
+		if g := inv.groups[name]; name != allGroup && len(g.parents) == 0 {
+			inv.addChild(allGroup, name)
+		}
+	}
+
+	for _, g := range inv.groups {
+		var err error
+		g.priority, err = groupPriority(g)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// List returns the inventory as output by an inventory script's --list. Every
+// group has its hosts, children and variables, and the variables of every
+// host are in _meta.hostvars.
+func (inv *Inventory) List() map[string]interface{} {
+	list := map[string]interface{}{}
+
+	for name, g := range inv.groups {
+		entry := map[string]interface{}{}
+		if len(g.hosts) > 0 {
+			entry["hosts"] = g.hosts
+		}
+		if len(g.children) > 0 {
+			children := append([]string(nil), g.children...)
+			sort.Strings(children)
+			entry["children"] = children
+		}
+		if vars := inv.opts.Merge([]interface{}{g.vars, g.fileVars}); len(vars.(map[string]interface{})) > 0 {
+			entry["vars"] = vars
+		}
+		list[name] = entry
+	}
+
+	hostvars := map[string]interface{}{}
+	for name := range inv.hosts {
+		hostvars[name], _ = inv.HostVars(name)
+	}
+	list["_meta"] = map[string]interface{}{"hostvars": hostvars}
+
+	return list
+}
+
+// HostVars returns the variables of the host with a given name, as output by an
+// inventory script's --host. Variables are combined in Ansible's order of
+// precedence, each overriding the last:
+//
+//   - the all group's variables in the inventory file
+//   - the variables of the host's other groups in the inventory file
+//   - group_vars/all
+//   - group_vars of the host's other groups
+//   - the host's variables in the inventory file
+//   - host_vars of the host
+//
+// The groups are ordered by their depth below the all group, then by their
+// ansible_group_priority and their name. Returns false if there's no host with
+// the name.
+func (inv *Inventory) HostVars(name string) (map[string]interface{}, bool) {
+	h, ok := inv.hosts[name]
+	if !ok {
+		return nil, false
+	}
+
+	groups := inv.sortedGroups(h)
+	all := inv.groups[allGroup]
+
+	layers := []interface{}{all.vars}
+	for _, g := range groups {
+		layers = append(layers, g.vars)
+	}
+	layers = append(layers, all.fileVars)
+	for _, g := range groups {
+		layers = append(layers, g.fileVars)
+	}
+	layers = append(layers, h.vars, h.fileVars)
+
+	return inv.opts.Merge(layers).(map[string]interface{}), true
+}
+
+// sortedGroups returns the groups of a host and their ancestors, except for the
+// all group, in the order their variables are combined.
+func (inv *Inventory) sortedGroups(h *host) []*group {
+	ancestors := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if ancestors[name] || name == allGroup {
+			return
+		}
+		ancestors[name] = true
+		for _, parent := range inv.groups[name].parents {
+			visit(parent)
+		}
+	}
+	for _, name := range h.groups {
+		visit(name)
+	}
+
+	depths := map[string]int{}
+	var groups []*group
+	for name := range ancestors {
+		depths[name] = inv.depth(name, map[string]bool{})
+		groups = append(groups, inv.groups[name])
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if depths[a.name] != depths[b.name] {
+			return depths[a.name] < depths[b.name]
+		}
+		if a.priority != b.priority {
+			return a.priority < b.priority
+		}
+		return a.name < b.name
+	})
+	return groups
+}
+
+// depth returns the length of the longest path from the all group down to the
+// group with a given name. seen guards against cycles.
+func (inv *Inventory) depth(name string, seen map[string]bool) int {
+	if name == allGroup || seen[name] {
+		return 0
+	}
+	seen[name] = true
+	defer delete(seen, name)
+
+	depth := 0
+	for _, parent := range inv.groups[name].parents {
+		if d := inv.depth(parent, seen) + 1; d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// groupPriority parses the ansible_group_priority of a group, 1 if it's not
+// set. INI inventories set it as a string.
+func groupPriority(g *group) (int64, error) {
+	switch priority := g.vars[groupPriorityVar].(type) {
+	case nil:
+		return 1, nil
+	case int:
+		return int64(priority), nil
+	case int64:
+		return priority, nil
+	case string:
+		p, err := strconv.ParseInt(priority, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Group %s %s %s isn't an integer", g.name, groupPriorityVar, priority)
+		}
+		return p, nil
+	default:
+		return 0, fmt.Errorf("Group %s %s must be an integer, not %T", g.name, groupPriorityVar, priority)
+	}
+}
+
+// containsString reports whether s is in list.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// hostNames returns the sorted names of the hosts.
+func (inv *Inventory) hostNames() []string {
+	names := make([]string, 0, len(inv.hosts))
+	for name := range inv.hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// groupNames returns the sorted names of the groups.
+func (inv *Inventory) groupNames() []string {
+	names := make([]string, 0, len(inv.groups))
+	for name := range inv.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,221 @@
+package ansible
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newTestInventory returns an inventory with variables at every level of
+// precedence, combined as by hashBehaviour.
+func newTestInventory(t *testing.T, hashBehaviour string) *Inventory {
+	inv, err := ParseINI([]byte(`[web]
+web1 level=host
+web2
+
+[eu]
+web1
+
+[web:vars]
+level=web
+group=web
+
+[eu:vars]
+level=eu
+group=eu
+
+[all:vars]
+level=all
+only_all=1
+
+[prod:children]
+web
+
+[prod:vars]
+level=prod
+group=prod
+`))
+	if err != nil {
+		t.Fatalf("ParseINI() error = %v", err)
+	}
+
+	inv.opts, err = MergeOptions(hashBehaviour)
+	if err != nil {
+		t.Fatalf("MergeOptions() error = %v", err)
+	}
+
+	inv.groups["all"].fileVars = map[string]interface{}{"level": "group_vars/all", "ntp": map[string]interface{}{"server": "a", "iburst": true}}
+	inv.groups["web"].fileVars = map[string]interface{}{"from_file": "web"}
+	inv.hosts["web1"].fileVars = map[string]interface{}{"ntp": map[string]interface{}{"server": "b"}}
+
+	return inv
+}
+
+func TestMergeOptions(t *testing.T) {
+	for _, hashBehaviour := range []string{"", HashReplace, HashMerge} {
+		if _, err := MergeOptions(hashBehaviour); err != nil {
+			t.Errorf("MergeOptions(%q) error = %v", hashBehaviour, err)
+		}
+	}
+	if _, err := MergeOptions("append"); err == nil {
+		t.Errorf("MergeOptions() error = %v, wantErr true", err)
+	}
+}
+
+func TestInventory_HostVars(t *testing.T) {
+	tests := []struct {
+		name          string
+		hashBehaviour string
+		host          string
+		want          map[string]interface{}
+		wantOk        bool
+	}{
+		{
+			name: "Host variables win",
+			host: "web1",
+			want: map[string]interface{}{
+				"level":     "host",
+				"only_all":  "1",
+				"from_file": "web",
+				"ntp":       map[string]interface{}{"server": "b"},
+				// eu and prod are at depth 1, web at depth 2 so its
+				// variables win
+				"group": "web",
+			},
+			wantOk: true,
+		},
+		{
+			name:          "Merged hashes",
+			hashBehaviour: HashMerge,
+			host:          "web1",
+			want: map[string]interface{}{
+				"level":     "host",
+				"only_all":  "1",
+				"from_file": "web",
+				"ntp":       map[string]interface{}{"server": "b", "iburst": true},
+				"group":     "web",
+			},
+			wantOk: true,
+		},
+		{
+			name: "Group variables",
+			host: "web2",
+			want: map[string]interface{}{
+				// group_vars/all wins over the groups' variables in the
+				// inventory file
+				"level":     "group_vars/all",
+				"only_all":  "1",
+				"from_file": "web",
+				"ntp":       map[string]interface{}{"server": "a", "iburst": true},
+				"group":     "web",
+			},
+			wantOk: true,
+		},
+		{
+			name: "Missing host",
+			host: "web3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := newTestInventory(t, tt.hashBehaviour)
+
+			got, ok := inv.HostVars(tt.host)
+			if ok != tt.wantOk {
+				t.Fatalf("Inventory.HostVars() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Inventory.HostVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInventory_HostVars_groupPriority(t *testing.T) {
+	const inventory = `[web]
+web1
+
+[eu]
+web1
+
+[prod:children]
+web
+
+[eu:vars]
+group=eu
+
+[prod:vars]
+group=prod
+`
+	tests := []struct {
+		name string
+		vars string
+		want string
+	}{
+		{
+			name: "Same priority",
+			want: "prod",
+		},
+		{
+			name: "Higher priority",
+			vars: "[eu:vars]\nansible_group_priority=10\n",
+			want: "eu",
+		},
+		{
+			name: "Lower priority",
+			vars: "[prod:vars]\nansible_group_priority=0\n",
+			want: "eu",
+		},
+		{
+			// eu and prod are at depth 1, web at depth 2 so its
+			// variables win
+			name: "Deeper group",
+			vars: "[eu:vars]\nansible_group_priority=10\n[web:vars]\ngroup=web\n",
+			want: "web",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, err := ParseINI([]byte(inventory + tt.vars))
+			if err != nil {
+				t.Fatalf("ParseINI() error = %v", err)
+			}
+			inv.opts, err = MergeOptions(HashReplace)
+			if err != nil {
+				t.Fatalf("MergeOptions() error = %v", err)
+			}
+
+			got, _ := inv.HostVars("web1")
+			if got["group"] != tt.want {
+				t.Errorf("Inventory.HostVars() group = %v, want %v", got["group"], tt.want)
+			}
+		})
+	}
+}
+
+func TestInventory_List(t *testing.T) {
+	inv := newTestInventory(t, HashReplace)
+	list := inv.List()
+
+	want := map[string]interface{}{
+		"hosts":    []string{"web1", "web2"},
+		"children": nil,
+		"vars":     map[string]interface{}{"level": "web", "group": "web", "from_file": "web"},
+	}
+	web := list["web"].(map[string]interface{})
+	if !reflect.DeepEqual(web["hosts"], want["hosts"]) || !reflect.DeepEqual(web["vars"], want["vars"]) || web["children"] != nil {
+		t.Errorf("Inventory.List() web = %v, want %v", web, want)
+	}
+
+	all := list["all"].(map[string]interface{})
+	if got, want := all["children"], []string{"eu", "prod", "ungrouped"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Inventory.List() all children = %v, want %v", got, want)
+	}
+
+	hostvars := list["_meta"].(map[string]interface{})["hostvars"].(map[string]interface{})
+	if len(hostvars) != 2 {
+		t.Errorf("Inventory.List() hostvars = %v, want 2 hosts", hostvars)
+	}
+	if want, _ := inv.HostVars("web1"); !reflect.DeepEqual(hostvars["web1"], want) {
+		t.Errorf("Inventory.List() web1 hostvars = %v, want %v", hostvars["web1"], want)
+	}
+}
@@ -0,0 +1,186 @@
+package ansible
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/internal/merge"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+)
+
+// Names of the files and directories of an inventory.
+const (
+	inventoryFile = "inventory"
+	groupVarsDir  = "group_vars"
+	hostVarsDir   = "host_vars"
+)
+
+// ErrNoInventory is returned by Load if the directory has no inventory file.
+var ErrNoInventory = errors.New("No inventory file")
+
+// inventoryFormats are the names of inventory files, in order of preference,
+// and their parsers.
+var inventoryFormats = []struct {
+	name  string
+	parse func([]byte) (*Inventory, error)
+}{
+	{inventoryFile, ParseINI},
+	{inventoryFile + ".yml", ParseYAML},
+	{inventoryFile + ".yaml", ParseYAML},
+}
+
+// varsExtensions are the extensions of the variable files in group_vars and
+// host_vars. Files without an extension are YAML.
+var varsExtensions = map[string]format.Format{
+	"":      format.YAML,
+	".yml":  format.YAML,
+	".yaml": format.YAML,
+	".json": format.JSON,
+}
+
+// Load reads the inventory in the directory at dirPath at a version. The
+// inventory file is inventory in the INI format, or inventory.yml in the YAML
+// format. Group and host variables are read from group_vars and host_vars as
+// by Ansible: from files named after the group or host, optionally with a
+// .yml, .yaml or .json extension, or from every such file in a directory named
+// after it. Variables from several files of the same group or host are
+// combined in the order of their paths. opts combines the variables, see
+// MergeOptions. Returns ErrNoInventory if the directory doesn't exist or has
+// no inventory file.
+func Load(cloned *repository.Repository, dirPath string, v *repository.Version, opts *merge.Options) (*Inventory, error) {
+	dir, err := cloned.ListTreeAtVersion(dirPath, v, false)
+	if err == repository.ErrNotExist || err == repository.ErrNotDir {
+		return nil, ErrNoInventory
+	} else if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]string{}
+	for _, entry := range dir.Entries {
+		entries[entry.Name] = entry.Type
+	}
+
+	var inv *Inventory
+	for _, f := range inventoryFormats {
+		if entries[f.name] != "blob" {
+			continue
+		}
+
+		data, err := cloned.ReadAtVersion(path.Join(dirPath, f.name), v)
+		if err != nil {
+			return nil, err
+		}
+		if inv, err = f.parse(data); err != nil {
+			return nil, fmt.Errorf("Parse %s: %v", f.name, err)
+		}
+		break
+	}
+
+	if inv == nil {
+		return nil, ErrNoInventory
+	}
+	inv.opts = opts
+
+	if entries[groupVarsDir] == "tree" {
+		vars, err := loadVarsDir(cloned, path.Join(dirPath, groupVarsDir), v, opts, inv.groupNames())
+		if err != nil {
+			return nil, err
+		}
+		for name, g := range inv.groups {
+			g.fileVars = vars[name]
+		}
+	}
+
+	if entries[hostVarsDir] == "tree" {
+		vars, err := loadVarsDir(cloned, path.Join(dirPath, hostVarsDir), v, opts, inv.hostNames())
+		if err != nil {
+			return nil, err
+		}
+		for name, h := range inv.hosts {
+			h.fileVars = vars[name]
+		}
+	}
+
+	return inv, nil
+}
+
+// loadVarsDir reads the variable files of groups or hosts with given names in
+// a group_vars or host_vars directory, returning the combined variables of
+// each by name.
+func loadVarsDir(cloned *repository.Repository, dirPath string, v *repository.Version, opts *merge.Options, names []string) (map[string]map[string]interface{}, error) {
+	dir, err := cloned.ListTreeAtVersion(dirPath, v, true)
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[string]bool{}
+	for _, name := range names {
+		known[name] = true
+	}
+
+	files := map[string][]string{}
+	for _, entry := range dir.Entries {
+		parts := strings.Split(entry.Name, "/")
+		if entry.Type != "blob" || strings.HasPrefix(parts[len(parts)-1], ".") {
+			continue
+		}
+
+		// Files directly in the directory are named after the group or
+		// host, which may contain dots, with an optional extension. Files in
+		// subdirectories are in a directory named after it.
+		name := parts[0]
+		ext := path.Ext(entry.Name)
+		if _, ok := varsExtensions[ext]; !ok {
+			if len(parts) > 1 || !known[name] {
+				continue
+			}
+		} else if len(parts) == 1 && !known[name] {
+			name = strings.TrimSuffix(name, ext)
+		}
+
+		if known[name] {
+			files[name] = append(files[name], entry.Name)
+		}
+	}
+
+	vars := map[string]map[string]interface{}{}
+	for name, paths := range files {
+		sort.Strings(paths)
+
+		var docs []interface{}
+		for _, p := range paths {
+			data, err := cloned.ReadAtVersion(path.Join(dirPath, p), v)
+			if err != nil {
+				return nil, err
+			}
+
+			// Files named exactly after the group or host are YAML
+			f := format.YAML
+			if p != name {
+				f = varsExtensions[path.Ext(p)]
+			}
+
+			doc, err := format.Decode(f, data)
+			if err != nil {
+				return nil, fmt.Errorf("Parse %s: %v", path.Join(dirPath, p), err)
+			}
+			if doc == nil {
+				continue
+			}
+			if _, ok := doc.(map[string]interface{}); !ok {
+				return nil, fmt.Errorf("Variables in %s must be a mapping, not %T", path.Join(dirPath, p), doc)
+			}
+			docs = append(docs, doc)
+		}
+
+		if len(docs) > 0 {
+			vars[name] = opts.Merge(docs).(map[string]interface{})
+		}
+	}
+
+	return vars, nil
+}
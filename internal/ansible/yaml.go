@@ -0,0 +1,124 @@
+package ansible
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+)
+
+// ParseYAML parses an inventory in Ansible's YAML format: a mapping of group
+// names, usually just all, to groups with hosts, vars and children keys. hosts
+// maps host patterns to their variables and children maps group names to
+// groups.
+func ParseYAML(data []byte) (*Inventory, error) {
+	doc, err := format.Decode(format.YAML, data)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := newInventory()
+
+	if doc != nil {
+		groups, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Inventory must be a mapping of groups, not %T", doc)
+		}
+
+		for _, name := range mappingKeys(groups) {
+			if err := inv.parseYAMLGroup(name, groups[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := inv.finish(); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// parseYAMLGroup adds a group and its hosts, variables and children.
+func (inv *Inventory) parseYAMLGroup(name string, def interface{}) error {
+	g := inv.addGroup(name)
+	if def == nil {
+		return nil
+	}
+
+	groupDef, ok := def.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Group %s must be a mapping, not %T", name, def)
+	}
+
+	vars, err := yamlMapping(groupDef["vars"], "Variables of group "+name)
+	if err != nil {
+		return err
+	}
+	for key, value := range vars {
+		g.vars[key] = value
+	}
+
+	hosts, err := yamlMapping(groupDef["hosts"], "Hosts of group "+name)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range mappingKeys(hosts) {
+		hostVars, err := yamlMapping(hosts[pattern], "Variables of host "+pattern)
+		if err != nil {
+			return err
+		}
+
+		pattern, port := splitPort(pattern)
+		names, err := expandHostPattern(pattern)
+		if err != nil {
+			return err
+		}
+
+		for _, hostName := range names {
+			h := inv.addHost(hostName, name)
+			if port != "" {
+				h.vars[portVar] = parseINIValue(port)
+			}
+			for key, value := range hostVars {
+				h.vars[key] = value
+			}
+		}
+	}
+
+	children, err := yamlMapping(groupDef["children"], "Children of group "+name)
+	if err != nil {
+		return err
+	}
+	for _, childName := range mappingKeys(children) {
+		inv.addChild(name, childName)
+		if err := inv.parseYAMLGroup(childName, children[childName]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// yamlMapping returns v as a mapping, or nil if it's null.
+func yamlMapping(v interface{}, what string) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a mapping, not %T", what, v)
+	}
+	return m, nil
+}
+
+// mappingKeys returns the sorted keys of a mapping so hosts and groups are
+// added in the same order every time.
+func mappingKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package ansible
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	data := `all:
+  hosts:
+    bastion:
+      ansible_host: 192.0.2.1
+  vars:
+    ntp: ntp.example.com
+  children:
+    web:
+      hosts:
+        web[1:2]:
+          http_port: 8080
+        web3:2222:
+    db:
+      hosts:
+        db1:
+      vars:
+        port: 5432
+`
+
+	inv, err := ParseYAML([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	groups := map[string][]string{}
+	for name, g := range inv.groups {
+		groups[name] = g.hosts
+	}
+	wantGroups := map[string][]string{
+		"all":       {"bastion"},
+		"ungrouped": {"bastion"},
+		"web":       {"web3", "web1", "web2"},
+		"db":        {"db1"},
+	}
+	if !reflect.DeepEqual(groups, wantGroups) {
+		t.Errorf("ParseYAML() groups = %v, want %v", groups, wantGroups)
+	}
+
+	if got, want := inv.groups["all"].children, []string{"db", "web", "ungrouped"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseYAML() all children = %v, want %v", got, want)
+	}
+	if got, want := inv.groups["all"].vars, map[string]interface{}{"ntp": "ntp.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseYAML() all vars = %v, want %v", got, want)
+	}
+	if got, want := inv.groups["db"].vars, map[string]interface{}{"port": 5432}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseYAML() db vars = %v, want %v", got, want)
+	}
+	if got, want := inv.hosts["web2"].vars, map[string]interface{}{"http_port": 8080}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseYAML() web2 vars = %v, want %v", got, want)
+	}
+	if got, want := inv.hosts["web3"].vars, map[string]interface{}{"ansible_port": int64(2222)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseYAML() web3 vars = %v, want %v", got, want)
+	}
+}
+
+func TestParseYAML_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "Invalid YAML", data: "all: [\n"},
+		{name: "Not a mapping", data: "- all\n"},
+		{name: "Group not a mapping", data: "all: web\n"},
+		{name: "Hosts not a mapping", data: "all:\n  hosts: [web1]\n"},
+		{name: "Host variables not a mapping", data: "all:\n  hosts:\n    web1: 1\n"},
+		{name: "Group priority not an integer", data: "web:\n  vars:\n    ansible_group_priority: 1.5\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseYAML([]byte(tt.data)); err == nil {
+				t.Errorf("ParseYAML() error = %v, wantErr true", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+// Package cloudinit assembles the files of cloud-init's NoCloud datasource for
+// an instance from a directory in a repo, so a seedfrom URL can point at it.
+package cloudinit
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/internal/hostmap"
+	"github.com/cfg8er/cfg8er/internal/merge"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+)
+
+// Files of a NoCloud datasource.
+const (
+	MetaData      = "meta-data"
+	UserData      = "user-data"
+	VendorData    = "vendor-data"
+	NetworkConfig = "network-config"
+)
+
+// Names in a cloud-init directory.
+const (
+	// defaultDir has the files of every instance.
+	defaultDir = "default"
+	// instancesFile is a host map of the instances by instance ID.
+	instancesFile = "instances.yml"
+	// partsSuffix is the suffix of the directories of user-data and
+	// vendor-data parts.
+	partsSuffix = ".d"
+)
+
+// instanceIDKey is the key of the instance ID in meta-data.
+const instanceIDKey = "instance-id"
+
+// ErrNoInstance is returned by Find if no instance has the instance ID or MAC
+// address.
+var ErrNoInstance = errors.New("No such instance")
+
+// ErrNoFile is returned by Seed.File for names that aren't NoCloud files and
+// network-config of instances without one.
+var ErrNoFile = errors.New("No such file")
+
+// Seed is the NoCloud datasource of an instance.
+type Seed struct {
+	InstanceID string
+	cloned     *repository.Repository
+	dirPath    string
+	v          *repository.Version
+	// vars are the variables of the instance in instances.yml.
+	vars map[string]interface{}
+}
+
+// Find returns the Seed of the instance with an instance ID or MAC address in
+// the cloud-init directory at dirPath at a version. Instances are those with
+// a directory named after their instance ID, or in instances.yml, a host map
+// of instances by instance ID that finds them by their MAC address too.
+// Returns ErrNoInstance if there's no such instance.
+func Find(cloned *repository.Repository, dirPath string, v *repository.Version, selector string) (*Seed, error) {
+	hosts, err := loadInstances(cloned, path.Join(dirPath, instancesFile), v)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Seed{cloned: cloned, dirPath: dirPath, v: v}
+
+	if vars, ok := hosts.Lookup(selector, "", selector); ok {
+		s.InstanceID = vars[hostmap.HostnameVar].(string)
+		for _, key := range []string{hostmap.MACVar, hostmap.UUIDVar, hostmap.HostnameVar} {
+			delete(vars, key)
+		}
+		s.vars = vars
+		return s, nil
+	}
+
+	if selector == defaultDir || strings.HasPrefix(selector, ".") {
+		return nil, ErrNoInstance
+	}
+	_, err = cloned.ListTreeAtVersion(path.Join(dirPath, selector), v, false)
+	if err == repository.ErrNotExist || err == repository.ErrNotDir {
+		return nil, ErrNoInstance
+	} else if err != nil {
+		return nil, err
+	}
+
+	s.InstanceID = selector
+	s.vars = hosts.Defaults()
+	return s, nil
+}
+
+// File returns the contents of a NoCloud file of the instance:
+//
+//   - meta-data is default/meta-data, the variables of the instance in
+//     instances.yml and meta-data in the directory of the instance merged
+//     deeply, with the instance ID as instance-id unless they set it.
+//   - user-data and vendor-data are the parts in default and the directory of
+//     the instance, in that order. The parts of each are the file itself and
+//     the files in a directory with a .d suffix, eg. user-data.d, in the order
+//     of their names. Several parts are assembled into a multipart MIME
+//     message, and no parts are an empty file.
+//   - network-config is the file in the directory of the instance, or in
+//     default if it has none.
+//
+// Returns ErrNoFile for other names and if neither directory has a
+// network-config.
+func (s *Seed) File(name string) ([]byte, error) {
+	switch name {
+	case MetaData:
+		return s.metaData()
+	case UserData, VendorData:
+		parts, err := s.parts(name)
+		if err != nil {
+			return nil, err
+		}
+		return assemble(parts)
+	case NetworkConfig:
+		for _, dir := range []string{s.InstanceID, defaultDir} {
+			data, err := s.cloned.ReadAtVersion(path.Join(s.dirPath, dir, name), s.v)
+			if err == nil {
+				return data, nil
+			} else if err != repository.ErrNotExist {
+				return nil, err
+			}
+		}
+	}
+	return nil, ErrNoFile
+}
+
+// metaData returns the merged meta-data of the instance as YAML.
+func (s *Seed) metaData() ([]byte, error) {
+	defaults, err := s.readMetaData(defaultDir)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := s.readMetaData(s.InstanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []interface{}{map[string]interface{}{}}
+	for _, doc := range []map[string]interface{}{defaults, s.vars, instance} {
+		if doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+
+	opts, _ := merge.NewOptions(merge.Deep, merge.ListsReplace)
+	metaData := opts.Merge(docs).(map[string]interface{})
+	if _, ok := metaData[instanceIDKey]; !ok {
+		metaData[instanceIDKey] = s.InstanceID
+	}
+
+	return format.Encode(format.YAML, metaData)
+}
+
+// readMetaData returns the decoded meta-data in a directory, or nil if it has
+// none.
+func (s *Seed) readMetaData(dir string) (map[string]interface{}, error) {
+	filePath := path.Join(s.dirPath, dir, MetaData)
+	data, err := s.cloned.ReadAtVersion(filePath, s.v)
+	if err == repository.ErrNotExist {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	doc, err := format.Decode(format.YAML, data)
+	if err != nil {
+		return nil, fmt.Errorf("Parse %s: %v", filePath, err)
+	}
+	if doc == nil {
+		return nil, nil
+	}
+
+	metaData, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a mapping, not %T", filePath, doc)
+	}
+	return metaData, nil
+}
+
+// parts returns the user-data or vendor-data parts of the instance.
+func (s *Seed) parts(name string) ([]part, error) {
+	var parts []part
+
+	for _, dir := range []string{defaultDir, s.InstanceID} {
+		filePath := path.Join(s.dirPath, dir, name)
+		data, err := s.cloned.ReadAtVersion(filePath, s.v)
+		if err == nil {
+			parts = append(parts, part{name: path.Join(dir, name), data: data})
+		} else if err != repository.ErrNotExist {
+			return nil, err
+		}
+
+		partsDir, err := s.cloned.ListTreeAtVersion(filePath+partsSuffix, s.v, false)
+		if err == repository.ErrNotExist {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range partsDir.Entries {
+			if entry.Type != "blob" || strings.HasPrefix(entry.Name, ".") {
+				continue
+			}
+
+			partPath := path.Join(filePath+partsSuffix, entry.Name)
+			data, err := s.cloned.ReadAtVersion(partPath, s.v)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part{name: path.Join(dir, name+partsSuffix, entry.Name), data: data})
+		}
+	}
+
+	return parts, nil
+}
+
+// loadInstances reads the host map of instances at filePath at a version. A
+// missing host map is empty.
+func loadInstances(cloned *repository.Repository, filePath string, v *repository.Version) (*hostmap.HostMap, error) {
+	data, err := cloned.ReadAtVersion(filePath, v)
+	if err == repository.ErrNotExist {
+		return hostmap.New(nil)
+	} else if err != nil {
+		return nil, err
+	}
+
+	doc, err := format.Decode(format.YAML, data)
+	if err != nil {
+		return nil, fmt.Errorf("Parse %s: %v", filePath, err)
+	}
+
+	hosts, err := hostmap.New(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+	return hosts, nil
+}
@@ -0,0 +1,93 @@
+package cloudinit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// partTypes maps the first line prefixes of user-data parts to their MIME
+// types, as recognised by cloud-init. Longer prefixes come before the prefixes
+// they start with.
+var partTypes = []struct {
+	prefix    string
+	mediaType string
+}{
+	{"#cloud-config-archive", "text/cloud-config-archive"},
+	{"#cloud-config", "text/cloud-config"},
+	{"#cloud-boothook", "text/cloud-boothook"},
+	{"#include-once", "text/x-include-once-url"},
+	{"#include", "text/x-include-url"},
+	{"#part-handler", "text/part-handler"},
+	{"#upstart-job", "text/upstart-job"},
+	{"## template: jinja", "text/jinja2"},
+	{"#!", "text/x-shellscript"},
+}
+
+// part is a user-data or vendor-data part.
+type part struct {
+	name string
+	data []byte
+}
+
+// mediaType returns the MIME type of a part from its first line, or
+// text/plain if cloud-init wouldn't recognise it.
+func (p part) mediaType() string {
+	for _, t := range partTypes {
+		if bytes.HasPrefix(p.data, []byte(t.prefix)) {
+			return t.mediaType
+		}
+	}
+	return "text/plain"
+}
+
+// assemble returns the contents of a single part, or a multipart MIME message
+// of several parts that cloud-init handles as if each part were the whole of
+// the user-data. The boundary is derived from the parts so the same parts are
+// always assembled into the same message.
+func assemble(parts []part) ([]byte, error) {
+	switch len(parts) {
+	case 0:
+		return []byte{}, nil
+	case 1:
+		return parts[0].data, nil
+	}
+
+	digest := sha256.New()
+	for _, p := range parts {
+		fmt.Fprintf(digest, "%s\x00%d\x00", p.name, len(p.data))
+		digest.Write(p.data)
+	}
+
+	var message bytes.Buffer
+	w := multipart.NewWriter(&message)
+	if err := w.SetBoundary(fmt.Sprintf("===============%x==", digest.Sum(nil)[:12])); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&message, "Content-Type: multipart/mixed; boundary=%q\r\nMIME-Version: 1.0\r\n\r\n", w.Boundary())
+
+	for _, p := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", mime.FormatMediaType(p.mediaType(), map[string]string{"charset": "utf-8"}))
+		header.Set("MIME-Version", "1.0")
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": strings.Replace(p.name, "/", "-", -1)}))
+
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write(p.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
@@ -0,0 +1,93 @@
+package cloudinit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+)
+
+func Test_part_mediaType(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{data: "#cloud-config\npackages: [nginx]\n", want: "text/cloud-config"},
+		{data: "#cloud-config-archive\n- type: text/cloud-config\n", want: "text/cloud-config-archive"},
+		{data: "#!/bin/sh\necho hello\n", want: "text/x-shellscript"},
+		{data: "#include-once\nhttp://example.com/user-data\n", want: "text/x-include-once-url"},
+		{data: "#include\nhttp://example.com/user-data\n", want: "text/x-include-url"},
+		{data: "## template: jinja\n#cloud-config\n", want: "text/jinja2"},
+		{data: "hello\n", want: "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := (part{data: []byte(tt.data)}).mediaType(); got != tt.want {
+				t.Errorf("part.mediaType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_assemble(t *testing.T) {
+	if got, err := assemble(nil); err != nil || len(got) != 0 {
+		t.Errorf("assemble() = %q, %v, want empty", got, err)
+	}
+
+	single := []part{{name: "default/user-data", data: []byte("#cloud-config\n")}}
+	if got, err := assemble(single); err != nil || string(got) != "#cloud-config\n" {
+		t.Errorf("assemble() = %q, %v, want the part", got, err)
+	}
+
+	parts := []part{
+		{name: "default/user-data", data: []byte("#cloud-config\npackages: [nginx]\n")},
+		{name: "web1/user-data.d/10-setup.sh", data: []byte("#!/bin/sh\necho hello\n")},
+	}
+	got, err := assemble(parts)
+	if err != nil {
+		t.Fatalf("assemble() error = %v", err)
+	}
+
+	again, _ := assemble(parts)
+	if !bytes.Equal(got, again) {
+		t.Errorf("assemble() = %q, then %q", got, again)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("mail.ReadMessage() error = %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("assemble() Content-Type = %v, want multipart/mixed", msg.Header.Get("Content-Type"))
+	}
+
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct {
+		mediaType string
+		filename  string
+		data      []byte
+	}{
+		{"text/cloud-config", "default-user-data", parts[0].data},
+		{"text/x-shellscript", "web1-user-data.d-10-setup.sh", parts[1].data},
+	} {
+		p, err := r.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		if got, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type")); got != want.mediaType {
+			t.Errorf("assemble() part Content-Type = %v, want %v", got, want.mediaType)
+		}
+		if got := p.FileName(); got != want.filename {
+			t.Errorf("assemble() part filename = %v, want %v", got, want.filename)
+		}
+		if data, _ := ioutil.ReadAll(p); !bytes.Equal(data, want.data) {
+			t.Errorf("assemble() part = %q, want %q", data, want.data)
+		}
+	}
+	if _, err := r.NextPart(); err == nil {
+		t.Errorf("assemble() has more than %d parts", len(parts))
+	}
+}
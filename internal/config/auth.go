@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+// Auth returns the transport.AuthMethod used to clone and fetch the repo,
+// picked by the protocol of its URL. Returns nil if no credentials are
+// configured, in which case go-git's defaults are used.
+//
+// HTTP(S) remotes use basic auth with auth_username and a password or token
+// read from auth_password_file or the auth_password_env environment variable.
+// SSH remotes use the private key in ssh_key_file, decrypted with the optional
+// passphrase from ssh_passphrase_file or ssh_passphrase_env, or the keys held
+// by ssh-agent if ssh_agent is set. Host keys are checked against the
+// ssh_known_hosts file if set.
+func (r *Repo) Auth() (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(r.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ep.Protocol {
+	case "http", "https":
+		return r.httpAuth(ep)
+	case "ssh":
+		return r.sshAuth(ep)
+	}
+	return nil, nil
+}
+
+// httpAuth returns basic auth for an HTTP(S) remote. The username defaults to
+// the one in the URL, or git since token based logins ignore it.
+func (r *Repo) httpAuth(ep *transport.Endpoint) (transport.AuthMethod, error) {
+	password, err := readSecret(r.AuthPasswordFile, r.AuthPasswordEnv)
+	if err != nil || password == "" {
+		return nil, err
+	}
+
+	return &http.BasicAuth{Username: r.username(ep), Password: password}, nil
+}
+
+// sshAuth returns public key auth for an SSH remote from a key file or
+// ssh-agent. If only known hosts are set they're applied to go-git's default
+// SSH auth.
+func (r *Repo) sshAuth(ep *transport.Endpoint) (transport.AuthMethod, error) {
+	var auth ssh.AuthMethod
+	var err error
+
+	switch {
+	case r.SSHKeyFile != "":
+		passphrase, err := readSecret(r.SSHPassphraseFile, r.SSHPassphraseEnv)
+		if err != nil {
+			return nil, err
+		}
+		auth, err = ssh.NewPublicKeysFromFile(r.username(ep), r.SSHKeyFile, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("SSH key %s: %s", r.SSHKeyFile, err)
+		}
+	case r.SSHAgent:
+		auth, err = ssh.NewSSHAgentAuth(r.username(ep))
+		if err != nil {
+			return nil, fmt.Errorf("SSH agent: %s", err)
+		}
+	case r.SSHKnownHosts != "":
+		auth, err = ssh.DefaultAuthBuilder(r.username(ep))
+		if err != nil {
+			return nil, fmt.Errorf("SSH default auth: %s", err)
+		}
+	default:
+		return nil, nil
+	}
+
+	if r.SSHKnownHosts != "" {
+		// ssh.NewKnownHostsCallback ignores its files, so use knownhosts directly
+		callback, err := knownhosts.New(r.SSHKnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("SSH known hosts %s: %s", r.SSHKnownHosts, err)
+		}
+		if err := setHostKeyCallback(auth, callback); err != nil {
+			return nil, err
+		}
+	}
+
+	return auth, nil
+}
+
+// setHostKeyCallback sets the callback checking host keys of an SSH auth.
+func setHostKeyCallback(auth ssh.AuthMethod, callback gossh.HostKeyCallback) error {
+	switch auth := auth.(type) {
+	case *ssh.PublicKeys:
+		auth.HostKeyCallback = callback
+	case *ssh.PublicKeysCallback:
+		auth.HostKeyCallback = callback
+	case *ssh.Password:
+		auth.HostKeyCallback = callback
+	case *ssh.PasswordCallback:
+		auth.HostKeyCallback = callback
+	case *ssh.KeyboardInteractive:
+		auth.HostKeyCallback = callback
+	default:
+		return fmt.Errorf("Can't check host keys of SSH auth %T", auth)
+	}
+	return nil
+}
+
+// username returns auth_username, falling back to the user in the remote URL
+// and then to git.
+func (r *Repo) username(ep *transport.Endpoint) string {
+	switch {
+	case r.AuthUsername != "":
+		return r.AuthUsername
+	case ep.User != "":
+		return ep.User
+	}
+	return ssh.DefaultUsername
+}
+
+// readSecret reads a secret from file, or from the environment variable env if
+// no file is set. Trailing newlines are trimmed from files. Returns an empty
+// string if neither is set.
+func readSecret(file string, env string) (string, error) {
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+
+	if env != "" {
+		secret, ok := os.LookupEnv(env)
+		if !ok || secret == "" {
+			return "", fmt.Errorf("Environment variable %s is empty", env)
+		}
+		return secret, nil
+	}
+
+	return "", nil
+}
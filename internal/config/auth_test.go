@@ -0,0 +1,167 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+func TestRepo_Auth(t *testing.T) {
+	dir := t.TempDir()
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	encryptedBlock, err := x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes, []byte("passphrase"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{
+		"token":       []byte("t0ken\n"),
+		"passphrase":  []byte("passphrase\n"),
+		"id_rsa":      pem.EncodeToMemory(block),
+		"id_rsa_enc":  pem.EncodeToMemory(encryptedBlock),
+		"known_hosts": []byte("github.com ssh-rsa AAAAB3NzaC1yc2EAAAABIwAAAQEAq2A7hRGmdnm9tUDbO9IDSwBK6TbQa+PXYPCPy6rbTrTtw7PHkccKrpp0yVhp5HdEIcKr6pLlVDBfOLX9QUsyCOV0wzfjIJNlGEYsdlLJizHhbn2mUjvSAHQqZETYP81eFzLQNnPHt4EVVUh7VfDESU84KezmD5QlWpXLmvU31/yMf+Se8xhHTvKSCZIFImWwoG6mbUoWf9nzpIoaSjB+weqqUUmpaaasXVal72J+UX2B+2RPW3RcT0eOzQgqlJL3RKrTJvdsjE3JEAvGq3lGHSZXy28G3skua2SmVi/w4yCE6gbODqnTWlg7+wC604ydGXA8VJiS5ap43JXiUFFAaQ==\n"),
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), contents, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	os.Setenv("CFG8ER_TEST_TOKEN", "env-t0ken")
+	defer os.Unsetenv("CFG8ER_TEST_TOKEN")
+
+	// The agent is only dialled, not asked for keys
+	agent, err := net.Listen("unix", filepath.Join(dir, "agent.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer agent.Close()
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Setenv("SSH_AUTH_SOCK", agent.Addr().String())
+
+	tests := []struct {
+		name     string
+		repo     *Repo
+		wantType interface{}
+		wantUser string
+		wantErr  bool
+	}{
+		{
+			name: "No credentials",
+			repo: &Repo{URL: "https://github.com/cfg8er/fixture.git"},
+		},
+		{
+			name: "Local repo",
+			repo: &Repo{URL: "/srv/git/fixture.git", AuthPasswordEnv: "CFG8ER_TEST_TOKEN"},
+		},
+		{
+			name:     "HTTPS token from file",
+			repo:     &Repo{URL: "https://github.com/cfg8er/fixture.git", AuthPasswordFile: filepath.Join(dir, "token")},
+			wantType: &http.BasicAuth{},
+			wantUser: "git",
+		},
+		{
+			name:     "HTTPS password from env",
+			repo:     &Repo{URL: "https://github.com/cfg8er/fixture.git", AuthUsername: "cfg8er", AuthPasswordEnv: "CFG8ER_TEST_TOKEN"},
+			wantType: &http.BasicAuth{},
+			wantUser: "cfg8er",
+		},
+		{
+			name:    "HTTPS password from unset env",
+			repo:    &Repo{URL: "https://github.com/cfg8er/fixture.git", AuthPasswordEnv: "CFG8ER_TEST_UNSET"},
+			wantErr: true,
+		},
+		{
+			name:    "HTTPS password from missing file",
+			repo:    &Repo{URL: "https://github.com/cfg8er/fixture.git", AuthPasswordFile: filepath.Join(dir, "missing")},
+			wantErr: true,
+		},
+		{
+			name:     "SSH key",
+			repo:     &Repo{URL: "ssh://deploy@github.com/cfg8er/fixture.git", SSHKeyFile: filepath.Join(dir, "id_rsa")},
+			wantType: &ssh.PublicKeys{},
+			wantUser: "deploy",
+		},
+		{
+			name:     "SSH key with passphrase and known hosts",
+			repo:     &Repo{URL: "git@github.com:cfg8er/fixture.git", SSHKeyFile: filepath.Join(dir, "id_rsa_enc"), SSHPassphraseFile: filepath.Join(dir, "passphrase"), SSHKnownHosts: filepath.Join(dir, "known_hosts")},
+			wantType: &ssh.PublicKeys{},
+			wantUser: "git",
+		},
+		{
+			name:    "SSH key with wrong passphrase",
+			repo:    &Repo{URL: "git@github.com:cfg8er/fixture.git", SSHKeyFile: filepath.Join(dir, "id_rsa_enc"), SSHPassphraseEnv: "CFG8ER_TEST_TOKEN"},
+			wantErr: true,
+		},
+		{
+			name:     "SSH agent",
+			repo:     &Repo{URL: "git@github.com:cfg8er/fixture.git", SSHAgent: true},
+			wantType: &ssh.PublicKeysCallback{},
+			wantUser: "git",
+		},
+		{
+			name:     "SSH known hosts with the default auth",
+			repo:     &Repo{URL: "git@github.com:cfg8er/fixture.git", SSHKnownHosts: filepath.Join(dir, "known_hosts")},
+			wantType: &ssh.PublicKeysCallback{},
+			wantUser: "git",
+		},
+		{
+			name:    "SSH missing known hosts",
+			repo:    &Repo{URL: "git@github.com:cfg8er/fixture.git", SSHKeyFile: filepath.Join(dir, "id_rsa"), SSHKnownHosts: filepath.Join(dir, "missing")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.repo.Auth()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repo.Auth() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			switch auth := got.(type) {
+			case nil:
+				if tt.wantType != nil {
+					t.Errorf("Repo.Auth() = nil, want %T", tt.wantType)
+				}
+			case *http.BasicAuth:
+				if _, ok := tt.wantType.(*http.BasicAuth); !ok || auth.Username != tt.wantUser || auth.Password == "" {
+					t.Errorf("Repo.Auth() = %v, want %T for %s", auth, tt.wantType, tt.wantUser)
+				}
+			case *ssh.PublicKeys:
+				if _, ok := tt.wantType.(*ssh.PublicKeys); !ok || auth.User != tt.wantUser {
+					t.Errorf("Repo.Auth() = %v, want %T for %s", auth, tt.wantType, tt.wantUser)
+				}
+				if (auth.HostKeyCallback != nil) != (tt.repo.SSHKnownHosts != "") {
+					t.Errorf("Repo.Auth() HostKeyCallback set = %v, want %v", auth.HostKeyCallback != nil, tt.repo.SSHKnownHosts != "")
+				}
+			case *ssh.PublicKeysCallback:
+				if _, ok := tt.wantType.(*ssh.PublicKeysCallback); !ok || auth.User != tt.wantUser {
+					t.Errorf("Repo.Auth() = %v, want %T for %s", auth, tt.wantType, tt.wantUser)
+				}
+				if (auth.HostKeyCallback != nil) != (tt.repo.SSHKnownHosts != "") {
+					t.Errorf("Repo.Auth() HostKeyCallback set = %v, want %v", auth.HostKeyCallback != nil, tt.repo.SSHKnownHosts != "")
+				}
+			default:
+				t.Errorf("Repo.Auth() = %T, want %T", got, tt.wantType)
+			}
+		})
+	}
+}
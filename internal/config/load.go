@@ -1,30 +1,57 @@
 package config
 
 import (
+	"fmt"
+
 	"github.com/micro/go-config"
 	"github.com/micro/go-config/source/file"
 )
 
 // LoadConfig opens the filePath using micro/go-config to scan it onto
-// a map[String]*Repo.
+// a map[String]*Repo. Repos with enable_update_api set must have an
+// update_api_secret so webhooks can be verified.
 func LoadConfig(filePath string) (map[string]*Repo, error) {
+	repos := map[string]*Repo{}
+
+	if err := scanConfig(filePath, "repositories", &repos); err != nil {
+		return repos, err
+	}
+
+	for n, r := range repos {
+		if r.EnableUpdateAPI && r.UpdateAPISecret == "" {
+			return nil, fmt.Errorf("Repo %s: enable_update_api requires an update_api_secret", n)
+		}
+	}
+
+	return repos, nil
+}
+
+// LoadServerConfig opens the filePath using micro/go-config to scan the
+// server section onto a *Server. A missing server section results in
+// the zero value Server.
+func LoadServerConfig(filePath string) (*Server, error) {
+	server := &Server{}
+
+	if err := scanConfig(filePath, "server", server); err != nil {
+		return server, err
+	}
+
+	return server, nil
+}
+
+// scanConfig loads the filePath and scans the value at key onto v.
+func scanConfig(filePath string, key string, v interface{}) error {
 	// Create new config
 	conf := config.NewConfig()
 
-	repos := map[string]*Repo{}
-
 	// Load file source
 	f := file.WithPath(filePath)
 	s := file.NewSource(f)
 
 	if err := conf.Load(s); err != nil {
-		return repos, err
+		return err
 	}
 	defer conf.Close()
 
-	if err := conf.Get("repositories").Scan(&repos); err != nil {
-		return repos, err
-	}
-
-	return repos, nil
+	return conf.Get(key).Scan(v)
 }
@@ -34,7 +34,9 @@ func TestLoadConfig(t *testing.T) {
 				"cfg8er-fixture": {
 					URL:               "https://github.com/cfg8er/fixture.git",
 					UpdateFrequency:   600,
+					UpdateTimeout:     120,
 					EnableUpdateAPI:   false,
+					UpdateAPISecret:   "s3cret",
 					EnableSemversTags: true,
 					EnableTags:        true,
 					EnableCommits:     false,
@@ -44,6 +46,9 @@ func TestLoadConfig(t *testing.T) {
 					GpgVerifyCommit:   true,
 					GpgVerifyTag:      true,
 					GpgAllowIds:       []string{"29DF880B"},
+					GpgKeyRing:        "/etc/cfg8er/fixture.asc",
+					AuthUsername:      "cfg8er",
+					AuthPasswordEnv:   "CFG8ER_FIXTURE_TOKEN",
 				},
 			},
 			wantErr: false,
@@ -55,7 +60,9 @@ func TestLoadConfig(t *testing.T) {
 				"cfg8er-fixture": {
 					URL:               "https://github.com/cfg8er/fixture.git",
 					UpdateFrequency:   600,
+					UpdateTimeout:     120,
 					EnableUpdateAPI:   false,
+					UpdateAPISecret:   "s3cret",
 					EnableSemversTags: true,
 					EnableTags:        true,
 					EnableCommits:     false,
@@ -65,10 +72,18 @@ func TestLoadConfig(t *testing.T) {
 					GpgVerifyCommit:   true,
 					GpgVerifyTag:      true,
 					GpgAllowIds:       []string{"29DF880B"},
+					GpgKeyRing:        "/etc/cfg8er/fixture.asc",
+					AuthUsername:      "cfg8er",
+					AuthPasswordEnv:   "CFG8ER_FIXTURE_TOKEN",
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name:    "Update API without a secret",
+			args:    args{filePath: "../../fixtures/config/update_api_without_secret.yaml"},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -83,3 +98,54 @@ func TestLoadConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadServerConfig(t *testing.T) {
+	type args struct {
+		filePath string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Server
+		wantErr bool
+	}{
+		{
+			name:    "Non-existant path",
+			args:    args{filePath: "/non-existant/path"},
+			want:    &Server{},
+			wantErr: true,
+		},
+		{
+			name: "Load example yaml config",
+			args: args{filePath: "../../fixtures/config/config.yaml"},
+			want: &Server{
+				StorageDir:     "/var/lib/cfg8er",
+				AllowHosts:     []string{"10.0.0.0/8", "fd00::/8"},
+				TrustedProxies: []string{"127.0.0.1"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Load example json config",
+			args: args{filePath: "../../fixtures/config/config.json"},
+			want: &Server{
+				StorageDir:     "/var/lib/cfg8er",
+				AllowHosts:     []string{"10.0.0.0/8", "fd00::/8"},
+				TrustedProxies: []string{"127.0.0.1"},
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadServerConfig(tt.args.filePath)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadServerConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadServerConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,12 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/internal/merge"
 	"github.com/cfg8er/cfg8er/pkg/repository"
 )
 
@@ -8,17 +14,137 @@ import (
 // with the additional of tracking the cloned repo. This is the primary
 // type that is passed around the serve package.
 type Repo struct {
-	URL               string
-	UpdateFrequency   int      `json:"update_frequency"`
-	EnableUpdateAPI   bool     `json:"enable_update_api"`
-	EnableSemversTags bool     `json:"enable_semvers_tags"`
-	EnableTags        bool     `json:"enable_tags"`
-	EnableCommits     bool     `json:"enable_commits"`
-	WhitelistRefs     []string `json:"whitelist_refs"`
-	BlacklistRefs     []string `json:"blacklist_refs"`
-	AllowHosts        []string `json:"allow_hosts"`
-	GpgVerifyCommit   bool     `json:"gpg_verify_commit"`
-	GpgVerifyTag      bool     `json:"gpg_verify_tag"`
-	GpgAllowIds       []string `json:"gpg_allow_ids"`
-	ClonedRepo        repository.Repository
+	// mu guards clonedRepo, which is replaced when the repo is updated.
+	mu                   sync.RWMutex
+	clonedRepo           *repository.Repository
+	URL                  string
+	UpdateFrequency      int               `json:"update_frequency"`
+	UpdateTimeout        int               `json:"update_timeout"`
+	EnableUpdateAPI      bool              `json:"enable_update_api"`
+	UpdateAPISecret      string            `json:"update_api_secret"`
+	EnableSemversTags    bool              `json:"enable_semvers_tags"`
+	EnableTags           bool              `json:"enable_tags"`
+	EnableCommits        bool              `json:"enable_commits"`
+	WhitelistRefs        []string          `json:"whitelist_refs"`
+	BlacklistRefs        []string          `json:"blacklist_refs"`
+	AllowHosts           []string          `json:"allow_hosts"`
+	GpgVerifyCommit      bool              `json:"gpg_verify_commit"`
+	GpgVerifyTag         bool              `json:"gpg_verify_tag"`
+	GpgAllowIds          []string          `json:"gpg_allow_ids"`
+	GpgKeyRing           string            `json:"gpg_keyring"`
+	AuthUsername         string            `json:"auth_username"`
+	AuthPasswordFile     string            `json:"auth_password_file"`
+	AuthPasswordEnv      string            `json:"auth_password_env"`
+	SSHKeyFile           string            `json:"ssh_key_file"`
+	SSHPassphraseFile    string            `json:"ssh_passphrase_file"`
+	SSHPassphraseEnv     string            `json:"ssh_passphrase_env"`
+	SSHAgent             bool              `json:"ssh_agent"`
+	SSHKnownHosts        string            `json:"ssh_known_hosts"`
+	ContentTypes         map[string]string `json:"content_types"`
+	ContentDisposition   string            `json:"content_disposition"`
+	RedirectFloating     bool              `json:"redirect_floating"`
+	RedirectMaxAge       int               `json:"redirect_max_age"`
+	Merge                string            `json:"merge"`
+	MergeLists           string            `json:"merge_lists"`
+	ShellPrefix          string            `json:"shell_prefix"`
+	ShellSeparator       string            `json:"shell_separator"`
+	ShellCase            string            `json:"shell_case"`
+	AnsibleHashBehaviour string            `json:"ansible_hash_behaviour"`
+	IPXEHostMap          string            `json:"ipxe_host_map"`
+	SpringSearchPaths    []string          `json:"spring_search_paths"`
+	SpringDefaultLabel   string            `json:"spring_default_label"`
+}
+
+// ClonedRepo returns the repo's clone, or an empty Repository if it hasn't
+// been cloned yet. Updates replace the clone rather than modify it so the
+// returned clone can be read while the repo is being updated.
+func (r *Repo) ClonedRepo() *repository.Repository {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.clonedRepo == nil {
+		return &repository.Repository{}
+	}
+	return r.clonedRepo
+}
+
+// SetClonedRepo replaces the repo's clone. The clone mustn't be modified
+// afterwards.
+func (r *Repo) SetClonedRepo(cloned *repository.Repository) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clonedRepo = cloned
+}
+
+// Verifier returns the repository.Verifier for the repo's gpg_verify_tag,
+// gpg_verify_commit and gpg_allow_ids settings, reading the armored keyring
+// from the gpg_keyring path. Returns nil if no verification is enabled.
+func (r *Repo) Verifier() (*repository.Verifier, error) {
+	if !r.GpgVerifyTag && !r.GpgVerifyCommit {
+		return nil, nil
+	}
+
+	f, err := os.Open(r.GpgKeyRing)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return repository.NewVerifier(f, r.GpgAllowIds, r.GpgVerifyTag, r.GpgVerifyCommit)
+}
+
+// RefFilter returns the repository.RefFilter for the repo's whitelist_refs and
+// blacklist_refs settings. Returns nil if neither is set.
+func (r *Repo) RefFilter() (*repository.RefFilter, error) {
+	if len(r.WhitelistRefs) == 0 && len(r.BlacklistRefs) == 0 {
+		return nil, nil
+	}
+
+	return repository.NewRefFilter(r.WhitelistRefs, r.BlacklistRefs)
+}
+
+// MergeOptions returns the merge.Options for the repo's merge and merge_lists
+// settings. Returns nil if merging isn't enabled.
+func (r *Repo) MergeOptions() (*merge.Options, error) {
+	if r.Merge == "" {
+		return nil, nil
+	}
+
+	return merge.NewOptions(r.Merge, r.MergeLists)
+}
+
+// ShellOptions returns the format.ShellOptions for the repo's shell_prefix,
+// shell_separator and shell_case settings.
+func (r *Repo) ShellOptions() (*format.ShellOptions, error) {
+	return format.NewShellOptions(r.ShellPrefix, r.ShellSeparator, r.ShellCase)
+}
+
+// Configure applies the repo's verification, ref filtering, enabled ref type
+// and authentication settings to a cloned repo.
+func (r *Repo) Configure(cloned *repository.Repository) error {
+	verifier, err := r.Verifier()
+	if err != nil {
+		return fmt.Errorf("GPG keyring: %v", err)
+	}
+
+	auth, err := r.Auth()
+	if err != nil {
+		return fmt.Errorf("Auth: %v", err)
+	}
+
+	refFilter, err := r.RefFilter()
+	if err != nil {
+		return err
+	}
+
+	cloned.Verifier = verifier
+	cloned.RefFilter = refFilter
+	cloned.Auth = auth
+	cloned.RefTypes = &repository.RefTypes{
+		SemverTags: r.EnableSemversTags,
+		Tags:       r.EnableTags,
+		Commits:    r.EnableCommits,
+	}
+	return nil
 }
@@ -0,0 +1,15 @@
+package config
+
+// Server represents the server wide settings of the cfg8er-server
+// configuration file that apply to every repo.
+type Server struct {
+	// StorageDir is the directory repos are cloned into, one bare repo per
+	// repo name. Repos are cloned into memory when StorageDir is empty.
+	StorageDir string `json:"storage_dir"`
+	// AllowHosts are the CIDRs clients may connect from for repos that don't
+	// set their own allow_hosts. Clients are allowed from anywhere if empty.
+	AllowHosts []string `json:"allow_hosts"`
+	// TrustedProxies are the CIDRs of proxies whose Forwarded and
+	// X-Forwarded-For headers are used to find the client address.
+	TrustedProxies []string `json:"trusted_proxies"`
+}
@@ -0,0 +1,214 @@
+// Package format decodes and encodes structured configuration documents.
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/cfg8er/cfg8er/internal/mediatype"
+	"gopkg.in/yaml.v2"
+)
+
+// Format is a structured document format.
+type Format string
+
+// Formats of the documents that can be decoded and encoded. Shell documents
+// are flattened shell variable assignments that can only be encoded.
+const (
+	YAML  Format = "yaml"
+	JSON  Format = "json"
+	TOML  Format = "toml"
+	Shell Format = "shell"
+)
+
+// extensions maps file extensions to formats.
+var extensions = map[string]Format{
+	".yml":  YAML,
+	".yaml": YAML,
+	".json": JSON,
+	".toml": TOML,
+}
+
+// sourceExtensions are the extensions of the files a document may be converted
+// from, in order of preference.
+var sourceExtensions = []string{".yml", ".yaml", ".json", ".toml"}
+
+// mediaTypes maps media types to formats, including the unregistered media
+// types commonly used for YAML.
+var mediaTypes = map[string]Format{
+	mediatype.YAML:       YAML,
+	"application/x-yaml": YAML,
+	"text/yaml":          YAML,
+	"text/x-yaml":        YAML,
+	mediatype.JSON:       JSON,
+	mediatype.TOML:       TOML,
+	mediatype.Shell:      Shell,
+}
+
+// names maps the names of formats, and their common abbreviations, to formats.
+var names = map[string]Format{
+	"yaml":  YAML,
+	"yml":   YAML,
+	"json":  JSON,
+	"toml":  TOML,
+	"shell": Shell,
+	"sh":    Shell,
+}
+
+// FromPath returns the format of the file at filePath from its extension.
+// Returns false if the extension isn't of a known format.
+func FromPath(filePath string) (Format, bool) {
+	f, ok := extensions[strings.ToLower(path.Ext(filePath))]
+	return f, ok
+}
+
+// Alternates returns filePath followed by filePath with the extension of each
+// format swapped in for its own, the paths a document requested at filePath
+// can be converted from.
+func Alternates(filePath string) []string {
+	ext := path.Ext(filePath)
+	base := strings.TrimSuffix(filePath, ext)
+
+	paths := []string{filePath}
+	for _, alternate := range sourceExtensions {
+		if alternate != strings.ToLower(ext) {
+			paths = append(paths, base+alternate)
+		}
+	}
+	return paths
+}
+
+// FromMediaType returns the format of a media type, without parameters.
+// Returns false if it isn't the media type of a known format.
+func FromMediaType(mediaType string) (Format, bool) {
+	f, ok := mediaTypes[strings.ToLower(mediaType)]
+	return f, ok
+}
+
+// FromName returns the format with a given name, eg. yaml. Returns false if
+// there's no format with the name.
+func FromName(name string) (Format, bool) {
+	f, ok := names[strings.ToLower(name)]
+	return f, ok
+}
+
+// MediaType returns the media type documents in format f are served with.
+func (f Format) MediaType() string {
+	switch f {
+	case YAML:
+		return mediatype.YAML
+	case JSON:
+		return mediatype.JSON
+	case TOML:
+		return mediatype.TOML
+	case Shell:
+		return mediatype.Shell
+	default:
+		return mediatype.Binary
+	}
+}
+
+// Decode parses a document in format f. Mappings are decoded as
+// map[string]interface{}, sequences as []interface{} and JSON numbers as int64
+// if they are integers, float64 otherwise. TOML datetimes are decoded as
+// time.Time.
+func Decode(f Format, data []byte) (interface{}, error) {
+	var doc interface{}
+
+	switch f {
+	case YAML:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	case JSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, err
+		}
+	case TOML:
+		var table map[string]interface{}
+		if err := toml.Unmarshal(data, &table); err != nil {
+			return nil, err
+		}
+		doc = table
+	case Shell:
+		return nil, fmt.Errorf("Shell documents can't be decoded")
+	default:
+		return nil, fmt.Errorf("Unknown format %s", f)
+	}
+
+	return normalize(doc), nil
+}
+
+// Encode formats a decoded document as f. Mapping keys are sorted so the same
+// document is always encoded the same way. Only mappings can be encoded as
+// TOML, and null values in them are left out. Shell documents are encoded with
+// the DefaultShellOptions.
+func Encode(f Format, doc interface{}) ([]byte, error) {
+	switch f {
+	case YAML:
+		return yaml.Marshal(doc)
+	case JSON:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case TOML:
+		if _, ok := doc.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("TOML document must be a table, not %T", doc)
+		}
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case Shell:
+		return EncodeShell(doc, DefaultShellOptions)
+	default:
+		return nil, fmt.Errorf("Unknown format %s", f)
+	}
+}
+
+// normalize converts the YAML mappings of a decoded document to maps keyed by
+// string, the TOML arrays of tables to []interface{} and the JSON numbers to
+// int64 or float64.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalize(value)
+		}
+		return m
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalize(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalize(value)
+		}
+		return v
+	case []map[string]interface{}:
+		l := make([]interface{}, len(v))
+		for i, value := range v {
+			l[i] = normalize(value)
+		}
+		return l
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
@@ -0,0 +1,182 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFromPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		want     Format
+		wantOk   bool
+	}{
+		{name: "YAML", filePath: "/config.yml", want: YAML, wantOk: true},
+		{name: "Long YAML extension", filePath: "/config.yaml", want: YAML, wantOk: true},
+		{name: "Upper case JSON", filePath: "/config.JSON", want: JSON, wantOk: true},
+		{name: "TOML", filePath: "/config.toml", want: TOML, wantOk: true},
+		{name: "Unknown", filePath: "/boot.ipxe"},
+		{name: "No extension", filePath: "/config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FromPath(tt.filePath)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("FromPath() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestAlternates(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		want     []string
+	}{
+		{name: "JSON", filePath: "/a/config.json", want: []string{"/a/config.json", "/a/config.yml", "/a/config.yaml", "/a/config.toml"}},
+		{name: "YAML", filePath: "/a/config.yml", want: []string{"/a/config.yml", "/a/config.yaml", "/a/config.json", "/a/config.toml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Alternates(tt.filePath); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Alternates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromMediaType(t *testing.T) {
+	tests := []struct {
+		name      string
+		mediaType string
+		want      Format
+		wantOk    bool
+	}{
+		{name: "YAML", mediaType: "application/yaml", want: YAML, wantOk: true},
+		{name: "Unregistered YAML", mediaType: "text/x-yaml", want: YAML, wantOk: true},
+		{name: "Upper case JSON", mediaType: "Application/JSON", want: JSON, wantOk: true},
+		{name: "TOML", mediaType: "application/toml", want: TOML, wantOk: true},
+		{name: "HTML", mediaType: "text/html"},
+		{name: "Wildcard", mediaType: "*/*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FromMediaType(tt.mediaType)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("FromMediaType() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestFromName(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   Format
+		wantOk bool
+	}{
+		{name: "yml", want: YAML, wantOk: true},
+		{name: "JSON", want: JSON, wantOk: true},
+		{name: "shell", want: Shell, wantOk: true},
+		{name: "ini"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FromName(tt.name)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("FromName() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		data    string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:   "YAML",
+			format: YAML,
+			data:   "a:\n  b: 1\n  1: true\nc: [x, 2.5]\n",
+			want: map[string]interface{}{
+				"a": map[string]interface{}{"b": 1, "1": true},
+				"c": []interface{}{"x", 2.5},
+			},
+		},
+		{
+			name:   "JSON",
+			format: JSON,
+			data:   `{"a": {"b": 1}, "c": ["x", 2.5]}`,
+			want: map[string]interface{}{
+				"a": map[string]interface{}{"b": int64(1)},
+				"c": []interface{}{"x", 2.5},
+			},
+		},
+		{
+			name:   "TOML",
+			format: TOML,
+			data:   "c = [\"x\", \"y\"]\n\n[a]\nb = 1\n\n[[d]]\ne = true\n",
+			want: map[string]interface{}{
+				"a": map[string]interface{}{"b": int64(1)},
+				"c": []interface{}{"x", "y"},
+				"d": []interface{}{map[string]interface{}{"e": true}},
+			},
+		},
+		{name: "Invalid YAML", format: YAML, data: "a: [\n", wantErr: true},
+		{name: "Invalid TOML", format: TOML, data: "a = \n", wantErr: true},
+		{name: "Invalid JSON", format: JSON, data: "{", wantErr: true},
+		{name: "Shell", format: Shell, data: "A=\"1\"\n", wantErr: true},
+		{name: "Unknown format", format: Format("ini"), data: "a=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.format, []byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	doc := map[string]interface{}{
+		"b": []interface{}{1, "two"},
+		"a": map[string]interface{}{"d": true, "c": nil},
+	}
+
+	tests := []struct {
+		name    string
+		format  Format
+		doc     interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "YAML", format: YAML, doc: doc, want: "a:\n  c: null\n  d: true\nb:\n- 1\n- two\n"},
+		{name: "JSON", format: JSON, doc: doc, want: "{\n  \"a\": {\n    \"c\": null,\n    \"d\": true\n  },\n  \"b\": [\n    1,\n    \"two\"\n  ]\n}\n"},
+		{name: "TOML", format: TOML, doc: map[string]interface{}{"b": []interface{}{1, 2}, "a": map[string]interface{}{"d": true, "c": nil}}, want: "b = [1, 2]\n\n[a]\n  d = true\n"},
+		{name: "TOML sequence", format: TOML, doc: []interface{}{1, 2}, wantErr: true},
+		{name: "TOML mixed array", format: TOML, doc: doc, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Encode(tt.format, tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("Encode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
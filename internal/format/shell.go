@@ -0,0 +1,170 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cases of the shell variable names encoded by EncodeShell.
+const (
+	CaseUpper    = "upper"
+	CaseLower    = "lower"
+	CasePreserve = "preserve"
+)
+
+// shellNameChars matches the characters allowed in shell variable names.
+var shellNameChars = regexp.MustCompile(`^[A-Za-z0-9_]*$`)
+
+// shellNameReplacer replaces the characters not allowed in shell variable
+// names.
+var shellNameReplacer = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// shellValueEscaper escapes the characters special in double quoted shell
+// strings.
+var shellValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+
+// DefaultShellOptions are the ShellOptions used to encode documents as Shell
+// by Encode.
+var DefaultShellOptions = &ShellOptions{Separator: "_", Case: CaseUpper}
+
+// ShellOptions are how EncodeShell names the variables of a document. Each
+// value is assigned to a variable named Prefix followed by the keys leading
+// to it, or the indexes for sequence items, joined by Separator and converted
+// to Case.
+type ShellOptions struct {
+	Prefix    string
+	Separator string
+	Case      string
+}
+
+// NewShellOptions validates the prefix, separator and case of variable names.
+// The separator defaults to an underscore and the case to CaseUpper.
+func NewShellOptions(prefix string, separator string, nameCase string) (*ShellOptions, error) {
+	if !shellNameChars.MatchString(prefix) {
+		return nil, fmt.Errorf("Invalid shell variable prefix %s", prefix)
+	}
+
+	if separator == "" {
+		separator = "_"
+	}
+	if !shellNameChars.MatchString(separator) {
+		return nil, fmt.Errorf("Invalid shell variable separator %s", separator)
+	}
+
+	switch nameCase {
+	case "":
+		nameCase = CaseUpper
+	case CaseUpper, CaseLower, CasePreserve:
+	default:
+		return nil, fmt.Errorf("Invalid shell variable case %s", nameCase)
+	}
+
+	return &ShellOptions{Prefix: prefix, Separator: separator, Case: nameCase}, nil
+}
+
+// EncodeShell flattens a decoded document into one double quoted shell
+// variable assignment per line, eg. NTP_SERVERS_0="ntp1", as read by Warewulf.
+// Mapping keys are sorted and characters not allowed in variable names are
+// replaced with underscores. Empty mappings and sequences are left out and
+// null values are assigned an empty string. Returns an error if the keys of
+// two values map to the same variable name, eg. a-b and a_b.
+func EncodeShell(doc interface{}, opts *ShellOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := opts.flatten(&buf, map[string]string{}, opts.Prefix, "", doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// flatten writes the assignments of the variables of v, the value at path in
+// the document, to buf, naming them after name. assigned maps the variables
+// already assigned to the paths of their values.
+func (o *ShellOptions) flatten(buf *bytes.Buffer, assigned map[string]string, name string, path string, v interface{}) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if err := o.flatten(buf, assigned, o.join(name, key), joinPath(path, key), v[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if err := o.flatten(buf, assigned, o.join(name, strconv.Itoa(i)), joinPath(path, strconv.Itoa(i)), item); err != nil {
+				return err
+			}
+		}
+	default:
+		if name == "" {
+			return fmt.Errorf("Shell variable name of a %T document without a prefix", v)
+		}
+
+		// Variable names can't start with a digit
+		if name[0] >= '0' && name[0] <= '9' {
+			name = "_" + name
+		}
+
+		if other, ok := assigned[name]; ok {
+			return fmt.Errorf("Keys %s and %s are both shell variable %s", other, path, name)
+		}
+		assigned[name] = path
+
+		buf.WriteString(name)
+		buf.WriteString(`="`)
+		buf.WriteString(shellValueEscaper.Replace(shellValue(v)))
+		buf.WriteString("\"\n")
+	}
+
+	return nil
+}
+
+// join appends key to the variable name.
+func (o *ShellOptions) join(name string, key string) string {
+	key = shellNameReplacer.ReplaceAllString(key, "_")
+
+	switch o.Case {
+	case CaseUpper:
+		key = strings.ToUpper(key)
+	case CaseLower:
+		key = strings.ToLower(key)
+	}
+
+	if name == "" {
+		return key
+	}
+	return name + o.Separator + key
+}
+
+// joinPath appends key to the path of a value in a document.
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// shellValue formats a scalar value of a decoded document.
+func shellValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,140 @@
+package format
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewShellOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefix    string
+		separator string
+		nameCase  string
+		want      *ShellOptions
+		wantErr   bool
+	}{
+		{name: "Defaults", want: &ShellOptions{Separator: "_", Case: CaseUpper}},
+		{name: "Custom", prefix: "WW", separator: "__", nameCase: CaseLower, want: &ShellOptions{Prefix: "WW", Separator: "__", Case: CaseLower}},
+		{name: "Invalid prefix", prefix: "WW-", wantErr: true},
+		{name: "Invalid separator", separator: ".", wantErr: true},
+		{name: "Invalid case", nameCase: "title", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewShellOptions(tt.prefix, tt.separator, tt.nameCase)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewShellOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewShellOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeShell(t *testing.T) {
+	doc := map[string]interface{}{
+		"hostname": "node01",
+		"network": map[string]interface{}{
+			"ip-addr": "10.0.0.5",
+			"mtu":     int64(9000),
+			"routes":  []interface{}{"10.1.0.0/16", map[string]interface{}{"via": "10.0.0.1"}},
+		},
+		"motd":    "Say \"hi\" to $USER `now` \\o/",
+		"enabled": true,
+		"ratio":   0.25,
+		"unset":   nil,
+		"empty":   map[string]interface{}{},
+	}
+
+	tests := []struct {
+		name    string
+		doc     interface{}
+		opts    *ShellOptions
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Defaults",
+			doc:  doc,
+			opts: DefaultShellOptions,
+			want: "ENABLED=\"true\"\n" +
+				"HOSTNAME=\"node01\"\n" +
+				"MOTD=\"Say \\\"hi\\\" to \\$USER \\`now\\` \\\\o/\"\n" +
+				"NETWORK_IP_ADDR=\"10.0.0.5\"\n" +
+				"NETWORK_MTU=\"9000\"\n" +
+				"NETWORK_ROUTES_0=\"10.1.0.0/16\"\n" +
+				"NETWORK_ROUTES_1_VIA=\"10.0.0.1\"\n" +
+				"RATIO=\"0.25\"\n" +
+				"UNSET=\"\"\n",
+		},
+		{
+			name: "Prefix, separator and case",
+			doc:  map[string]interface{}{"Network": map[string]interface{}{"MTU": 1500}},
+			opts: &ShellOptions{Prefix: "WW", Separator: "__", Case: CaseLower},
+			want: "WW__network__mtu=\"1500\"\n",
+		},
+		{
+			name: "Preserved case",
+			doc:  map[string]interface{}{"Network": map[string]interface{}{"MTU": 1500}},
+			opts: &ShellOptions{Separator: "_", Case: CasePreserve},
+			want: "Network_MTU=\"1500\"\n",
+		},
+		{
+			name: "Top level sequence",
+			doc:  []interface{}{"a", "b"},
+			opts: DefaultShellOptions,
+			want: "_0=\"a\"\n_1=\"b\"\n",
+		},
+		{
+			name:    "Keys differing in punctuation",
+			doc:     map[string]interface{}{"a-b": 1, "a_b": 2},
+			opts:    DefaultShellOptions,
+			wantErr: true,
+		},
+		{
+			name:    "Keys differing in case",
+			doc:     map[string]interface{}{"Foo": 1, "foo": 2},
+			opts:    DefaultShellOptions,
+			wantErr: true,
+		},
+		{
+			name:    "Nested key joined like a key",
+			doc:     map[string]interface{}{"a": map[string]interface{}{"b": 1}, "a_b": 2},
+			opts:    DefaultShellOptions,
+			wantErr: true,
+		},
+		{
+			name: "Keys differing in preserved case",
+			doc:  map[string]interface{}{"Foo": 1, "foo": 2},
+			opts: &ShellOptions{Separator: "_", Case: CasePreserve},
+			want: "Foo=\"1\"\nfoo=\"2\"\n",
+		},
+		{
+			name:    "Top level scalar without a prefix",
+			doc:     "a",
+			opts:    DefaultShellOptions,
+			wantErr: true,
+		},
+		{
+			name: "Top level scalar",
+			doc:  "a",
+			opts: &ShellOptions{Prefix: "VALUE", Separator: "_", Case: CaseUpper},
+			want: "VALUE=\"a\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeShell(tt.doc, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EncodeShell() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("EncodeShell() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
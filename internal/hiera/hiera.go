@@ -0,0 +1,177 @@
+// Package hiera looks up data in a repo as Puppet's Hiera 5 does, following the
+// hierarchy of a hiera.yaml with paths interpolated from a client's facts.
+package hiera
+
+import (
+	"fmt"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+)
+
+// ConfigFile is the name of the file configuring the hierarchy.
+const ConfigFile = "hiera.yaml"
+
+// Backends of hierarchy levels.
+const (
+	yamlData = "yaml_data"
+	jsonData = "json_data"
+)
+
+// Defaults of hierarchy levels that don't set them, as in Hiera.
+const (
+	defaultDatadir  = "data"
+	defaultDataHash = yamlData
+)
+
+// defaultHierarchy is the hierarchy used if hiera.yaml doesn't set one.
+var defaultHierarchy = []interface{}{
+	map[string]interface{}{"name": "Common", "path": "common.yaml"},
+}
+
+// Config is a version 5 hiera.yaml.
+type Config struct {
+	Hierarchy []Level
+}
+
+// Level is a level of the hierarchy, reading data files at paths relative to
+// its datadir with a data_hash backend.
+type Level struct {
+	Name     string
+	Datadir  string
+	DataHash string
+	Paths    []string
+}
+
+// ParseConfig parses a version 5 hiera.yaml. Levels may set a path or a list of
+// paths, or else their name is the path, and the yaml_data or json_data
+// backend. Levels without a datadir or data_hash use those of the defaults
+// key, or data and yaml_data. Without a hierarchy the only level is
+// common.yaml.
+func ParseConfig(data []byte) (*Config, error) {
+	doc, err := format.Decode(format.YAML, data)
+	if err != nil {
+		return nil, err
+	}
+
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a mapping, not %T", ConfigFile, doc)
+	}
+
+	if version := fmt.Sprint(root["version"]); version != "5" {
+		return nil, fmt.Errorf("Unsupported %s version %s, only version 5 is supported", ConfigFile, version)
+	}
+
+	defaults := Level{Datadir: defaultDatadir, DataHash: defaultDataHash}
+	if root["defaults"] != nil {
+		def, ok := root["defaults"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("defaults must be a mapping, not %T", root["defaults"])
+		}
+		if err := parseLevelOptions(&defaults, def); err != nil {
+			return nil, fmt.Errorf("defaults: %v", err)
+		}
+	}
+
+	levels := defaultHierarchy
+	if root["hierarchy"] != nil {
+		if levels, ok = root["hierarchy"].([]interface{}); !ok {
+			return nil, fmt.Errorf("hierarchy must be a list, not %T", root["hierarchy"])
+		}
+	}
+
+	c := &Config{}
+	for i, l := range levels {
+		def, ok := l.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Hierarchy level %d must be a mapping, not %T", i, l)
+		}
+
+		level, err := parseLevel(def, defaults)
+		if err != nil {
+			return nil, fmt.Errorf("Hierarchy level %d: %v", i, err)
+		}
+		c.Hierarchy = append(c.Hierarchy, level)
+	}
+
+	return c, nil
+}
+
+// parseLevel parses a level of the hierarchy, using the datadir and data_hash
+// of defaults if it doesn't set them.
+func parseLevel(def map[string]interface{}, defaults Level) (Level, error) {
+	level := defaults
+	level.Name, _ = def["name"].(string)
+	if level.Name == "" {
+		return level, fmt.Errorf("Missing name")
+	}
+
+	if err := parseLevelOptions(&level, def); err != nil {
+		return level, err
+	}
+
+	for _, key := range []string{"glob", "globs", "uri", "uris", "mapped_paths"} {
+		if def[key] != nil {
+			return level, fmt.Errorf("Unsupported %s in level %s", key, level.Name)
+		}
+	}
+
+	switch paths := def["paths"].(type) {
+	case nil:
+	case []interface{}:
+		for _, p := range paths {
+			s, ok := p.(string)
+			if !ok {
+				return level, fmt.Errorf("Paths of level %s must be strings, not %T", level.Name, p)
+			}
+			level.Paths = append(level.Paths, s)
+		}
+	default:
+		return level, fmt.Errorf("Paths of level %s must be a list, not %T", level.Name, paths)
+	}
+
+	switch p := def["path"].(type) {
+	case nil:
+	case string:
+		level.Paths = append(level.Paths, p)
+	default:
+		return level, fmt.Errorf("Path of level %s must be a string, not %T", level.Name, p)
+	}
+
+	if len(level.Paths) == 0 {
+		level.Paths = []string{level.Name}
+	}
+	return level, nil
+}
+
+// parseLevelOptions sets the datadir and data_hash of a level from a level or
+// the defaults key.
+func parseLevelOptions(level *Level, def map[string]interface{}) error {
+	if datadir, ok := def["datadir"].(string); ok {
+		level.Datadir = datadir
+	}
+
+	if dataHash, ok := def["data_hash"].(string); ok {
+		switch dataHash {
+		case yamlData, jsonData:
+		default:
+			return fmt.Errorf("Unsupported data_hash %s", dataHash)
+		}
+		level.DataHash = dataHash
+	}
+
+	for _, key := range []string{"lookup_key", "data_dig"} {
+		if backend, ok := def[key]; ok {
+			return fmt.Errorf("Unsupported %s %v", key, backend)
+		}
+	}
+	return nil
+}
+
+// dataFormat returns the format of the data files of a level.
+func (l Level) dataFormat() format.Format {
+	if l.DataHash == jsonData {
+		return format.JSON
+	}
+	return format.YAML
+}
@@ -0,0 +1,76 @@
+package hiera
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Config
+		wantErr bool
+	}{
+		{
+			name: "Hierarchy",
+			data: `version: 5
+defaults:
+  datadir: hieradata
+hierarchy:
+  - name: Per-node data
+    path: "nodes/%{trusted.certname}.yaml"
+  - name: Per-OS defaults
+    data_hash: json_data
+    datadir: json
+    paths:
+      - "os/%{facts.os.family}.json"
+      - "os/%{facts.os.name}.json"
+  - name: common.yaml
+`,
+			want: &Config{Hierarchy: []Level{
+				{Name: "Per-node data", Datadir: "hieradata", DataHash: yamlData, Paths: []string{"nodes/%{trusted.certname}.yaml"}},
+				{Name: "Per-OS defaults", Datadir: "json", DataHash: jsonData, Paths: []string{"os/%{facts.os.family}.json", "os/%{facts.os.name}.json"}},
+				{Name: "common.yaml", Datadir: "hieradata", DataHash: yamlData, Paths: []string{"common.yaml"}},
+			}},
+		},
+		{
+			name: "Default hierarchy",
+			data: "version: 5\n",
+			want: &Config{Hierarchy: []Level{
+				{Name: "Common", Datadir: "data", DataHash: yamlData, Paths: []string{"common.yaml"}},
+			}},
+		},
+		{
+			name:    "Version 3",
+			data:    ":backends:\n  - yaml\n",
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported backend",
+			data:    "version: 5\nhierarchy:\n  - name: Vault\n    lookup_key: vault_lookup\n",
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported glob",
+			data:    "version: 5\nhierarchy:\n  - name: Roles\n    glob: \"roles/*.yaml\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "Missing name",
+			data:    "version: 5\nhierarchy:\n  - path: common.yaml\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConfig([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
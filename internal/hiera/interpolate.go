@@ -0,0 +1,128 @@
+package hiera
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// interpolation matches an interpolation in a path or value, eg.
+// %{facts.os.family}.
+var interpolation = regexp.MustCompile(`%\{([^}]*)\}`)
+
+// literalFunction matches the literal function, which interpolates its quoted
+// argument as is, eg. %{literal('%')}.
+var literalFunction = regexp.MustCompile(`^literal\((?:'([^']*)'|"([^"]*)")\)$`)
+
+// Interpolate replaces the interpolations in s with the facts they name, as in
+// Hiera. %{facts.os.family} and %{os.family} are the os.family fact,
+// %{::fqdn} is the top scope fqdn fact, and %{trusted.certname} is the
+// certname of a trusted fact. Interpolations of facts that aren't set are
+// empty strings and %{literal('%')} is a literal %. Other functions aren't
+// supported.
+func Interpolate(s string, facts map[string]interface{}) (string, error) {
+	var err error
+	interpolated := interpolation.ReplaceAllStringFunc(s, func(m string) string {
+		expr := strings.TrimSpace(m[2 : len(m)-1])
+
+		if strings.Contains(expr, "(") {
+			if lit := literalFunction.FindStringSubmatch(expr); lit != nil {
+				return lit[1] + lit[2]
+			}
+			if err == nil {
+				err = fmt.Errorf("Unsupported interpolation function in %s", m)
+			}
+			return ""
+		}
+
+		value := fact(facts, expr)
+		if value == nil {
+			return ""
+		}
+		return scalarString(value)
+	})
+
+	if err != nil {
+		return "", err
+	}
+	return interpolated, nil
+}
+
+// fact returns the value of the fact named by a variable, or nil if it isn't
+// set. Segments of a name separated by dots dig into hashes and arrays.
+func fact(facts map[string]interface{}, name string) interface{} {
+	name = strings.TrimPrefix(name, "::")
+
+	segments := strings.Split(name, ".")
+	if segments[0] == "facts" && len(segments) > 1 {
+		segments = segments[1:]
+	}
+
+	var value interface{} = facts
+	for _, segment := range segments {
+		value = dig(value, segment)
+		if value == nil {
+			return nil
+		}
+	}
+	return value
+}
+
+// dig returns the value of a key in a hash, or of an index in an array, or nil
+// if there's none.
+func dig(v interface{}, key string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v[key]
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(v) {
+			return nil
+		}
+		return v[i]
+	default:
+		return nil
+	}
+}
+
+// scalarString formats a value interpolated into a string.
+func scalarString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// interpolateValue returns a copy of a value with the interpolations in its
+// strings replaced as by Interpolate.
+func interpolateValue(v interface{}, facts map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return Interpolate(v, facts)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			var err error
+			if m[key], err = interpolateValue(value, facts); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, value := range v {
+			var err error
+			if l[i], err = interpolateValue(value, facts); err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+	default:
+		return v, nil
+	}
+}
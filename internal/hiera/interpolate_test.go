@@ -0,0 +1,40 @@
+package hiera
+
+import "testing"
+
+func TestInterpolate(t *testing.T) {
+	facts := map[string]interface{}{
+		"fqdn":    "web1.example.com",
+		"os":      map[string]interface{}{"family": "RedHat", "release": map[string]interface{}{"major": int64(8)}},
+		"trusted": map[string]interface{}{"certname": "web1.example.com"},
+		"ips":     []interface{}{"192.0.2.1", "192.0.2.2"},
+	}
+
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{name: "Facts hash", s: "os/%{facts.os.family}.yaml", want: "os/RedHat.yaml"},
+		{name: "Top scope", s: "nodes/%{::fqdn}.yaml", want: "nodes/web1.example.com.yaml"},
+		{name: "Variable", s: "%{os.family}-%{os.release.major}", want: "RedHat-8"},
+		{name: "Trusted", s: "nodes/%{trusted.certname}.yaml", want: "nodes/web1.example.com.yaml"},
+		{name: "Array index", s: "%{facts.ips.1}", want: "192.0.2.2"},
+		{name: "Missing fact", s: "roles/%{facts.role}.yaml", want: "roles/.yaml"},
+		{name: "Literal", s: "100%{literal('%')}", want: "100%"},
+		{name: "No interpolation", s: "common.yaml", want: "common.yaml"},
+		{name: "Unsupported function", s: "%{lookup('ntp::servers')}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Interpolate(tt.s, facts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Interpolate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Interpolate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
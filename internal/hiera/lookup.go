@@ -0,0 +1,215 @@
+package hiera
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+)
+
+// lookupOptionsKey is the key of the data setting the merge strategy of other
+// keys.
+const lookupOptionsKey = "lookup_options"
+
+// ErrNoConfig is returned by Load if the directory has no hiera.yaml.
+var ErrNoConfig = errors.New("No " + ConfigFile)
+
+// Data is the data of the levels of a hierarchy found for a client's facts.
+type Data struct {
+	// sources are the data hashes of the files found, from the highest to
+	// the lowest priority.
+	sources       []map[string]interface{}
+	facts         map[string]interface{}
+	lookupOptions map[string]interface{}
+}
+
+// Load reads the data of the hierarchy configured by the hiera.yaml in the
+// directory at dirPath at a version. The paths of the hierarchy are
+// interpolated with facts and data files that don't exist are skipped.
+// Returns ErrNoConfig if the directory has no hiera.yaml.
+func Load(cloned *repository.Repository, dirPath string, v *repository.Version, facts map[string]interface{}) (*Data, error) {
+	data, err := cloned.ReadAtVersion(path.Join(dirPath, ConfigFile), v)
+	if err == repository.ErrNotExist {
+		return nil, ErrNoConfig
+	} else if err != nil {
+		return nil, err
+	}
+
+	c, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("Parse %s: %v", ConfigFile, err)
+	}
+
+	d := &Data{facts: facts}
+	var lookupOptions []interface{}
+
+	for _, level := range c.Hierarchy {
+		for _, p := range level.Paths {
+			p, err := Interpolate(p, facts)
+			if err != nil {
+				return nil, fmt.Errorf("Level %s: %v", level.Name, err)
+			}
+
+			filePath := path.Join(dirPath, level.Datadir, p)
+			data, err := cloned.ReadAtVersion(filePath, v)
+			if err == repository.ErrNotExist {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			doc, err := format.Decode(level.dataFormat(), data)
+			if err != nil {
+				return nil, fmt.Errorf("Parse %s: %v", filePath, err)
+			}
+			if doc == nil {
+				continue
+			}
+
+			source, ok := doc.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("Data in %s must be a hash, not %T", filePath, doc)
+			}
+			d.sources = append(d.sources, source)
+
+			if options, ok := source[lookupOptionsKey]; ok {
+				lookupOptions = append(lookupOptions, options)
+			}
+		}
+	}
+
+	if len(lookupOptions) > 0 {
+		options, err := mergeValues(Deep, lookupOptions)
+		if err != nil {
+			return nil, err
+		}
+		if d.lookupOptions, _ = options.(map[string]interface{}); d.lookupOptions == nil {
+			return nil, fmt.Errorf("%s must be a hash, not %T", lookupOptionsKey, options)
+		}
+	}
+
+	return d, nil
+}
+
+// Lookup returns the value of a key, merging its values in every level with a
+// strategy, or if strategy is empty that of its lookup_options or else First.
+// Segments of the key separated by dots dig into the merged value, eg.
+// ntp::servers.0. Strings in the values are interpolated with the facts.
+// Returns false if no level has the key.
+func (d *Data) Lookup(key string, strategy string) (interface{}, bool, error) {
+	segments := strings.Split(key, ".")
+	root := segments[0]
+	if root == lookupOptionsKey {
+		return nil, false, nil
+	}
+
+	var values []interface{}
+	for _, source := range d.sources {
+		value, ok := source[root]
+		if !ok {
+			continue
+		}
+
+		value, err := interpolateValue(value, d.facts)
+		if err != nil {
+			return nil, false, fmt.Errorf("Lookup of %s: %v", root, err)
+		}
+		values = append(values, value)
+	}
+
+	if len(values) == 0 {
+		return nil, false, nil
+	}
+
+	if strategy == "" {
+		var err error
+		if strategy, err = d.keyStrategy(root); err != nil {
+			return nil, false, err
+		}
+	}
+
+	value, err := mergeValues(strategy, values)
+	if err != nil {
+		return nil, false, fmt.Errorf("Lookup of %s: %v", root, err)
+	}
+
+	for _, segment := range segments[1:] {
+		if value = dig(value, segment); value == nil {
+			return nil, false, nil
+		}
+	}
+	return value, true, nil
+}
+
+// All returns every key of the data looked up as by Lookup, with the merged
+// lookup_options so clients can merge the data with that of other sources.
+func (d *Data) All(strategy string) (map[string]interface{}, error) {
+	all := map[string]interface{}{}
+	for _, source := range d.sources {
+		for key := range source {
+			if _, ok := all[key]; ok || key == lookupOptionsKey {
+				continue
+			}
+
+			value, _, err := d.Lookup(key, strategy)
+			if err != nil {
+				return nil, err
+			}
+			all[key] = value
+		}
+	}
+
+	if d.lookupOptions != nil {
+		all[lookupOptionsKey] = d.lookupOptions
+	}
+	return all, nil
+}
+
+// keyStrategy returns the merge strategy of a key in the lookup_options, First
+// if none is set. Keys of the lookup_options starting with ^ are regular
+// expressions matching keys without options of their own.
+func (d *Data) keyStrategy(key string) (string, error) {
+	options, ok := d.lookupOptions[key]
+	if !ok {
+		var patterns []string
+		for pattern := range d.lookupOptions {
+			if strings.HasPrefix(pattern, "^") {
+				patterns = append(patterns, pattern)
+			}
+		}
+		sort.Strings(patterns)
+
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return "", fmt.Errorf("%s pattern %s: %v", lookupOptionsKey, pattern, err)
+			}
+			if re.MatchString(key) {
+				options = d.lookupOptions[pattern]
+				break
+			}
+		}
+	}
+
+	optionsMap, _ := options.(map[string]interface{})
+
+	// The merge option is a strategy or a hash with the strategy and its
+	// options, which aren't supported
+	strategy := First
+	switch merge := optionsMap["merge"].(type) {
+	case string:
+		strategy = merge
+	case map[string]interface{}:
+		strategy, _ = merge["strategy"].(string)
+	}
+
+	if !ValidStrategy(strategy) {
+		return "", fmt.Errorf("%s of %s: Invalid merge strategy %v", lookupOptionsKey, key, optionsMap["merge"])
+	}
+	return strategy, nil
+}
@@ -0,0 +1,165 @@
+package hiera
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newTestData returns data with a node, an OS and a common level.
+func newTestData() *Data {
+	return &Data{
+		facts: map[string]interface{}{"fqdn": "web1.example.com"},
+		sources: []map[string]interface{}{
+			{
+				"ntp::servers": []interface{}{"ntp1.example.com"},
+				"users":        map[string]interface{}{"alice": map[string]interface{}{"shell": "zsh"}},
+				"motd":         "Welcome to %{::fqdn}",
+				"packages":     []interface{}{"nginx"},
+			},
+			{
+				"ntp::servers": []interface{}{"ntp2.example.com", "ntp1.example.com"},
+				"users":        map[string]interface{}{"alice": map[string]interface{}{"uid": int64(1000)}, "bob": map[string]interface{}{"uid": int64(1001)}},
+				"packages":     []interface{}{"vim", []interface{}{"curl"}},
+				lookupOptionsKey: map[string]interface{}{
+					"packages": map[string]interface{}{"merge": "unique"},
+				},
+			},
+			{
+				"ntp::servers": "pool.ntp.org",
+				"users":        map[string]interface{}{"root": map[string]interface{}{"uid": int64(0)}},
+				"motd":         "Welcome",
+				lookupOptionsKey: map[string]interface{}{
+					"^users$": map[string]interface{}{"merge": map[string]interface{}{"strategy": "deep"}},
+				},
+			},
+		},
+		lookupOptions: map[string]interface{}{
+			"packages": map[string]interface{}{"merge": "unique"},
+			"^users$":  map[string]interface{}{"merge": map[string]interface{}{"strategy": "deep"}},
+		},
+	}
+}
+
+func TestData_Lookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		strategy string
+		want     interface{}
+		wantOk   bool
+		wantErr  bool
+	}{
+		{
+			name:   "First",
+			key:    "ntp::servers",
+			want:   []interface{}{"ntp1.example.com"},
+			wantOk: true,
+		},
+		{
+			name:     "Unique",
+			key:      "ntp::servers",
+			strategy: Unique,
+			want:     []interface{}{"ntp1.example.com", "ntp2.example.com", "pool.ntp.org"},
+			wantOk:   true,
+		},
+		{
+			name:     "Hash",
+			key:      "users",
+			strategy: Hash,
+			want: map[string]interface{}{
+				"alice": map[string]interface{}{"shell": "zsh"},
+				"bob":   map[string]interface{}{"uid": int64(1001)},
+				"root":  map[string]interface{}{"uid": int64(0)},
+			},
+			wantOk: true,
+		},
+		{
+			name: "Deep from lookup_options pattern",
+			key:  "users",
+			want: map[string]interface{}{
+				"alice": map[string]interface{}{"shell": "zsh", "uid": int64(1000)},
+				"bob":   map[string]interface{}{"uid": int64(1001)},
+				"root":  map[string]interface{}{"uid": int64(0)},
+			},
+			wantOk: true,
+		},
+		{
+			name:   "Unique from lookup_options",
+			key:    "packages",
+			want:   []interface{}{"nginx", "vim", "curl"},
+			wantOk: true,
+		},
+		{
+			name:   "Dig",
+			key:    "users.alice.uid",
+			want:   int64(1000),
+			wantOk: true,
+		},
+		{
+			name:   "Interpolated",
+			key:    "motd",
+			want:   "Welcome to web1.example.com",
+			wantOk: true,
+		},
+		{
+			name: "Missing key",
+			key:  "dns::servers",
+		},
+		{
+			name: "Missing dug key",
+			key:  "users.carol",
+		},
+		{
+			name: "lookup_options",
+			key:  lookupOptionsKey,
+		},
+		{
+			name:     "Hash merge of an array",
+			key:      "packages",
+			strategy: Hash,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := newTestData().Lookup(tt.key, tt.strategy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Data.Lookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOk {
+				t.Errorf("Data.Lookup() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Data.Lookup() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestData_All(t *testing.T) {
+	d := newTestData()
+
+	got, err := d.All("")
+	if err != nil {
+		t.Fatalf("Data.All() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"ntp::servers": []interface{}{"ntp1.example.com"},
+		"users": map[string]interface{}{
+			"alice": map[string]interface{}{"shell": "zsh", "uid": int64(1000)},
+			"bob":   map[string]interface{}{"uid": int64(1001)},
+			"root":  map[string]interface{}{"uid": int64(0)},
+		},
+		"motd":           "Welcome to web1.example.com",
+		"packages":       []interface{}{"nginx", "vim", "curl"},
+		lookupOptionsKey: d.lookupOptions,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Data.All() = %#v, want %#v", got, want)
+	}
+
+	if _, err := d.All(Hash); err == nil {
+		t.Errorf("Data.All() error = %v, wantErr true", err)
+	}
+}
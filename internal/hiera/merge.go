@@ -0,0 +1,133 @@
+package hiera
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Merge strategies of lookups, as in Hiera.
+const (
+	// First returns the value of the highest priority level with the key.
+	First = "first"
+	// Unique flattens the arrays and scalars of every level into one array
+	// without duplicates, the highest priority values first.
+	Unique = "unique"
+	// Hash merges the top level keys of the hashes of every level, higher
+	// priority levels overriding lower ones.
+	Hash = "hash"
+	// Deep merges the hashes of every level recursively, higher priority
+	// levels overriding lower ones, and merges their arrays as Unique.
+	Deep = "deep"
+)
+
+// ValidStrategy reports whether s is the name of a merge strategy.
+func ValidStrategy(s string) bool {
+	switch s {
+	case First, Unique, Hash, Deep:
+		return true
+	default:
+		return false
+	}
+}
+
+// mergeValues merges the values of a key found in each level, ordered from the
+// highest to the lowest priority, with a strategy.
+func mergeValues(strategy string, values []interface{}) (interface{}, error) {
+	switch strategy {
+	case Unique:
+		merged := []interface{}{}
+		for _, value := range values {
+			if _, ok := value.(map[string]interface{}); ok {
+				return nil, fmt.Errorf("Unique merge of a hash")
+			}
+			merged = appendUnique(merged, flatten(value)...)
+		}
+		return merged, nil
+	case Hash:
+		merged := map[string]interface{}{}
+		for i := len(values) - 1; i >= 0; i-- {
+			m, ok := values[i].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("Hash merge of %T, not a hash", values[i])
+			}
+			for key, value := range m {
+				merged[key] = value
+			}
+		}
+		return merged, nil
+	case Deep:
+		merged := values[len(values)-1]
+		for i := len(values) - 2; i >= 0; i-- {
+			merged = deepMerge(values[i], merged)
+		}
+		return merged, nil
+	default:
+		return values[0], nil
+	}
+}
+
+// deepMerge merges the value of a higher priority level over that of a lower
+// one. Hashes are merged recursively and arrays are merged as by Unique. Other
+// values of the higher priority level win.
+func deepMerge(high interface{}, low interface{}) interface{} {
+	switch high := high.(type) {
+	case map[string]interface{}:
+		lowMap, ok := low.(map[string]interface{})
+		if !ok {
+			return high
+		}
+
+		merged := make(map[string]interface{}, len(high)+len(lowMap))
+		for key, value := range lowMap {
+			merged[key] = value
+		}
+		for key, value := range high {
+			if lowValue, ok := merged[key]; ok {
+				merged[key] = deepMerge(value, lowValue)
+			} else {
+				merged[key] = value
+			}
+		}
+		return merged
+	case []interface{}:
+		lowList, ok := low.([]interface{})
+		if !ok {
+			return high
+		}
+		return appendUnique(appendUnique(nil, high...), lowList...)
+	default:
+		return high
+	}
+}
+
+// flatten returns the items of an array and of every array in it, or a
+// scalar as the only item.
+func flatten(v interface{}) []interface{} {
+	list, ok := v.([]interface{})
+	if !ok {
+		return []interface{}{v}
+	}
+
+	var items []interface{}
+	for _, item := range list {
+		items = append(items, flatten(item)...)
+	}
+	return items
+}
+
+// appendUnique appends the items that aren't in list already to it.
+func appendUnique(list []interface{}, items ...interface{}) []interface{} {
+	for _, item := range items {
+		found := false
+		for _, existing := range list {
+			if reflect.DeepEqual(existing, item) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
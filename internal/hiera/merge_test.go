@@ -0,0 +1,79 @@
+package hiera
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_mergeValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		values   []interface{}
+		want     interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "First",
+			strategy: First,
+			values:   []interface{}{"a", "b"},
+			want:     "a",
+		},
+		{
+			name:     "Unique flattens scalars and arrays",
+			strategy: Unique,
+			values:   []interface{}{[]interface{}{"a", []interface{}{"b"}}, "c", []interface{}{"b", "a"}},
+			want:     []interface{}{"a", "b", "c"},
+		},
+		{
+			name:     "Unique of a hash",
+			strategy: Unique,
+			values:   []interface{}{map[string]interface{}{"a": 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "Hash",
+			strategy: Hash,
+			values: []interface{}{
+				map[string]interface{}{"a": map[string]interface{}{"x": 1}},
+				map[string]interface{}{"a": map[string]interface{}{"y": 2}, "b": 3},
+			},
+			want: map[string]interface{}{"a": map[string]interface{}{"x": 1}, "b": 3},
+		},
+		{
+			name:     "Hash of a scalar",
+			strategy: Hash,
+			values:   []interface{}{map[string]interface{}{"a": 1}, "b"},
+			wantErr:  true,
+		},
+		{
+			name:     "Deep",
+			strategy: Deep,
+			values: []interface{}{
+				map[string]interface{}{"a": map[string]interface{}{"x": 1, "list": []interface{}{"c"}}},
+				map[string]interface{}{"a": map[string]interface{}{"y": 2, "list": []interface{}{"a", "c"}}, "b": 3},
+			},
+			want: map[string]interface{}{
+				"a": map[string]interface{}{"x": 1, "y": 2, "list": []interface{}{"c", "a"}},
+				"b": 3,
+			},
+		},
+		{
+			name:     "Deep of scalars",
+			strategy: Deep,
+			values:   []interface{}{"a", "b"},
+			want:     "a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeValues(tt.strategy, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mergeValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeValues() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
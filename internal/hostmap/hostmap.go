@@ -0,0 +1,162 @@
+// Package hostmap finds the variables of machines in a host map by their MAC
+// address, UUID or hostname.
+package hostmap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Variables of a host that identify it rather than configure it.
+const (
+	MACVar      = "mac"
+	UUIDVar     = "uuid"
+	HostnameVar = "hostname"
+)
+
+// HostMap is the variables of hosts by name and the defaults of every host.
+type HostMap struct {
+	defaults map[string]interface{}
+	hosts    map[string]map[string]interface{}
+	// names are the sorted names of the hosts, so the same host is found
+	// every time if several match.
+	names []string
+}
+
+// New returns the HostMap of a decoded host map document, a mapping with the
+// defaults variables of every host and the hosts variables of each host by
+// hostname. The mac variable of a host is its MAC address or a list of them,
+// and the uuid variable its SMBIOS UUID. A nil document is an empty host map.
+func New(doc interface{}) (*HostMap, error) {
+	m := &HostMap{hosts: map[string]map[string]interface{}{}}
+	if doc == nil {
+		return m, nil
+	}
+
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Host map must be a mapping, not %T", doc)
+	}
+
+	if root["defaults"] != nil {
+		if m.defaults, ok = root["defaults"].(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("Defaults must be a mapping, not %T", root["defaults"])
+		}
+	}
+
+	if root["hosts"] != nil {
+		hosts, ok := root["hosts"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Hosts must be a mapping, not %T", root["hosts"])
+		}
+
+		for name, vars := range hosts {
+			if vars == nil {
+				m.hosts[name] = map[string]interface{}{}
+				continue
+			}
+			if m.hosts[name], ok = vars.(map[string]interface{}); !ok {
+				return nil, fmt.Errorf("Variables of host %s must be a mapping, not %T", name, vars)
+			}
+		}
+	}
+
+	for name := range m.hosts {
+		m.names = append(m.names, name)
+	}
+	sort.Strings(m.names)
+
+	return m, nil
+}
+
+// Lookup returns the variables of the host with a MAC address, UUID or
+// hostname, tried in that order, merged over the defaults. Empty identifiers
+// are ignored. MAC addresses may be separated by colons or hyphens, as by
+// iPXE's ${mac} and ${mac:hexhyp}, and are compared ignoring case as are UUIDs.
+// The hostname variable is set to the name of the host. Returns false if no
+// host matches.
+func (m *HostMap) Lookup(mac string, uuid string, hostname string) (map[string]interface{}, bool) {
+	name, ok := m.find(mac, uuid, hostname)
+	if !ok {
+		return nil, false
+	}
+
+	vars := m.Defaults()
+	for key, value := range m.hosts[name] {
+		vars[key] = value
+	}
+	vars[HostnameVar] = name
+	return vars, true
+}
+
+// Defaults returns a copy of the defaults, the variables of hosts that aren't
+// identified.
+func (m *HostMap) Defaults() map[string]interface{} {
+	vars := make(map[string]interface{}, len(m.defaults))
+	for key, value := range m.defaults {
+		vars[key] = value
+	}
+	return vars
+}
+
+// find returns the name of the host with a MAC address, UUID or hostname.
+func (m *HostMap) find(mac string, uuid string, hostname string) (string, bool) {
+	if mac != "" {
+		mac = NormalizeMAC(mac)
+		for _, name := range m.names {
+			for _, hostMAC := range stringList(m.hosts[name][MACVar]) {
+				if NormalizeMAC(hostMAC) == mac {
+					return name, true
+				}
+			}
+		}
+	}
+
+	if uuid != "" {
+		for _, name := range m.names {
+			if hostUUID, ok := m.hosts[name][UUIDVar].(string); ok && strings.EqualFold(hostUUID, uuid) {
+				return name, true
+			}
+		}
+	}
+
+	if _, ok := m.hosts[hostname]; ok && hostname != "" {
+		return hostname, true
+	}
+	return "", false
+}
+
+// NormalizeMAC returns a MAC address in lower case with colon separators, or
+// in lower case if it isn't one.
+func NormalizeMAC(mac string) string {
+	mac = strings.ToLower(mac)
+	hex := strings.NewReplacer(":", "", "-", "", ".", "").Replace(mac)
+	if len(hex) != 12 || strings.Trim(hex, "0123456789abcdef") != "" {
+		return mac
+	}
+
+	parts := make([]string, 0, 6)
+	for i := 0; i < len(hex); i += 2 {
+		parts = append(parts, hex[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// stringList returns a string, or the strings in a list, as a list.
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,86 @@
+package hostmap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+)
+
+const testHostMap = `defaults:
+  image: rocky9
+  server: http://boot.example.com
+hosts:
+  node01:
+    mac: 52:54:00:12:34:56
+    uuid: 4C4C4544-0042-3010-8057-B4C04F4E3232
+  node02:
+    mac:
+      - 52:54:00:ab:cd:01
+      - 52:54:00:ab:cd:02
+    image: debian12
+  node03:
+`
+
+func TestHostMap_Lookup(t *testing.T) {
+	doc, err := format.Decode(format.YAML, []byte(testHostMap))
+	if err != nil {
+		t.Fatalf("format.Decode() error = %v", err)
+	}
+	m, err := New(doc)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		mac      string
+		uuid     string
+		hostname string
+		want     string
+		wantOk   bool
+	}{
+		{name: "MAC", mac: "52:54:00:12:34:56", want: "node01", wantOk: true},
+		{name: "MAC with hyphens", mac: "52-54-00-AB-CD-02", want: "node02", wantOk: true},
+		{name: "UUID", uuid: "4c4c4544-0042-3010-8057-b4c04f4e3232", want: "node01", wantOk: true},
+		{name: "Hostname", hostname: "node03", want: "node03", wantOk: true},
+		{name: "MAC before hostname", mac: "52:54:00:ab:cd:01", hostname: "node03", want: "node02", wantOk: true},
+		{name: "Unknown MAC", mac: "52:54:00:00:00:00"},
+		{name: "No identifiers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.Lookup(tt.mac, tt.uuid, tt.hostname)
+			if ok != tt.wantOk {
+				t.Fatalf("HostMap.Lookup() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && got["hostname"] != tt.want {
+				t.Errorf("HostMap.Lookup() hostname = %v, want %v", got["hostname"], tt.want)
+			}
+		})
+	}
+
+	got, _ := m.Lookup("52:54:00:ab:cd:01", "", "")
+	want := map[string]interface{}{
+		"hostname": "node02",
+		"image":    "debian12",
+		"server":   "http://boot.example.com",
+		"mac":      []interface{}{"52:54:00:ab:cd:01", "52:54:00:ab:cd:02"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HostMap.Lookup() = %v, want %v", got, want)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, doc := range []interface{}{
+		"hosts",
+		map[string]interface{}{"hosts": []interface{}{"node01"}},
+		map[string]interface{}{"hosts": map[string]interface{}{"node01": "52:54:00:12:34:56"}},
+		map[string]interface{}{"defaults": "rocky9"},
+	} {
+		if _, err := New(doc); err == nil {
+			t.Errorf("New(%v) error = %v, wantErr true", doc, err)
+		}
+	}
+}
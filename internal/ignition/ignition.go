@@ -0,0 +1,262 @@
+// Package ignition assembles Ignition configs for Fedora CoreOS nodes from the
+// files and systemd units in a hierarchy of role directories in a repo.
+package ignition
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+)
+
+// DefaultVersion is the Ignition spec version of configs that don't set one.
+const DefaultVersion = "3.3.0"
+
+// Names in a role directory.
+const (
+	// filesDir is the directory of files, by their path on the node.
+	filesDir = "files"
+	// unitsDir is the directory of systemd units, and of the drop-ins of
+	// each unit in a directory named after it with a .d suffix.
+	unitsDir = "units"
+	// dropinSuffix is the suffix of the directories of drop-ins.
+	dropinSuffix = ".d"
+)
+
+// fragmentFiles are the names of the config fragment of a role directory, in
+// order of preference.
+var fragmentFiles = []string{"ignition.yml", "ignition.yaml", "ignition.json"}
+
+// Git file modes of the files of a role directory.
+const (
+	modeExecutable = "100755"
+	modeSymlink    = "120000"
+)
+
+// installSection is the section of units that are enabled.
+const installSection = "[Install]"
+
+// ErrNoConfig is returned by Assemble if a role directory doesn't exist.
+var ErrNoConfig = errors.New("No such config")
+
+// Assemble returns the Ignition config of the role directory at dirPath at a
+// version. Every directory from the first directory of dirPath down to
+// dirPath is a role whose config is merged over that of its parent, eg.
+// /fcos/worker/gpu merges fcos/worker/gpu over fcos/worker over fcos. The
+// config of a role is its:
+//
+//   - files directory, as storage.files at the paths of the files relative to
+//     it, with their contents inlined as data URLs verified by their SHA-512
+//     hashes and their modes from Git. Symlinks are storage.links.
+//   - units directory, as systemd.units named after the files. Units with an
+//     [Install] section are enabled. The files in a directory named after a
+//     unit with a .d suffix are its drop-ins.
+//   - ignition.yml or ignition.json, a config fragment merged over the config
+//     of the files and units, eg. to set the overwrite of a file or the users.
+//
+// The spec version is DefaultVersion unless a fragment sets one. Returns
+// ErrNoConfig if a role directory doesn't exist.
+func Assemble(cloned *repository.Repository, dirPath string, v *repository.Version) (map[string]interface{}, error) {
+	dirPath = strings.Trim(path.Clean("/"+dirPath), "/")
+	if dirPath == "" {
+		return nil, ErrNoConfig
+	}
+
+	config := map[string]interface{}{}
+
+	var rolePath string
+	for _, role := range strings.Split(dirPath, "/") {
+		rolePath = path.Join(rolePath, role)
+
+		roleConfig, err := assembleRole(cloned, rolePath, v)
+		if err != nil {
+			return nil, err
+		}
+		config = Merge(config, roleConfig)
+	}
+
+	ignition, _ := config["ignition"].(map[string]interface{})
+	if ignition == nil {
+		ignition = map[string]interface{}{}
+		config["ignition"] = ignition
+	}
+	if ignition["version"] == nil {
+		ignition["version"] = DefaultVersion
+	}
+
+	return config, nil
+}
+
+// assembleRole returns the config of the role directory at rolePath.
+func assembleRole(cloned *repository.Repository, rolePath string, v *repository.Version) (map[string]interface{}, error) {
+	dir, err := cloned.ListTreeAtVersion(rolePath, v, false)
+	if err == repository.ErrNotExist || err == repository.ErrNotDir {
+		return nil, ErrNoConfig
+	} else if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]string{}
+	for _, entry := range dir.Entries {
+		entries[entry.Name] = entry.Type
+	}
+
+	config := map[string]interface{}{}
+
+	if entries[filesDir] == "tree" {
+		files, links, err := assembleFiles(cloned, path.Join(rolePath, filesDir), v)
+		if err != nil {
+			return nil, err
+		}
+
+		storage := map[string]interface{}{}
+		if len(files) > 0 {
+			storage["files"] = files
+		}
+		if len(links) > 0 {
+			storage["links"] = links
+		}
+		config["storage"] = storage
+	}
+
+	if entries[unitsDir] == "tree" {
+		units, err := assembleUnits(cloned, path.Join(rolePath, unitsDir), v)
+		if err != nil {
+			return nil, err
+		}
+		config["systemd"] = map[string]interface{}{"units": units}
+	}
+
+	for _, name := range fragmentFiles {
+		if entries[name] != "blob" {
+			continue
+		}
+
+		fragmentPath := path.Join(rolePath, name)
+		data, err := cloned.ReadAtVersion(fragmentPath, v)
+		if err != nil {
+			return nil, err
+		}
+
+		f, _ := format.FromPath(name)
+		doc, err := format.Decode(f, data)
+		if err != nil {
+			return nil, fmt.Errorf("Parse %s: %v", fragmentPath, err)
+		}
+		if doc == nil {
+			break
+		}
+
+		fragment, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a mapping, not %T", fragmentPath, doc)
+		}
+		config = Merge(config, fragment)
+		break
+	}
+
+	return config, nil
+}
+
+// assembleFiles returns the storage.files and storage.links entries of the
+// files in the directory at dirPath.
+func assembleFiles(cloned *repository.Repository, dirPath string, v *repository.Version) ([]interface{}, []interface{}, error) {
+	dir, err := cloned.ReadTreeAtVersion(dirPath, v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files, links []interface{}
+	for _, entry := range dir {
+		data := entry.Data
+
+		nodePath := "/" + entry.Name
+		if entry.Mode == modeSymlink {
+			links = append(links, map[string]interface{}{"path": nodePath, "target": string(data)})
+			continue
+		}
+
+		mode := int64(0644)
+		if entry.Mode == modeExecutable {
+			mode = 0755
+		}
+
+		files = append(files, map[string]interface{}{
+			"path":     nodePath,
+			"mode":     mode,
+			"contents": inlineContents(data),
+		})
+	}
+
+	return files, links, nil
+}
+
+// assembleUnits returns the systemd.units entries of the units and drop-ins in
+// the directory at dirPath.
+func assembleUnits(cloned *repository.Repository, dirPath string, v *repository.Version) ([]interface{}, error) {
+	dir, err := cloned.ReadTreeAtVersion(dirPath, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var units []interface{}
+	unitsByName := map[string]map[string]interface{}{}
+	unit := func(name string) map[string]interface{} {
+		u, ok := unitsByName[name]
+		if !ok {
+			u = map[string]interface{}{"name": name}
+			unitsByName[name] = u
+			units = append(units, u)
+		}
+		return u
+	}
+
+	for _, entry := range dir {
+		data := entry.Data
+
+		parts := strings.Split(entry.Name, "/")
+		switch {
+		case len(parts) == 1:
+			u := unit(entry.Name)
+			u["contents"] = string(data)
+			if hasInstallSection(data) {
+				u["enabled"] = true
+			}
+		case len(parts) == 2 && strings.HasSuffix(parts[0], dropinSuffix):
+			u := unit(strings.TrimSuffix(parts[0], dropinSuffix))
+			dropins, _ := u["dropins"].([]interface{})
+			u["dropins"] = append(dropins, map[string]interface{}{"name": parts[1], "contents": string(data)})
+		default:
+			return nil, fmt.Errorf("%s isn't a unit or drop-in", path.Join(dirPath, entry.Name))
+		}
+	}
+
+	return units, nil
+}
+
+// inlineContents returns the contents of a file as a data URL verified by its
+// SHA-512 hash.
+func inlineContents(data []byte) map[string]interface{} {
+	sum := sha512.Sum512(data)
+	return map[string]interface{}{
+		"source":       "data:;base64," + base64.StdEncoding.EncodeToString(data),
+		"verification": map[string]interface{}{"hash": "sha512-" + hex.EncodeToString(sum[:])},
+	}
+}
+
+// hasInstallSection reports whether a unit has an [Install] section.
+func hasInstallSection(data []byte) bool {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if string(bytes.TrimSpace(line)) == installSection {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package ignition
+
+// listKeys are the keys identifying the entries of lists of objects, by the
+// path of the list in a config, as in Ignition's merging of configs.
+var listKeys = map[string]string{
+	"ignition.config.merge":                        "source",
+	"ignition.security.tls.certificateAuthorities": "source",
+	"passwd.users":                                 "name",
+	"passwd.groups":                                "name",
+	"storage.disks":                                "device",
+	"storage.raid":                                 "name",
+	"storage.filesystems":                          "device",
+	"storage.files":                                "path",
+	"storage.directories":                          "path",
+	"storage.links":                                "path",
+	"storage.luks":                                 "name",
+	"systemd.units":                                "name",
+	"systemd.units.dropins":                        "name",
+}
+
+// nodeLists are the lists of storage.files, storage.directories and
+// storage.links, whose entries share the same paths.
+var nodeLists = []string{"files", "directories", "links"}
+
+// Merge merges a child config over a parent config as Ignition merges
+// configs. Objects are merged field by field and fields of the child that are
+// null are left out. Entries of lists with the same key, eg. the path of
+// storage.files or the name of systemd.units, are merged and other entries of
+// the child are appended, except for values already in lists of values. A
+// storage.files, storage.directories or storage.links entry of the child
+// replaces the entries with its path in the other two lists of the parent. The
+// configs aren't modified.
+func Merge(parent map[string]interface{}, child map[string]interface{}) map[string]interface{} {
+	return mergeValue(removeReplacedNodes(parent, child), child, "").(map[string]interface{})
+}
+
+// mergeValue merges a child value over a parent value at a path in a config.
+func mergeValue(parent interface{}, child interface{}, keyPath string) interface{} {
+	switch child := child.(type) {
+	case map[string]interface{}:
+		parentMap, ok := parent.(map[string]interface{})
+		if !ok {
+			return child
+		}
+
+		merged := make(map[string]interface{}, len(parentMap)+len(child))
+		for key, value := range parentMap {
+			merged[key] = value
+		}
+		for key, value := range child {
+			if value != nil {
+				merged[key] = mergeValue(merged[key], value, joinKey(keyPath, key))
+			}
+		}
+		return merged
+	case []interface{}:
+		parentList, ok := parent.([]interface{})
+		if !ok {
+			return child
+		}
+		return mergeList(parentList, child, keyPath)
+	default:
+		return child
+	}
+}
+
+// mergeList merges the entries of a child list over a parent list at a path in
+// a config.
+func mergeList(parent []interface{}, child []interface{}, keyPath string) []interface{} {
+	key := listKeys[keyPath]
+
+	merged := append([]interface{}(nil), parent...)
+	for _, item := range child {
+		itemMap, isMap := item.(map[string]interface{})
+
+		switch {
+		case isMap && key != "" && itemMap[key] != nil:
+			if i := indexOfKey(merged, key, itemMap[key]); i >= 0 {
+				merged[i] = mergeValue(merged[i], item, keyPath)
+				continue
+			}
+		case isMap:
+		case indexOf(merged, item) >= 0:
+			continue
+		}
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+// removeReplacedNodes returns a copy of the parent config without the storage
+// entries replaced by entries with the same path in another list of the child.
+func removeReplacedNodes(parent map[string]interface{}, child map[string]interface{}) map[string]interface{} {
+	parentStorage, _ := parent["storage"].(map[string]interface{})
+	childStorage, _ := child["storage"].(map[string]interface{})
+	if parentStorage == nil || childStorage == nil {
+		return parent
+	}
+
+	storage := make(map[string]interface{}, len(parentStorage))
+	for key, value := range parentStorage {
+		storage[key] = value
+	}
+
+	for _, childList := range nodeLists {
+		entries, _ := childStorage[childList].([]interface{})
+		for _, entry := range entries {
+			entryMap, _ := entry.(map[string]interface{})
+			if entryMap["path"] == nil {
+				continue
+			}
+
+			for _, parentList := range nodeLists {
+				list, ok := storage[parentList].([]interface{})
+				if parentList == childList || !ok {
+					continue
+				}
+				if i := indexOfKey(list, "path", entryMap["path"]); i >= 0 {
+					storage[parentList] = append(append([]interface{}(nil), list[:i]...), list[i+1:]...)
+				}
+			}
+		}
+	}
+
+	withStorage := make(map[string]interface{}, len(parent))
+	for key, value := range parent {
+		withStorage[key] = value
+	}
+	withStorage["storage"] = storage
+	return withStorage
+}
+
+// indexOfKey returns the index of the object in a list with a value of a key,
+// or -1 if there's none. Values that are objects or lists aren't keys.
+func indexOfKey(list []interface{}, key string, value interface{}) int {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return -1
+	}
+
+	for i, item := range list {
+		if itemMap, ok := item.(map[string]interface{}); ok && itemMap[key] == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexOf returns the index of a value in a list, or -1 if there's none. Only
+// values that aren't objects or lists are compared.
+func indexOf(list []interface{}, value interface{}) int {
+	for i, item := range list {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			continue
+		}
+		if item == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// joinKey returns the path of a key in an object at a path.
+func joinKey(keyPath string, key string) string {
+	if keyPath == "" {
+		return key
+	}
+	return keyPath + "." + key
+}
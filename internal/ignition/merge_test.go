@@ -0,0 +1,89 @@
+package ignition
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent map[string]interface{}
+		child  map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			name: "Files with the same path",
+			parent: map[string]interface{}{"storage": map[string]interface{}{"files": []interface{}{
+				map[string]interface{}{"path": "/etc/hostname", "mode": 420, "contents": map[string]interface{}{"source": "data:,a"}},
+				map[string]interface{}{"path": "/etc/motd", "mode": 420},
+			}}},
+			child: map[string]interface{}{"storage": map[string]interface{}{"files": []interface{}{
+				map[string]interface{}{"path": "/etc/hostname", "overwrite": true},
+				map[string]interface{}{"path": "/etc/issue", "mode": 420},
+			}}},
+			want: map[string]interface{}{"storage": map[string]interface{}{"files": []interface{}{
+				map[string]interface{}{"path": "/etc/hostname", "mode": 420, "overwrite": true, "contents": map[string]interface{}{"source": "data:,a"}},
+				map[string]interface{}{"path": "/etc/motd", "mode": 420},
+				map[string]interface{}{"path": "/etc/issue", "mode": 420},
+			}}},
+		},
+		{
+			name: "Units and drop-ins",
+			parent: map[string]interface{}{"systemd": map[string]interface{}{"units": []interface{}{
+				map[string]interface{}{"name": "kubelet.service", "enabled": true, "dropins": []interface{}{
+					map[string]interface{}{"name": "10-base.conf", "contents": "[Service]\n"},
+				}},
+			}}},
+			child: map[string]interface{}{"systemd": map[string]interface{}{"units": []interface{}{
+				map[string]interface{}{"name": "kubelet.service", "dropins": []interface{}{
+					map[string]interface{}{"name": "20-gpu.conf", "contents": "[Service]\n"},
+				}},
+			}}},
+			want: map[string]interface{}{"systemd": map[string]interface{}{"units": []interface{}{
+				map[string]interface{}{"name": "kubelet.service", "enabled": true, "dropins": []interface{}{
+					map[string]interface{}{"name": "10-base.conf", "contents": "[Service]\n"},
+					map[string]interface{}{"name": "20-gpu.conf", "contents": "[Service]\n"},
+				}},
+			}}},
+		},
+		{
+			name: "Lists of values",
+			parent: map[string]interface{}{"passwd": map[string]interface{}{"users": []interface{}{
+				map[string]interface{}{"name": "core", "sshAuthorizedKeys": []interface{}{"ssh-ed25519 A", "ssh-ed25519 B"}},
+			}}},
+			child: map[string]interface{}{"passwd": map[string]interface{}{"users": []interface{}{
+				map[string]interface{}{"name": "core", "sshAuthorizedKeys": []interface{}{"ssh-ed25519 B", "ssh-ed25519 C"}},
+			}}},
+			want: map[string]interface{}{"passwd": map[string]interface{}{"users": []interface{}{
+				map[string]interface{}{"name": "core", "sshAuthorizedKeys": []interface{}{"ssh-ed25519 A", "ssh-ed25519 B", "ssh-ed25519 C"}},
+			}}},
+		},
+		{
+			name: "Link replaces file",
+			parent: map[string]interface{}{"storage": map[string]interface{}{
+				"files": []interface{}{map[string]interface{}{"path": "/etc/localtime"}, map[string]interface{}{"path": "/etc/motd"}},
+			}},
+			child: map[string]interface{}{"storage": map[string]interface{}{
+				"links": []interface{}{map[string]interface{}{"path": "/etc/localtime", "target": "../usr/share/zoneinfo/UTC"}},
+			}},
+			want: map[string]interface{}{"storage": map[string]interface{}{
+				"files": []interface{}{map[string]interface{}{"path": "/etc/motd"}},
+				"links": []interface{}{map[string]interface{}{"path": "/etc/localtime", "target": "../usr/share/zoneinfo/UTC"}},
+			}},
+		},
+		{
+			name:   "Null fields are left out",
+			parent: map[string]interface{}{"ignition": map[string]interface{}{"version": "3.2.0"}},
+			child:  map[string]interface{}{"ignition": map[string]interface{}{"version": nil}, "kernelArguments": map[string]interface{}{"shouldExist": []interface{}{"quiet"}}},
+			want:   map[string]interface{}{"ignition": map[string]interface{}{"version": "3.2.0"}, "kernelArguments": map[string]interface{}{"shouldExist": []interface{}{"quiet"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Merge(tt.parent, tt.child); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
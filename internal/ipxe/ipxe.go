@@ -0,0 +1,46 @@
+// Package ipxe renders iPXE script templates with the variables of the host
+// booting them.
+package ipxe
+
+import (
+	"bytes"
+	"errors"
+	"text/template"
+)
+
+// Extension is the extension of iPXE script templates.
+const Extension = ".ipxe"
+
+// DefaultHostMap is the path of the host map in a repo that doesn't set
+// ipxe_host_map.
+const DefaultHostMap = "hosts.yml"
+
+// magic is the line every iPXE script must start with.
+const magic = "#!ipxe"
+
+// ErrNotScript is returned by Render if the rendered template doesn't start
+// with #!ipxe.
+var ErrNotScript = errors.New("Rendered template doesn't start with " + magic)
+
+// Render executes an iPXE script template, in the syntax of text/template,
+// with the variables of a host, eg. {{ .kernel }}. Using a variable that isn't
+// set is an error. Blank lines before #!ipxe, such as those left by template
+// actions, are removed as iPXE only runs scripts starting with it. Returns
+// ErrNotScript if the script doesn't start with #!ipxe.
+func Render(name string, text []byte, vars map[string]interface{}) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, vars); err != nil {
+		return nil, err
+	}
+
+	script := bytes.TrimLeft(rendered.Bytes(), " \t\r\n")
+	if !bytes.HasPrefix(script, []byte(magic)) {
+		return nil, ErrNotScript
+	}
+	return script, nil
+}
@@ -0,0 +1,51 @@
+package ipxe
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	vars := map[string]interface{}{"hostname": "node01", "server": "http://boot.example.com", "image": "rocky9"}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Script",
+			text: "#!ipxe\nkernel {{ .server }}/{{ .image }}/vmlinuz hostname={{ .hostname }}\nboot\n",
+			want: "#!ipxe\nkernel http://boot.example.com/rocky9/vmlinuz hostname=node01\nboot\n",
+		},
+		{
+			name: "Leading template comment",
+			text: "{{/* Boots the image of the host */}}\n#!ipxe\nboot\n",
+			want: "#!ipxe\nboot\n",
+		},
+		{
+			name:    "Missing variable",
+			text:    "#!ipxe\nkernel {{ .kernel }}\n",
+			wantErr: true,
+		},
+		{
+			name:    "Not a script",
+			text:    "kernel {{ .image }}\n",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid template",
+			text:    "#!ipxe\n{{ .image\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render("boot.ipxe", []byte(tt.text), vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package mediatype picks the media type a file is served with from its path,
+// its contents and path glob overrides.
+package mediatype
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SniffLen is the number of bytes at the start of a file Detect looks at.
+const SniffLen = 512
+
+// Media types served for common configuration file formats.
+const (
+	YAML   = "application/yaml"
+	JSON   = "application/json"
+	TOML   = "application/toml"
+	Shell  = "text/x-shellscript"
+	Text   = "text/plain; charset=utf-8"
+	Binary = "application/octet-stream"
+)
+
+// extensions maps file extensions to media types. iPXE scripts are served as
+// plain text as that's what iPXE expects.
+var extensions = map[string]string{
+	".yml":  YAML,
+	".yaml": YAML,
+	".json": JSON,
+	".toml": TOML,
+	".sh":   Shell,
+	".bash": Shell,
+	".ipxe": Text,
+	".txt":  Text,
+	".conf": Text,
+	".cfg":  Text,
+	".ini":  Text,
+}
+
+// Detect returns the media type of the file at filePath starting with head,
+// the first SniffLen bytes of its contents. Known file extensions take
+// precedence, otherwise the contents are sniffed for scripts and YAML and JSON
+// documents, falling back to plain text or binary.
+func Detect(filePath string, head []byte) string {
+	if mediaType, ok := extensions[strings.ToLower(path.Ext(filePath))]; ok {
+		return mediaType
+	}
+
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+
+	switch {
+	case bytes.HasPrefix(head, []byte("#!ipxe")):
+		return Text
+	case bytes.HasPrefix(head, []byte("#!")):
+		return Shell
+	case bytes.HasPrefix(head, []byte("---")), bytes.HasPrefix(head, []byte("%YAML")):
+		return YAML
+	case bytes.HasPrefix(trimmed, []byte("{")), bytes.HasPrefix(trimmed, []byte("[")):
+		if http.DetectContentType(head) != Binary {
+			return JSON
+		}
+	}
+
+	if strings.HasPrefix(http.DetectContentType(head), "text/plain") {
+		return Text
+	}
+	return Binary
+}
+
+// Overrides maps path globs, as matched by path.Match, to media types. Globs
+// containing a slash are matched against the whole path, others against the
+// file name only.
+type Overrides map[string]string
+
+// NewOverrides validates the globs and media types of overrides.
+func NewOverrides(overrides map[string]string) (Overrides, error) {
+	o := Overrides{}
+
+	for glob, mediaType := range overrides {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("Invalid glob %s: %s", glob, err)
+		}
+		parsed, _, err := mime.ParseMediaType(mediaType)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid media type %s for %s: %s", mediaType, glob, err)
+		}
+		if !strings.Contains(parsed, "/") {
+			return nil, fmt.Errorf("Invalid media type %s for %s: Missing subtype", mediaType, glob)
+		}
+		o[glob] = mediaType
+	}
+
+	return o, nil
+}
+
+// Lookup returns the media type of the override matching filePath. If more
+// than one matches the longest, most specific, glob wins.
+func (o Overrides) Lookup(filePath string) (string, bool) {
+	filePath = strings.TrimPrefix(filePath, "/")
+
+	globs := make([]string, 0, len(o))
+	for glob := range o {
+		globs = append(globs, glob)
+	}
+	sort.Slice(globs, func(i, j int) bool {
+		if len(globs[i]) != len(globs[j]) {
+			return len(globs[i]) > len(globs[j])
+		}
+		return globs[i] < globs[j]
+	})
+
+	for _, glob := range globs {
+		name := filePath
+		if !strings.Contains(glob, "/") {
+			name = path.Base(filePath)
+		}
+		if ok, _ := path.Match(strings.TrimPrefix(glob, "/"), name); ok {
+			return o[glob], true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,84 @@
+package mediatype
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		head     string
+		want     string
+	}{
+		{name: "YAML extension", filePath: "/config.yml", head: "a: 1\n", want: YAML},
+		{name: "Upper case YAML extension", filePath: "/config.YAML", head: "a: 1\n", want: YAML},
+		{name: "JSON extension", filePath: "/config.json", head: "{}", want: JSON},
+		{name: "TOML extension", filePath: "/config.toml", head: "a = 1\n", want: TOML},
+		{name: "Shell extension", filePath: "/bin/setup.sh", head: "echo hi\n", want: Shell},
+		{name: "iPXE extension", filePath: "/boot.ipxe", head: "#!ipxe\n", want: Text},
+		{name: "Sniffed iPXE script", filePath: "/boot", head: "#!ipxe\nchain http://boot/\n", want: Text},
+		{name: "Sniffed shell script", filePath: "/bin/setup", head: "#!/bin/sh\necho hi\n", want: Shell},
+		{name: "Sniffed YAML document", filePath: "/config", head: "---\na: 1\n", want: YAML},
+		{name: "Sniffed JSON document", filePath: "/config", head: "\n  {\"a\": 1}", want: JSON},
+		{name: "Plain text", filePath: "/README", head: "Hello\n", want: Text},
+		{name: "Binary", filePath: "/blob", head: "\x00\x01\x02\x03", want: Binary},
+		{name: "Binary that looks like JSON", filePath: "/blob", head: "{\x00\x01\x02", want: Binary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.filePath, []byte(tt.head)); got != tt.want {
+				t.Errorf("Detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		wantErr   bool
+	}{
+		{name: "Valid", overrides: map[string]string{"*.cfg": "application/yaml", "hosts/*": "text/plain; charset=utf-8"}},
+		{name: "Invalid glob", overrides: map[string]string{"[": "application/yaml"}, wantErr: true},
+		{name: "Invalid media type", overrides: map[string]string{"*.cfg": "yaml"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewOverrides(tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOverrides_Lookup(t *testing.T) {
+	o, err := NewOverrides(map[string]string{
+		"*.cfg":         "application/yaml",
+		"hosts/*.cfg":   "application/json",
+		"/scripts/boot": "text/plain",
+	})
+	if err != nil {
+		t.Fatalf("NewOverrides() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filePath string
+		want     string
+		wantOk   bool
+	}{
+		{name: "File name glob", filePath: "/a/b/config.cfg", want: "application/yaml", wantOk: true},
+		{name: "More specific path glob", filePath: "/hosts/web.cfg", want: "application/json", wantOk: true},
+		{name: "Exact path", filePath: "/scripts/boot", want: "text/plain", wantOk: true},
+		{name: "No match", filePath: "/config.yml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := o.Lookup(tt.filePath)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Overrides.Lookup() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
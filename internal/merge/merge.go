@@ -0,0 +1,162 @@
+// Package merge combines decoded configuration documents, such as the config
+// files found at each level of a directory tree, into one.
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Reset is the marker that stops a value being merged with the values of less
+// specific documents. As a mapping value it removes the key, as the first item
+// of a sequence it makes the sequence replace the inherited one, and as a key
+// of a mapping it makes the mapping replace the inherited one. The marker is
+// left out of the merged document.
+//
+// It must be quoted in YAML documents, eg. '!reset', as YAML reads an unquoted
+// !reset as a tag.
+const Reset = "!reset"
+
+// Modes of merging mappings.
+const (
+	// Override replaces the top level keys of less specific documents with
+	// those of more specific ones.
+	Override = "override"
+	// Deep merges mappings at every level.
+	Deep = "deep"
+)
+
+// Modes of merging sequences.
+const (
+	// ListsReplace replaces sequences of less specific documents with those
+	// of more specific ones.
+	ListsReplace = "replace"
+	// ListsAppend appends the items of sequences of more specific documents
+	// to those of less specific ones.
+	ListsAppend = "append"
+)
+
+// ErrResetTag is returned by CheckYAML for documents using an unquoted !reset.
+var ErrResetTag = errors.New("Unquoted !reset is a YAML tag, quote it as '!reset'")
+
+// Options are the modes of merging mappings and sequences.
+type Options struct {
+	Mode  string
+	Lists string
+}
+
+// NewOptions validates the modes of merging mappings and sequences. The
+// sequence mode defaults to ListsReplace.
+func NewOptions(mode string, lists string) (*Options, error) {
+	switch mode {
+	case Override, Deep:
+	default:
+		return nil, fmt.Errorf("Invalid merge mode %s", mode)
+	}
+
+	switch lists {
+	case "":
+		lists = ListsReplace
+	case ListsReplace, ListsAppend:
+	default:
+		return nil, fmt.Errorf("Invalid list merge mode %s", lists)
+	}
+
+	return &Options{Mode: mode, Lists: lists}, nil
+}
+
+// CheckYAML returns ErrResetTag if a YAML document marks values with a !reset
+// tag, which is lost on decoding, rather than the quoted Reset marker. !reset
+// in comments, quoted scalars and block scalars isn't a tag.
+func CheckYAML(data []byte) error {
+	s := &yamlScanner{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if s.scanLine(line) {
+			return ErrResetTag
+		}
+	}
+	return nil
+}
+
+// Merge merges decoded documents, ordered from the least to the most specific,
+// into one. Documents are decoded as by the format package. The documents
+// aren't modified.
+func (o *Options) Merge(docs []interface{}) interface{} {
+	var merged interface{}
+	for _, doc := range docs {
+		merged = o.merge(merged, doc, 0)
+	}
+	return merged
+}
+
+// merge merges src over dst, a document at depth levels below the top of the
+// documents merged. dst has been merged before so it has no Reset markers.
+func (o *Options) merge(dst interface{}, src interface{}, depth int) interface{} {
+	merging := depth == 0 || o.Mode == Deep
+
+	switch src := src.(type) {
+	case map[string]interface{}:
+		dstMap, ok := dst.(map[string]interface{})
+		if _, reset := src[Reset]; reset || !ok || !merging {
+			return clean(src)
+		}
+
+		merged := make(map[string]interface{}, len(dstMap)+len(src))
+		for key, value := range dstMap {
+			merged[key] = value
+		}
+		for key, value := range src {
+			if value == Reset {
+				delete(merged, key)
+				continue
+			}
+			merged[key] = o.merge(merged[key], value, depth+1)
+		}
+		return merged
+	case []interface{}:
+		if len(src) > 0 && src[0] == Reset {
+			return clean(src[1:])
+		}
+
+		dstList, ok := dst.([]interface{})
+		if !ok || !merging || o.Lists != ListsAppend {
+			return clean(src)
+		}
+
+		merged := make([]interface{}, 0, len(dstList)+len(src))
+		merged = append(merged, dstList...)
+		return append(merged, clean(src).([]interface{})...)
+	default:
+		if src == Reset {
+			return nil
+		}
+		return src
+	}
+}
+
+// clean returns a copy of a document without Reset markers.
+func clean(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if key == Reset || value == Reset {
+				continue
+			}
+			m[key] = clean(value)
+		}
+		return m
+	case []interface{}:
+		if len(v) > 0 && v[0] == Reset {
+			v = v[1:]
+		}
+		l := make([]interface{}, len(v))
+		for i, value := range v {
+			l[i] = clean(value)
+		}
+		return l
+	default:
+		return v
+	}
+}
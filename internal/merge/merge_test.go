@@ -0,0 +1,169 @@
+package merge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		lists   string
+		want    *Options
+		wantErr bool
+	}{
+		{name: "Deep", mode: Deep, want: &Options{Mode: Deep, Lists: ListsReplace}},
+		{name: "Override appending lists", mode: Override, lists: ListsAppend, want: &Options{Mode: Override, Lists: ListsAppend}},
+		{name: "Invalid mode", mode: "shallow", wantErr: true},
+		{name: "No mode", lists: ListsAppend, wantErr: true},
+		{name: "Invalid list mode", mode: Deep, lists: "prepend", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewOptions(tt.mode, tt.lists)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "No marker", data: "a: 1\n"},
+		{name: "Quoted marker", data: "a: '!reset'\nb:\n- \"!reset\"\n"},
+		{name: "Marker in a string", data: "a: please !reset\n"},
+		{name: "Tagged value", data: "a: !reset\n", wantErr: true},
+		{name: "Tagged sequence item", data: "a:\n  - !reset\n", wantErr: true},
+		{name: "Tagged flow sequence item", data: "a: [!reset, 1]\n", wantErr: true},
+		{name: "Tagged flow mapping value", data: "a: {b: 1, c: !reset}\n", wantErr: true},
+		{name: "Tagged value with an anchor", data: "a: &b !reset\n", wantErr: true},
+		{name: "Tagged item of a nested sequence", data: "- - !reset\n", wantErr: true},
+		{name: "Tagged value after a block scalar", data: "a: |\n  text\nb: !reset\n", wantErr: true},
+		{name: "Marker in a comment", data: "a: 1 # b: !reset\n# - !reset\n"},
+		{name: "Marker in a literal block scalar", data: "a: |\n  b: !reset\n  - !reset\nc: 1\n"},
+		{name: "Marker in a folded block scalar", data: "- >-\n  [!reset, 1]\n\n  c: !reset\n"},
+		{name: "Marker in a quoted flow string", data: "a: [\"b, !reset\", 'c: !reset']\n"},
+		{name: "Marker in a multi-line quoted string", data: "a: \"b\n  - !reset\"\n"},
+		{name: "Marker in a plain string with indicators", data: "a: b - !reset\nc: d, !reset\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckYAML([]byte(tt.data)); (err != nil) != tt.wantErr {
+				t.Errorf("CheckYAML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOptions_Merge(t *testing.T) {
+	root := map[string]interface{}{
+		"name":     "root",
+		"packages": []interface{}{"vim", "curl"},
+		"ntp":      map[string]interface{}{"servers": []interface{}{"ntp1"}, "enabled": true},
+		"debug":    false,
+	}
+	leaf := map[string]interface{}{
+		"name":     "leaf",
+		"packages": []interface{}{"nginx"},
+		"ntp":      map[string]interface{}{"servers": []interface{}{"ntp2"}},
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		docs []interface{}
+		want interface{}
+	}{
+		{
+			name: "Deep",
+			opts: Options{Mode: Deep, Lists: ListsReplace},
+			docs: []interface{}{root, leaf},
+			want: map[string]interface{}{
+				"name":     "leaf",
+				"packages": []interface{}{"nginx"},
+				"ntp":      map[string]interface{}{"servers": []interface{}{"ntp2"}, "enabled": true},
+				"debug":    false,
+			},
+		},
+		{
+			name: "Deep appending lists",
+			opts: Options{Mode: Deep, Lists: ListsAppend},
+			docs: []interface{}{root, leaf},
+			want: map[string]interface{}{
+				"name":     "leaf",
+				"packages": []interface{}{"vim", "curl", "nginx"},
+				"ntp":      map[string]interface{}{"servers": []interface{}{"ntp1", "ntp2"}, "enabled": true},
+				"debug":    false,
+			},
+		},
+		{
+			name: "Override",
+			opts: Options{Mode: Override, Lists: ListsAppend},
+			docs: []interface{}{root, leaf},
+			want: map[string]interface{}{
+				"name":     "leaf",
+				"packages": []interface{}{"nginx"},
+				"ntp":      map[string]interface{}{"servers": []interface{}{"ntp2"}},
+				"debug":    false,
+			},
+		},
+		{
+			name: "Reset markers",
+			opts: Options{Mode: Deep, Lists: ListsAppend},
+			docs: []interface{}{root, map[string]interface{}{
+				"debug":    Reset,
+				"packages": []interface{}{Reset, "nginx"},
+				"ntp":      map[string]interface{}{Reset: true, "enabled": false},
+			}},
+			want: map[string]interface{}{
+				"name":     "root",
+				"packages": []interface{}{"nginx"},
+				"ntp":      map[string]interface{}{"enabled": false},
+			},
+		},
+		{
+			name: "Reset markers without inherited values",
+			opts: Options{Mode: Deep, Lists: ListsReplace},
+			docs: []interface{}{map[string]interface{}{
+				"a": Reset,
+				"b": []interface{}{Reset, map[string]interface{}{Reset: true, "c": 1}},
+			}},
+			want: map[string]interface{}{
+				"b": []interface{}{map[string]interface{}{"c": 1}},
+			},
+		},
+		{
+			name: "Top level lists",
+			opts: Options{Mode: Deep, Lists: ListsAppend},
+			docs: []interface{}{[]interface{}{1}, []interface{}{2}, []interface{}{3}},
+			want: []interface{}{1, 2, 3},
+		},
+		{
+			name: "Mapping replacing a scalar",
+			opts: Options{Mode: Deep, Lists: ListsReplace},
+			docs: []interface{}{map[string]interface{}{"a": 1}, map[string]interface{}{"a": map[string]interface{}{"b": 2}}},
+			want: map[string]interface{}{"a": map[string]interface{}{"b": 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.Merge(tt.docs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Options.Merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(root["packages"], []interface{}{"vim", "curl"}) {
+		t.Errorf("Options.Merge() modified a document")
+	}
+}
@@ -0,0 +1,112 @@
+package merge
+
+import "strings"
+
+// yamlScanner finds !reset tags in a YAML document line by line. It follows
+// just enough of the YAML syntax to tell where a node starts, so !reset in
+// comments, quoted scalars and block scalars isn't mistaken for a tag.
+type yamlScanner struct {
+	// quote is the quote of a quoted scalar continued on the next line, or 0.
+	quote byte
+	// flowDepth is the nesting of the flow collections the line is in.
+	flowDepth int
+	// inBlock is true within a block scalar, whose lines are indented more
+	// than blockIndent.
+	inBlock     bool
+	blockIndent int
+}
+
+// scanLine reports whether line has a !reset tag.
+func (s *yamlScanner) scanLine(line string) bool {
+	line = strings.TrimRight(line, "\r")
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+	if s.inBlock {
+		if indent == len(line) || indent > s.blockIndent {
+			return false
+		}
+		s.inBlock = false
+	}
+
+	i := indent
+	// nodeStart is true where a node, or the tag or anchor of one, may start
+	nodeStart := true
+	if s.quote != 0 {
+		i, nodeStart = s.skipQuoted(line, i), false
+	}
+
+	for i < len(line) {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return false
+		case nodeStart && strings.HasPrefix(line[i:], Reset) && s.endsToken(line, i+len(Reset)):
+			return true
+		case nodeStart && (c == '\'' || c == '"'):
+			s.quote = c
+			i, nodeStart = s.skipQuoted(line, i+1), false
+		case nodeStart && (c == '[' || c == '{'):
+			s.flowDepth++
+			i++
+		case nodeStart && s.flowDepth == 0 && (c == '|' || c == '>'):
+			// The rest of the line is the block scalar header
+			s.inBlock, s.blockIndent = true, indent
+			return false
+		case nodeStart && (c == '-' || c == '?') && s.endsToken(line, i+1):
+			// Sequence entries and complex keys are followed by a node
+			i++
+		case nodeStart && (c == '&' || c == '!'):
+			// Anchors and other tags are followed by the node they mark
+			for i < len(line) && !s.endsToken(line, i) {
+				i++
+			}
+		case c == ':' && s.endsToken(line, i+1):
+			i, nodeStart = i+1, true
+		case s.flowDepth > 0 && c == ',':
+			i, nodeStart = i+1, true
+		case s.flowDepth > 0 && (c == ']' || c == '}'):
+			s.flowDepth--
+			i, nodeStart = i+1, false
+		default:
+			i, nodeStart = i+1, false
+		}
+	}
+	return false
+}
+
+// skipQuoted returns the index after the end of the quoted scalar line is in
+// from i, or the length of line if the scalar continues on the next line.
+func (s *yamlScanner) skipQuoted(line string, i int) int {
+	for i < len(line) {
+		switch {
+		case s.quote == '"' && line[i] == '\\':
+			i += 2
+		case s.quote == '\'' && strings.HasPrefix(line[i:], "''"):
+			i += 2
+		case line[i] == s.quote:
+			s.quote = 0
+			return i + 1
+		default:
+			i++
+		}
+	}
+	return len(line)
+}
+
+// endsToken reports whether a token of line ends at i, at the end of the line,
+// whitespace or, in a flow collection, a flow indicator.
+func (s *yamlScanner) endsToken(line string, i int) bool {
+	if i >= len(line) {
+		return true
+	}
+
+	switch line[i] {
+	case ' ', '\t':
+		return true
+	case ',', ']', '}':
+		return s.flowDepth > 0
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/cfg8er/cfg8er/internal/ansible"
+	"github.com/gin-gonic/gin"
+)
+
+// getRepoInventory serves the Ansible inventory in a directory at a version,
+// eg. /ansible/repo/v1/production, as an inventory script would. The output of
+// --list is served unless the host query parameter names a host, for which the
+// output of --host is served.
+func getRepoInventory(c *gin.Context) {
+	repo := c.Param("repo")
+	version := c.Param("version")
+	dirPath := path.Clean("/" + c.Param("path"))
+
+	r, ok := repoLookup[repo]
+
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	cloned := r.ClonedRepo()
+
+	v, err := cloned.ResolveSemVer(version)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	setVersionHeaders(c, v)
+
+	inv, err := ansible.Load(cloned, dirPath, v, lookupRepoOptions(repo).ansible)
+	if err == ansible.ErrNoInventory {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: Loading inventory %s at %s in repo %s: %v\n", dirPath, version, r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	var output interface{}
+	if host := c.Query("host"); host != "" {
+		if output, ok = inv.HostVars(host); !ok {
+			c.Status(http.StatusNotFound)
+			return
+		}
+	} else {
+		output = inv.List()
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if checkNotModified(c, contentETag(data), versionModified(v, v.Date)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
@@ -0,0 +1,110 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+)
+
+func Test_getRepoInventory(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"fixture": newFixtureRepo(t, map[string]string{
+			"prod/inventory":                         "[web]\nweb1.example.com\nweb2.example.com http_port=8081\n",
+			"prod/group_vars/all.yml":                "ntp: ntp.example.com\nhttp_port: 80\n",
+			"prod/group_vars/web/main.yml":           "http_port: 8080\nproxy: none\n",
+			"prod/group_vars/web/proxy.json":         "{\"proxy\": \"proxy.example.com\"}\n",
+			"prod/group_vars/README.md":              "Not variables\n",
+			"prod/host_vars/web1.example.com":        "http_port: 9090\n",
+			"prod/host_vars/web2.example.com.yml":    "role: canary\n",
+			"prod/host_vars/unknown.example.com.yml": "role: unknown\n",
+			"staging/inventory.yml":                  "all:\n  hosts:\n    stage1:\n",
+		}, "v1.0.0"),
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "Host",
+			url:        "/ansible/fixture/v1/prod?host=web1.example.com",
+			wantStatus: http.StatusOK,
+			want:       `{"http_port":9090,"ntp":"ntp.example.com","proxy":"proxy.example.com"}`,
+		},
+		{
+			name:       "Host variables in the inventory file",
+			url:        "/ansible/fixture/v1/prod?host=web2.example.com",
+			wantStatus: http.StatusOK,
+			want:       `{"http_port":8081,"ntp":"ntp.example.com","proxy":"proxy.example.com","role":"canary"}`,
+		},
+		{
+			name:       "List",
+			url:        "/ansible/fixture/v1/prod",
+			wantStatus: http.StatusOK,
+			want: `{"_meta":{"hostvars":{` +
+				`"web1.example.com":{"http_port":9090,"ntp":"ntp.example.com","proxy":"proxy.example.com"},` +
+				`"web2.example.com":{"http_port":8081,"ntp":"ntp.example.com","proxy":"proxy.example.com","role":"canary"}}},` +
+				`"all":{"children":["ungrouped","web"],"vars":{"http_port":80,"ntp":"ntp.example.com"}},` +
+				`"ungrouped":{},` +
+				`"web":{"hosts":["web1.example.com","web2.example.com"],"vars":{"http_port":8080,"proxy":"proxy.example.com"}}}`,
+		},
+		{
+			name:       "YAML inventory",
+			url:        "/ansible/fixture/v1.0.0/staging?host=stage1",
+			wantStatus: http.StatusOK,
+			want:       `{}`,
+		},
+		{
+			name:       "Missing host",
+			url:        "/ansible/fixture/v1/prod?host=web3.example.com",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "No inventory",
+			url:        "/ansible/fixture/v1",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Missing directory",
+			url:        "/ansible/fixture/v1/dev",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Missing version",
+			url:        "/ansible/fixture/v2/prod",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("getRepoInventory() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("getRepoInventory() body = %s, error = %v", w.Body, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("getRepoInventory() body = %s, want %s", w.Body, tt.want)
+			}
+		})
+	}
+}
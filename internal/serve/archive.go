@@ -0,0 +1,96 @@
+package serve
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// archiveFormats maps the extensions of archive URLs to their media types.
+var archiveFormats = map[string]string{
+	".tar.gz": "application/gzip",
+	".zip":    "application/zip",
+}
+
+// getRepoArchive streams a reproducible archive of a directory at a version,
+// eg. /a/repo/v1/roles/web.tar.gz for roles/web or /a/repo/v1.zip for the
+// whole repo. The format is picked by the extension of the path, or of the
+// version if there's no path.
+func getRepoArchive(c *gin.Context) {
+	repo := c.Param("repo")
+	version := c.Param("version")
+	dirPath := c.Param("path")
+
+	var ext string
+	if dirPath == "" {
+		version, ext = splitArchiveExt(version)
+	} else {
+		dirPath, ext = splitArchiveExt(path.Clean(dirPath))
+	}
+
+	r, ok := repoLookup[repo]
+
+	if !ok || ext == "" || version == "" {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	cloned := r.ClonedRepo()
+
+	v, err := cloned.ResolveSemVer(version)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	setVersionHeaders(c, v)
+
+	// The archive only depends on the commit, the directory and the format,
+	// so the tree isn't walked if the client's copy is current. A client only
+	// has the ETag of a directory that exists at the commit.
+	if checkNotModified(c, contentETag([]byte(v.Hash.String()+dirPath+ext)), versionModified(v, v.Date)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	archive, err := cloned.NewArchive(dirPath, v)
+	if err != nil {
+		c.Header("ETag", "")
+		c.Header("Last-Modified", "")
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	name := path.Base(dirPath)
+	if dirPath == "" || name == "/" {
+		name = repo + "-" + version
+	}
+
+	c.Header("Content-Type", archiveFormats[ext])
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ext}))
+	c.Status(http.StatusOK)
+
+	if ext == ".zip" {
+		err = archive.WriteZip(c.Writer)
+	} else {
+		err = archive.WriteTarGz(c.Writer)
+	}
+	if err != nil {
+		fmt.Printf("Error: Writing archive of %s at %s in repo %s: %v\n", dirPath, version, r.URL, err)
+	}
+}
+
+// splitArchiveExt splits the archive format extension off name. Returns an
+// empty extension if name doesn't end with one.
+func splitArchiveExt(name string) (string, string) {
+	for ext := range archiveFormats {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext), ext
+		}
+	}
+	return name, ""
+}
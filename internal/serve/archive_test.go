@@ -0,0 +1,130 @@
+package serve
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+)
+
+func Test_getRepoArchive(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"fixture": newFixtureRepo(t, map[string]string{
+			"config.yml":           "version: 1.0.0\n",
+			"roles/web/vars.yml":   "port: 80\n",
+			"roles/web/nginx.conf": "server {}\n",
+		}, "v1.0.0"),
+	}
+
+	tests := []struct {
+		name            string
+		url             string
+		wantStatus      int
+		wantType        string
+		wantDisposition string
+		want            []string
+	}{
+		{
+			name:            "Subdirectory as tar.gz",
+			url:             "/a/fixture/v1/roles/web.tar.gz",
+			wantStatus:      http.StatusOK,
+			wantType:        "application/gzip",
+			wantDisposition: "attachment; filename=web.tar.gz",
+			want:            []string{"nginx.conf", "vars.yml"},
+		},
+		{
+			name:            "Whole repo as zip",
+			url:             "/a/fixture/v1.0.0.zip",
+			wantStatus:      http.StatusOK,
+			wantType:        "application/zip",
+			wantDisposition: "attachment; filename=fixture-v1.0.0.zip",
+			want:            []string{"config.yml", "roles/", "roles/web/", "roles/web/nginx.conf", "roles/web/vars.yml"},
+		},
+		{
+			name:       "Unknown format",
+			url:        "/a/fixture/v1/roles/web.rar",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Missing directory",
+			url:        "/a/fixture/v1/roles/db.zip",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Missing version",
+			url:        "/a/fixture/v2.zip",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("getRepoArchive() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("getRepoArchive() Content-Type = %v, want %v", got, tt.wantType)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != tt.wantDisposition {
+				t.Errorf("getRepoArchive() Content-Disposition = %v, want %v", got, tt.wantDisposition)
+			}
+
+			var got []string
+			if tt.wantType == "application/zip" {
+				zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+				if err != nil {
+					t.Fatalf("zip.NewReader() error = %v", err)
+				}
+				for _, f := range zr.File {
+					got = append(got, f.Name)
+				}
+			} else {
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("gzip.NewReader() error = %v", err)
+				}
+				tr := tar.NewReader(gz)
+				for hdr, err := tr.Next(); err == nil; hdr, err = tr.Next() {
+					got = append(got, hdr.Name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getRepoArchive() entries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Repeat downloads are identical and can be revalidated
+	var bodies [2][]byte
+	var etag string
+	for i := range bodies {
+		w := httptest.NewRecorder()
+		newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/a/fixture/v1.tar.gz", nil))
+		bodies[i] = w.Body.Bytes()
+		etag = w.Header().Get("ETag")
+	}
+	if !bytes.Equal(bodies[0], bodies[1]) {
+		t.Errorf("getRepoArchive() isn't reproducible")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/a/fixture/v1.tar.gz", nil)
+	req.Header.Set("If-None-Match", etag)
+	w := httptest.NewRecorder()
+	newRouter().ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("getRepoArchive() conditional status = %v, want %v", w.Code, http.StatusNotModified)
+	}
+}
@@ -0,0 +1,72 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/cfg8er/cfg8er/internal/cloudinit"
+	"github.com/cfg8er/cfg8er/internal/mediatype"
+	"github.com/gin-gonic/gin"
+)
+
+// getRepoCloudInit serves the file of cloud-init's NoCloud datasource of an
+// instance at a version. The path is the cloud-init directory, the instance
+// ID or MAC address and the file, eg.
+// /cloud-init/repo/v1/vms/52:54:00:12:34:56/meta-data, so the seedfrom URL
+// of an instance is the path without the file.
+func getRepoCloudInit(c *gin.Context) {
+	repo := c.Param("repo")
+	version := c.Param("version")
+	urlPath := path.Clean("/" + c.Param("path"))
+
+	name := path.Base(urlPath)
+	selector := path.Base(path.Dir(urlPath))
+	dirPath := path.Dir(path.Dir(urlPath))
+
+	r, ok := repoLookup[repo]
+
+	if !ok || selector == "/" {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	cloned := r.ClonedRepo()
+
+	v, err := cloned.ResolveSemVer(version)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	setVersionHeaders(c, v)
+
+	seed, err := cloudinit.Find(cloned, dirPath, v, selector)
+	if err == cloudinit.ErrNoInstance {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: Finding cloud-init instance %s in %s at %s in repo %s: %v\n", selector, dirPath, version, r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	data, err := seed.File(name)
+	if err == cloudinit.ErrNoFile {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: Assembling cloud-init %s of instance %s at %s in repo %s: %v\n", name, seed.InstanceID, version, r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if checkNotModified(c, contentETag(data), versionModified(v, v.Date)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, mediatype.Text, data)
+}
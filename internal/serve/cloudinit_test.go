@@ -0,0 +1,120 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+)
+
+func Test_getRepoCloudInit(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"fixture": newFixtureRepo(t, map[string]string{
+			"vms/instances.yml":                "defaults:\n  cloud-name: lab\nhosts:\n  web1:\n    mac: 52:54:00:12:34:56\n    local-hostname: web1.example.com\n",
+			"vms/default/meta-data":            "local-hostname: localhost\npublic-keys:\n  - ssh-ed25519 AAAA admin\n",
+			"vms/default/user-data":            "#cloud-config\npackages: [vim]\n",
+			"vms/default/network-config":       "version: 2\n",
+			"vms/web1/user-data.d/10-setup.sh": "#!/bin/sh\necho hello\n",
+			"vms/web1/network-config":          "version: 2\nethernets: {}\n",
+			"vms/db1/user-data":                "#cloud-config\npackages: [postgresql]\n",
+			"vms/db1/meta-data":                "instance-id: i-0123456789\n",
+			"vms/db1/vendor-data.d":            "Not a directory\n",
+		}, "v1.0.0"),
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		want       string
+		wantPrefix string
+	}{
+		{
+			name:       "meta-data by MAC",
+			url:        "/cloud-init/fixture/v1/vms/52:54:00:12:34:56/meta-data",
+			wantStatus: http.StatusOK,
+			want:       "cloud-name: lab\ninstance-id: web1\nlocal-hostname: web1.example.com\npublic-keys:\n- ssh-ed25519 AAAA admin\n",
+		},
+		{
+			name:       "meta-data by instance directory",
+			url:        "/cloud-init/fixture/v1/vms/db1/meta-data",
+			wantStatus: http.StatusOK,
+			want:       "cloud-name: lab\ninstance-id: i-0123456789\nlocal-hostname: localhost\npublic-keys:\n- ssh-ed25519 AAAA admin\n",
+		},
+		{
+			name:       "Multipart user-data",
+			url:        "/cloud-init/fixture/v1/vms/web1/user-data",
+			wantStatus: http.StatusOK,
+			wantPrefix: "Content-Type: multipart/mixed; boundary=",
+		},
+		{
+			name:       "No vendor-data",
+			url:        "/cloud-init/fixture/v1/vms/web1/vendor-data",
+			wantStatus: http.StatusOK,
+			want:       "",
+		},
+		{
+			name:       "Instance network-config",
+			url:        "/cloud-init/fixture/v1/vms/web1/network-config",
+			wantStatus: http.StatusOK,
+			want:       "version: 2\nethernets: {}\n",
+		},
+		{
+			name:       "Default network-config",
+			url:        "/cloud-init/fixture/v1/vms/db1/network-config",
+			wantStatus: http.StatusOK,
+			want:       "version: 2\n",
+		},
+		{
+			name:       "Unknown instance",
+			url:        "/cloud-init/fixture/v1/vms/web2/meta-data",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Default isn't an instance",
+			url:        "/cloud-init/fixture/v1/vms/default/meta-data",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "File isn't an instance",
+			url:        "/cloud-init/fixture/v1/vms/instances.yml/meta-data",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Parts directory that is a file",
+			url:        "/cloud-init/fixture/v1/vms/db1/vendor-data",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Unknown file",
+			url:        "/cloud-init/fixture/v1/vms/web1/instances.yml",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("getRepoCloudInit() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			got := w.Body.String()
+			if tt.wantPrefix != "" {
+				if !strings.HasPrefix(got, tt.wantPrefix) || !strings.Contains(got, "#!/bin/sh") {
+					t.Errorf("getRepoCloudInit() body = %q, want prefix %q", got, tt.wantPrefix)
+				}
+			} else if got != tt.want {
+				t.Errorf("getRepoCloudInit() body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package serve
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cfg8er/cfg8er/pkg/repository"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// hashETag returns a strong ETag from a Git object hash.
+func hashETag(hash plumbing.Hash) string {
+	return `"` + hash.String() + `"`
+}
+
+// contentETag returns a strong ETag for rendered content that isn't a Git
+// object.
+func contentETag(content []byte) string {
+	return fmt.Sprintf(`"%x"`, sha1.Sum(content))
+}
+
+// versionModified returns modified, the time the content at version v last
+// changed, or a zero time if v is floating. A floating version can move to a
+// commit made earlier, so only its ETag tells whether it changed.
+func versionModified(v *repository.Version, modified time.Time) time.Time {
+	if v.Floating {
+		return time.Time{}
+	}
+	return modified
+}
+
+// checkNotModified sets the ETag and Last-Modified validators of a response,
+// and its Cache-Control if one was set for the request as cacheControlKey,
+// and reports whether the client's cached copy is still current according to
+// the If-None-Match or, if absent, If-Modified-Since request headers. A zero
+// modified time isn't sent.
+func checkNotModified(c *gin.Context, etag string, modified time.Time) bool {
+	c.Header("ETag", etag)
+	if cacheControl := c.GetString(cacheControlKey); cacheControl != "" {
+		c.Header("Cache-Control", cacheControl)
+	}
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+
+	if ifModifiedSince := c.GetHeader("If-Modified-Since"); ifModifiedSince != "" && !modified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !modified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// etagMatches reports whether an If-None-Match header matches etag using weak
+// comparison.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
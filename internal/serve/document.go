@@ -0,0 +1,183 @@
+package serve
+
+import (
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/internal/merge"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// getRepoDocument responds with the structured document at filePath at a
+// version encoded as format f. The document is decoded from the file at
+// filePath, or if there's none from the file with the extension of another
+// format at the same path. If the repo merges documents the file is merged over
+// the files with the same name in each of its parent directories, from the
+// root of the repo down. Documents are encoded as Shell with the repo's shell
+// options.
+func getRepoDocument(c *gin.Context, r *config.Repo, cloned *repository.Repository, f format.Format, filePath string, v *repository.Version) {
+	opts := lookupRepoOptions(c.Param("repo")).merge
+
+	var files []*repository.File
+	var sourceFormat format.Format
+	for i, sourcePath := range format.Alternates(filePath) {
+		var err error
+		files, err = openDocumentFiles(cloned, opts, sourcePath, v)
+
+		if err == repository.ErrIsDir && i == 0 {
+			listRepoVersionDir(c, cloned, filePath, v)
+			return
+		}
+
+		if err == nil {
+			sourceFormat, _ = format.FromPath(sourcePath)
+			break
+		}
+	}
+
+	if files == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	if len(files) == 1 {
+		c.Header(headerBlob, files[0].Hash.String())
+	}
+
+	doc, err := decodeFiles(files, opts, sourceFormat)
+	if err != nil {
+		fmt.Printf("Error: Decoding %s at %s in repo %s: %v\n", filePath, v.Hash, r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// Not every document can be represented in every format, eg. TOML
+	// documents must be tables
+	var encoded []byte
+	if f == format.Shell {
+		encoded, err = format.EncodeShell(doc, lookupRepoOptions(c.Param("repo")).shell)
+	} else {
+		encoded, err = format.Encode(f, doc)
+	}
+	if err != nil {
+		c.String(http.StatusNotAcceptable, "%v\n", err)
+		return
+	}
+
+	if checkNotModified(c, contentETag(encoded), versionModified(v, files[0].Commit.Committer.When)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	contentType, ok := lookupRepoOptions(c.Param("repo")).contentTypes.Lookup(filePath)
+	if !ok {
+		contentType = f.MediaType()
+	}
+
+	if disposition := contentDisposition(r.ContentDisposition, filePath); disposition != "" {
+		c.Header("Content-Disposition", disposition)
+	}
+
+	c.Data(http.StatusOK, contentType, encoded)
+}
+
+// openDocumentFiles opens the file at filePath at a version, or if opts isn't
+// nil the file with its name in every directory down to it.
+func openDocumentFiles(cloned *repository.Repository, opts *merge.Options, filePath string, v *repository.Version) ([]*repository.File, error) {
+	if opts != nil {
+		return cloned.OpenHierarchyAtVersion(filePath, v)
+	}
+
+	file, err := cloned.OpenAtVersion(filePath, v)
+	if err != nil {
+		return nil, err
+	}
+	return []*repository.File{file}, nil
+}
+
+// decodeFiles decodes files, ordered from the least to the most specific, as
+// format f and merges them if opts isn't nil. The files are closed.
+func decodeFiles(files []*repository.File, opts *merge.Options, f format.Format) (interface{}, error) {
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	var docs []interface{}
+	for _, file := range files {
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if opts != nil && f == format.YAML {
+			if err := merge.CheckYAML(data); err != nil {
+				return nil, fmt.Errorf("Blob %v: %v", file.Hash, err)
+			}
+		}
+
+		doc, err := format.Decode(f, data)
+		if err != nil {
+			return nil, fmt.Errorf("Blob %v: %v", file.Hash, err)
+		}
+		docs = append(docs, doc)
+	}
+
+	if opts == nil {
+		return docs[0], nil
+	}
+	return opts.Merge(docs), nil
+}
+
+// requestedFormat returns the format named by the output query parameter, or
+// else the format accepted as by acceptedFormat. Returns false if the query
+// parameter isn't the name of a format. The format query parameter is left to
+// directory listings.
+func requestedFormat(c *gin.Context, f format.Format) (format.Format, bool) {
+	if name := c.Query("output"); name != "" {
+		return format.FromName(name)
+	}
+
+	return acceptedFormat(c.GetHeader("Accept"), f), true
+}
+
+// acceptedFormat returns the format of the media type with the highest quality
+// in an Accept header, preferring the earliest of those with the same quality.
+// Returns f if the header accepts none of the formats.
+func acceptedFormat(accept string, f format.Format) format.Format {
+	accepted := f
+	best := 0.0
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+
+		candidate, ok := format.FromMediaType(mediaType)
+		if !ok {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		if quality > best {
+			accepted, best = candidate, quality
+		}
+	}
+
+	return accepted
+}
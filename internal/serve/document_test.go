@@ -0,0 +1,287 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/cfg8er/cfg8er/internal/format"
+)
+
+func Test_getRepoDocument_merge(t *testing.T) {
+	files := map[string]string{
+		"config.yml":       "name: root\npackages: [vim]\nntp:\n  enabled: true\n  servers: [ntp1]\n",
+		"a/config.yml":     "name: a\npackages: [curl]\n",
+		"a/b/c/config.yml": "ntp:\n  servers: ['!reset', ntp2]\n",
+		"a/b/c/vars.json":  "{\"port\": 80}\n",
+		"a/b/c/notes.txt":  "Not merged\n",
+		"a/x/config.yml":   "broken: !reset\n",
+		"vars.json":        "{\"port\": 8080, \"host\": \"web\"}\n",
+	}
+	repoLookup = map[string]*config.Repo{
+		"deep":     newFixtureRepo(t, files, "v1.0.0"),
+		"override": newFixtureRepo(t, files, "v1.0.0"),
+		"raw":      newFixtureRepo(t, files, "v1.0.0"),
+	}
+	repoLookup["deep"].Merge = "deep"
+	repoLookup["deep"].MergeLists = "append"
+	repoLookup["override"].Merge = "override"
+
+	serverConfig = &config.Server{}
+	if err := loadRepoOptions(); err != nil {
+		t.Fatalf("loadRepoOptions() error = %v", err)
+	}
+	defer func() { repoOptionsLookup = nil }()
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantType   string
+		want       string
+	}{
+		{
+			name:       "Deep merge appending lists",
+			url:        "/r/deep/v1/a/b/c/config.yml",
+			wantStatus: http.StatusOK,
+			wantType:   "application/yaml",
+			want:       "name: a\nntp:\n  enabled: true\n  servers:\n  - ntp2\npackages:\n- vim\n- curl\n",
+		},
+		{
+			name:       "Override",
+			url:        "/r/override/v1/a/b/c/config.yml",
+			wantStatus: http.StatusOK,
+			wantType:   "application/yaml",
+			want:       "name: a\nntp:\n  servers:\n  - ntp2\npackages:\n- curl\n",
+		},
+		{
+			name:       "JSON",
+			url:        "/r/deep/v1/a/b/c/vars.json",
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			want:       "{\n  \"host\": \"web\",\n  \"port\": 80\n}\n",
+		},
+		{
+			name:       "Not a structured file",
+			url:        "/r/deep/v1/a/b/c/notes.txt",
+			wantStatus: http.StatusOK,
+			wantType:   "text/plain; charset=utf-8",
+			want:       "Not merged\n",
+		},
+		{
+			name:       "Merging disabled",
+			url:        "/r/raw/v1/a/b/c/config.yml",
+			wantStatus: http.StatusOK,
+			wantType:   "application/yaml",
+			want:       "ntp:\n  servers: ['!reset', ntp2]\n",
+		},
+		{
+			name:       "Unquoted reset tag",
+			url:        "/r/deep/v1/a/x/config.yml",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Missing directory",
+			url:        "/r/deep/v1/a/missing/config.yml",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Missing file",
+			url:        "/r/deep/v1/a/b/c/missing.yml",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("getRepoDocument() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("getRepoDocument() Content-Type = %v, want %v", got, tt.wantType)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("getRepoDocument() body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The ETag of merged files is that of the merged document
+	req := httptest.NewRequest(http.MethodGet, "/r/deep/v1/a/b/c/config.yml", nil)
+	w := httptest.NewRecorder()
+	newRouter().ServeHTTP(w, req)
+
+	req = httptest.NewRequest(http.MethodGet, "/r/deep/v1/a/b/c/config.yml", nil)
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	newRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("getRepoDocument() conditional status = %v, want %v", w.Code, http.StatusNotModified)
+	}
+}
+
+func Test_getRepoDocument_convert(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"fixture": newFixtureRepo(t, map[string]string{
+			"config.yml": "name: web\nports: [80, 443]\ntls:\n  enabled: true\n",
+			"list.yml":   "- a\n- b\n",
+			"app.toml":   "name = \"app\"\n",
+		}, "v1.0.0"),
+	}
+
+	repoLookup["fixture"].ShellPrefix = "CFG"
+	serverConfig = &config.Server{}
+	if err := loadRepoOptions(); err != nil {
+		t.Fatalf("loadRepoOptions() error = %v", err)
+	}
+	defer func() { repoOptionsLookup = nil }()
+
+	tests := []struct {
+		name       string
+		url        string
+		accept     string
+		wantStatus int
+		wantType   string
+		want       string
+	}{
+		{
+			name:       "Unconverted",
+			url:        "/r/fixture/v1/config.yml",
+			wantStatus: http.StatusOK,
+			wantType:   "application/yaml",
+			want:       "name: web\nports: [80, 443]\ntls:\n  enabled: true\n",
+		},
+		{
+			name:       "Extension swapped to JSON",
+			url:        "/r/fixture/v1/config.json",
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			want:       "{\n  \"name\": \"web\",\n  \"ports\": [\n    80,\n    443\n  ],\n  \"tls\": {\n    \"enabled\": true\n  }\n}\n",
+		},
+		{
+			name:       "Extension swapped to TOML",
+			url:        "/r/fixture/v1/config.toml",
+			wantStatus: http.StatusOK,
+			wantType:   "application/toml",
+			want:       "name = \"web\"\nports = [80, 443]\n\n[tls]\n  enabled = true\n",
+		},
+		{
+			name:       "Extension swapped from TOML",
+			url:        "/r/fixture/v1/app.yaml",
+			wantStatus: http.StatusOK,
+			wantType:   "application/yaml",
+			want:       "name: app\n",
+		},
+		{
+			name:       "Accept header",
+			url:        "/r/fixture/v1/config.yml",
+			accept:     "text/html, application/json;q=0.9, application/toml;q=0.5",
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			want:       "{\n  \"name\": \"web\",\n  \"ports\": [\n    80,\n    443\n  ],\n  \"tls\": {\n    \"enabled\": true\n  }\n}\n",
+		},
+		{
+			name:       "Accept header of other media types",
+			url:        "/r/fixture/v1/config.yml",
+			accept:     "text/html, */*;q=0.8",
+			wantStatus: http.StatusOK,
+			wantType:   "application/yaml",
+			want:       "name: web\nports: [80, 443]\ntls:\n  enabled: true\n",
+		},
+		{
+			name:       "Output query parameter",
+			url:        "/r/fixture/v1/config.yml?output=json",
+			accept:     "application/toml",
+			wantStatus: http.StatusOK,
+			wantType:   "application/json",
+			want:       "{\n  \"name\": \"web\",\n  \"ports\": [\n    80,\n    443\n  ],\n  \"tls\": {\n    \"enabled\": true\n  }\n}\n",
+		},
+		{
+			name:       "Shell variables",
+			url:        "/r/fixture/v1/config.yml?output=shell",
+			wantStatus: http.StatusOK,
+			wantType:   "text/x-shellscript",
+			want:       "CFG_NAME=\"web\"\nCFG_PORTS_0=\"80\"\nCFG_PORTS_1=\"443\"\nCFG_TLS_ENABLED=\"true\"\n",
+		},
+		{
+			name:       "Unknown output query parameter",
+			url:        "/r/fixture/v1/config.yml?output=ini",
+			wantStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:       "Listing format query parameter",
+			url:        "/r/fixture/v1/config.yml?format=text",
+			wantStatus: http.StatusOK,
+			wantType:   "application/yaml",
+			want:       "name: web\nports: [80, 443]\ntls:\n  enabled: true\n",
+		},
+		{
+			name:       "Not representable",
+			url:        "/r/fixture/v1/list.toml",
+			wantStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:       "Missing in every format",
+			url:        "/r/fixture/v1/missing.json",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("getRepoDocument() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("getRepoDocument() Content-Type = %v, want %v", got, tt.wantType)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("getRepoDocument() Vary = %v, want Accept", got)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("getRepoDocument() body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_acceptedFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   format.Format
+	}{
+		{name: "No header", want: format.YAML},
+		{name: "JSON", accept: "application/json", want: format.JSON},
+		{name: "Highest quality", accept: "application/json;q=0.5, application/toml", want: format.TOML},
+		{name: "Earliest of the same quality", accept: "application/toml, application/json", want: format.TOML},
+		{name: "Not acceptable", accept: "application/json;q=0", want: format.YAML},
+		{name: "Other media types", accept: "text/html, */*", want: format.YAML},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := acceptedFormat(tt.accept, format.YAML); got != tt.want {
+				t.Errorf("acceptedFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package serve
+
+import (
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/cfg8er/cfg8er/internal/testrepo"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+)
+
+// newFixtureRepo commits files, a map of path to contents, to a local repo in a
+// temporary dir, tags the commit with each of tags and returns a config.Repo
+// serving its clone with every ref type enabled.
+func newFixtureRepo(t *testing.T, files map[string]string, tags ...string) *config.Repo {
+	fixture := testrepo.New(t)
+	hash := fixture.Commit("Fixture", files)
+	for _, tag := range tags {
+		fixture.Tag(tag, hash)
+	}
+
+	r := &config.Repo{URL: fixture.Dir, EnableSemversTags: true, EnableTags: true, EnableCommits: true}
+	cloned, err := repository.CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("newFixtureRepo() error = %v", err)
+	}
+	if err := r.Configure(&cloned); err != nil {
+		t.Fatalf("newFixtureRepo() error = %v", err)
+	}
+	r.SetClonedRepo(&cloned)
+
+	return r
+}
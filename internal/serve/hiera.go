@@ -0,0 +1,154 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/internal/hiera"
+	"github.com/gin-gonic/gin"
+)
+
+// maxFactsSize is the largest facts body read by getRepoHiera.
+const maxFactsSize = 1 << 20
+
+// Query parameters of Hiera lookups that aren't facts.
+const (
+	hieraKeyParam   = "key"
+	hieraMergeParam = "merge"
+)
+
+// getRepoHiera looks up Hiera data with the hierarchy of the hiera.yaml in a
+// directory at a version, eg. /hiera/repo/v1/production?key=ntp::servers. The
+// facts interpolated into the hierarchy are the JSON body of a POST, or the
+// query parameters of a GET with dots in their names separating the keys of
+// nested facts, eg. os.family=RedHat. The value of the key query parameter is
+// served, or every key if it isn't set. The merge query parameter overrides
+// the merge strategy of the lookup_options.
+func getRepoHiera(c *gin.Context) {
+	repo := c.Param("repo")
+	version := c.Param("version")
+	dirPath := path.Clean("/" + c.Param("path"))
+
+	r, ok := repoLookup[repo]
+
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	strategy := c.Query(hieraMergeParam)
+	if strategy != "" && !hiera.ValidStrategy(strategy) {
+		c.String(http.StatusBadRequest, "Invalid merge strategy %s\n", strategy)
+		return
+	}
+
+	facts, err := requestFacts(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, "%v\n", err)
+		return
+	}
+
+	cloned := r.ClonedRepo()
+
+	v, err := cloned.ResolveSemVer(version)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	setVersionHeaders(c, v)
+
+	data, err := hiera.Load(cloned, dirPath, v, facts)
+	if err == hiera.ErrNoConfig {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: Loading Hiera data %s at %s in repo %s: %v\n", dirPath, version, r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	var output interface{}
+	if key := c.Query(hieraKeyParam); key != "" {
+		var found bool
+		output, found, err = data.Lookup(key, strategy)
+		if err == nil && !found {
+			c.Status(http.StatusNotFound)
+			return
+		}
+	} else {
+		output, err = data.All(strategy)
+	}
+	if err != nil {
+		fmt.Printf("Error: Looking up Hiera data %s at %s in repo %s: %v\n", dirPath, version, r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	encoded, err := json.Marshal(output)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if checkNotModified(c, contentETag(encoded), versionModified(v, v.Date)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", encoded)
+}
+
+// requestFacts returns the facts of a Hiera lookup, decoded from the JSON body
+// of a POST or the query parameters of a GET.
+func requestFacts(c *gin.Context) (map[string]interface{}, error) {
+	facts := map[string]interface{}{}
+
+	if c.Request.Method != http.MethodPost {
+		for name, values := range c.Request.URL.Query() {
+			if name == hieraKeyParam || name == hieraMergeParam {
+				continue
+			}
+			keys := strings.Split(strings.TrimPrefix(name, "facts."), ".")
+			setFact(facts, keys, values[len(values)-1])
+		}
+		return facts, nil
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxFactsSize))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return facts, nil
+	}
+
+	doc, err := format.Decode(format.JSON, body)
+	if err != nil {
+		return nil, fmt.Errorf("Facts: %v", err)
+	}
+	if facts, ok := doc.(map[string]interface{}); ok {
+		return facts, nil
+	}
+	return nil, fmt.Errorf("Facts must be a JSON object, not %T", doc)
+}
+
+// setFact sets the fact at a path of keys in facts, adding the hashes on the
+// way.
+func setFact(facts map[string]interface{}, keys []string, value string) {
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := facts[key].(map[string]interface{})
+		if !ok {
+			nested = map[string]interface{}{}
+			facts[key] = nested
+		}
+		facts = nested
+	}
+	facts[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,157 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+)
+
+func Test_getRepoHiera(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"fixture": newFixtureRepo(t, map[string]string{
+			"production/hiera.yaml": `version: 5
+defaults:
+  datadir: data
+hierarchy:
+  - name: Per-node data
+    path: "nodes/%{trusted.certname}.yaml"
+  - name: Per-OS defaults
+    path: "os/%{facts.os.family}.yaml"
+  - name: Common data
+    path: common.yaml
+`,
+			"production/data/nodes/web1.example.com.yaml": "ntp::servers:\n  - ntp1.example.com\nusers:\n  alice:\n    shell: zsh\n",
+			"production/data/os/RedHat.yaml":              "ntp::servers:\n  - ntp2.example.com\nusers:\n  alice:\n    uid: 1000\n",
+			"production/data/common.yaml":                 "ntp::servers:\n  - pool.ntp.org\nmotd: \"Welcome to %{facts.fqdn}\"\nlookup_options:\n  users:\n    merge: deep\n",
+			"broken/hiera.yaml":                           "version: 5\nhierarchy:\n  - name: Common\n    path: common.yaml\n",
+			"broken/data/common.yaml":                     "- not a hash\n",
+			"dir/hiera.yaml/notes":                        "Not a config\n",
+			"datadir/hiera.yaml":                          "version: 5\nhierarchy:\n  - name: Common\n    path: common.yaml\n",
+			"datadir/data/common.yaml/notes":              "Not data\n",
+		}, "v1.0.0"),
+	}
+
+	redHatFacts := `{"fqdn": "web1.example.com", "os": {"family": "RedHat"}, "trusted": {"certname": "web1.example.com"}}`
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "Key",
+			method:     http.MethodPost,
+			url:        "/hiera/fixture/v1/production?key=ntp::servers",
+			body:       redHatFacts,
+			wantStatus: http.StatusOK,
+			want:       `["ntp1.example.com"]`,
+		},
+		{
+			name:       "Unique merge",
+			method:     http.MethodPost,
+			url:        "/hiera/fixture/v1/production?key=ntp::servers&merge=unique",
+			body:       redHatFacts,
+			wantStatus: http.StatusOK,
+			want:       `["ntp1.example.com","ntp2.example.com","pool.ntp.org"]`,
+		},
+		{
+			name:       "Deep merge from lookup_options",
+			method:     http.MethodPost,
+			url:        "/hiera/fixture/v1/production?key=users",
+			body:       redHatFacts,
+			wantStatus: http.StatusOK,
+			want:       `{"alice":{"shell":"zsh","uid":1000}}`,
+		},
+		{
+			name:       "Facts in the query",
+			method:     http.MethodGet,
+			url:        "/hiera/fixture/v1/production?key=ntp::servers&facts.os.family=RedHat",
+			wantStatus: http.StatusOK,
+			want:       `["ntp2.example.com"]`,
+		},
+		{
+			name:       "All",
+			method:     http.MethodGet,
+			url:        "/hiera/fixture/v1/production?fqdn=db1.example.com",
+			wantStatus: http.StatusOK,
+			want:       `{"ntp::servers":["pool.ntp.org"],"motd":"Welcome to db1.example.com","lookup_options":{"users":{"merge":"deep"}}}`,
+		},
+		{
+			name:       "Missing key",
+			method:     http.MethodGet,
+			url:        "/hiera/fixture/v1/production?key=dns::servers",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Invalid merge strategy",
+			method:     http.MethodGet,
+			url:        "/hiera/fixture/v1/production?merge=append",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid facts",
+			method:     http.MethodPost,
+			url:        "/hiera/fixture/v1/production",
+			body:       `["web1"]`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "No hiera.yaml",
+			method:     http.MethodGet,
+			url:        "/hiera/fixture/v1",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Data that isn't a hash",
+			method:     http.MethodGet,
+			url:        "/hiera/fixture/v1/broken",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "hiera.yaml that is a directory",
+			method:     http.MethodGet,
+			url:        "/hiera/fixture/v1/dir",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Data file that is a directory",
+			method:     http.MethodGet,
+			url:        "/hiera/fixture/v1/datadir",
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("getRepoHiera() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("getRepoHiera() body = %s, error = %v", w.Body, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("getRepoHiera() body = %s, want %s", w.Body, tt.want)
+			}
+		})
+	}
+}
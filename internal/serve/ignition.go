@@ -0,0 +1,60 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cfg8er/cfg8er/internal/ignition"
+	"github.com/gin-gonic/gin"
+)
+
+// getRepoIgnition serves the Ignition config of a role directory at a
+// version, eg. /ignition/repo/v1/fcos/worker, assembled from the files and
+// units of the directory and its parents as by ignition.Assemble.
+func getRepoIgnition(c *gin.Context) {
+	repo := c.Param("repo")
+	version := c.Param("version")
+	dirPath := c.Param("path")
+
+	r, ok := repoLookup[repo]
+
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	cloned := r.ClonedRepo()
+
+	v, err := cloned.ResolveSemVer(version)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	setVersionHeaders(c, v)
+
+	config, err := ignition.Assemble(cloned, dirPath, v)
+	if err == ignition.ErrNoConfig {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: Assembling Ignition config %s at %s in repo %s: %v\n", dirPath, version, r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if checkNotModified(c, contentETag(data), versionModified(v, v.Date)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
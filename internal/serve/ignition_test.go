@@ -0,0 +1,127 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+)
+
+func Test_getRepoIgnition(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"fixture": newFixtureRepo(t, map[string]string{
+			"fcos/files/etc/motd":                             "Hello\n",
+			"fcos/units/node-exporter.service":                "[Service]\nExecStart=/usr/bin/node_exporter\n[Install]\nWantedBy=multi-user.target\n",
+			"fcos/ignition.yml":                               "passwd:\n  users:\n    - name: core\n      sshAuthorizedKeys:\n        - ssh-ed25519 AAAA admin\n",
+			"fcos/worker/files/etc/motd":                      "Worker\n",
+			"fcos/worker/units/kubelet.service.d/10-gpu.conf": "[Service]\n",
+			"fcos/worker/ignition.json":                       `{"storage": {"files": [{"path": "/etc/motd", "overwrite": true}]}}`,
+			"fcos/broken/ignition.yml":                        "- not a mapping\n",
+		}, "v1.0.0"),
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "Role",
+			url:        "/ignition/fixture/v1/fcos",
+			wantStatus: http.StatusOK,
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"passwd": {"users": [{"name": "core", "sshAuthorizedKeys": ["ssh-ed25519 AAAA admin"]}]},
+				"storage": {"files": [{
+					"path": "/etc/motd",
+					"mode": 420,
+					"contents": {
+						"source": "data:;base64,SGVsbG8K",
+						"verification": {"hash": "sha512-c2bad2223811194582af4d1508ac02cd69eeeeedeeb98d54fcae4dcefb13cc882e7640328206603d3fb9cd5f949a9be0db054dd34fbfa190c498a5fe09750cef"}
+					}
+				}]},
+				"systemd": {"units": [{
+					"name": "node-exporter.service",
+					"enabled": true,
+					"contents": "[Service]\nExecStart=/usr/bin/node_exporter\n[Install]\nWantedBy=multi-user.target\n"
+				}]}
+			}`,
+		},
+		{
+			name:       "Child role",
+			url:        "/ignition/fixture/v1/fcos/worker",
+			wantStatus: http.StatusOK,
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"passwd": {"users": [{"name": "core", "sshAuthorizedKeys": ["ssh-ed25519 AAAA admin"]}]},
+				"storage": {"files": [{
+					"path": "/etc/motd",
+					"mode": 420,
+					"overwrite": true,
+					"contents": {
+						"source": "data:;base64,V29ya2VyCg==",
+						"verification": {"hash": "sha512-9548aec4f2bb19acd9866c344ee1b636327a77911d0feb283c048b5b2def33f563adab49a0197629981ecbc8e8e3f29217fa53461ecacc946391cd9130cb1264"}
+					}
+				}]},
+				"systemd": {"units": [
+					{
+						"name": "node-exporter.service",
+						"enabled": true,
+						"contents": "[Service]\nExecStart=/usr/bin/node_exporter\n[Install]\nWantedBy=multi-user.target\n"
+					},
+					{"name": "kubelet.service", "dropins": [{"name": "10-gpu.conf", "contents": "[Service]\n"}]}
+				]}
+			}`,
+		},
+		{
+			name:       "Missing role",
+			url:        "/ignition/fixture/v1/fcos/storage",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Role that is a file",
+			url:        "/ignition/fixture/v1/fcos/ignition.yml",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Root",
+			url:        "/ignition/fixture/v1/",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Invalid fragment",
+			url:        "/ignition/fixture/v1/fcos/broken",
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("getRepoIgnition() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("getRepoIgnition() body = %s, error = %v", w.Body, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("getRepoIgnition() body = %s, want %s", w.Body, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/internal/hostmap"
+	"github.com/cfg8er/cfg8er/internal/ipxe"
+	"github.com/cfg8er/cfg8er/internal/mediatype"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// getRepoIPXE serves the iPXE script template at a path at a version rendered
+// with the variables of the host booting it, eg.
+// /ipxe/repo/v1/boot.ipxe?mac=${mac}&uuid=${uuid}&hostname=${hostname}. The
+// host is looked up by the mac, uuid and hostname query parameters in the
+// repo's ipxe_host_map at the same version, and responds with 404 Not Found if
+// none matches. Without any of them the template is rendered with the defaults
+// of the host map.
+func getRepoIPXE(c *gin.Context) {
+	repo := c.Param("repo")
+	version := c.Param("version")
+	templatePath := path.Clean("/" + c.Param("path"))
+
+	r, ok := repoLookup[repo]
+
+	if !ok || path.Ext(templatePath) != ipxe.Extension {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	cloned := r.ClonedRepo()
+
+	v, err := cloned.ResolveSemVer(version)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	setVersionHeaders(c, v)
+
+	text, err := cloned.ReadAtVersion(templatePath, v)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	hostMapPath := lookupRepoOptions(repo).ipxeHostMap
+
+	hostMap, err := loadHostMap(cloned, hostMapPath, v)
+	if err != nil {
+		fmt.Printf("Error: Loading host map %s at %s in repo %s: %v\n", hostMapPath, version, r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	vars := hostMap.Defaults()
+	mac, uuid, hostname := c.Query("mac"), c.Query("uuid"), c.Query("hostname")
+	if mac != "" || uuid != "" || hostname != "" {
+		if vars, ok = hostMap.Lookup(mac, uuid, hostname); !ok {
+			c.Status(http.StatusNotFound)
+			return
+		}
+	}
+
+	script, err := ipxe.Render(templatePath, text, vars)
+	if err != nil {
+		fmt.Printf("Error: Rendering %s at %s in repo %s: %v\n", templatePath, version, r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if checkNotModified(c, contentETag(script), versionModified(v, v.Date)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, mediatype.Text, script)
+}
+
+// loadHostMap reads the iPXE host map at hostMapPath at a version. A missing
+// host map is empty.
+func loadHostMap(cloned *repository.Repository, hostMapPath string, v *repository.Version) (*hostmap.HostMap, error) {
+	data, err := cloned.ReadAtVersion(hostMapPath, v)
+	if err == repository.ErrNotExist {
+		return hostmap.New(nil)
+	} else if err != nil {
+		return nil, err
+	}
+
+	f, _ := format.FromPath(hostMapPath)
+	doc, err := format.Decode(f, data)
+	if err != nil {
+		return nil, err
+	}
+	return hostmap.New(doc)
+}
@@ -0,0 +1,111 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/cfg8er/cfg8er/internal/mediatype"
+)
+
+func Test_getRepoIPXE(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"fixture": newFixtureRepo(t, map[string]string{
+			"pxe/hosts.json": `{"defaults": {"server": "http://boot.example.com", "image": "menu"},
+				"hosts": {"node01": {"mac": "52:54:00:12:34:56", "image": "rocky9"}}}`,
+			"boot.ipxe":   "{{/* Boots the image of the host */}}\n#!ipxe\nchain {{ .server }}/{{ .image }}.ipxe\n",
+			"broken.ipxe": "#!ipxe\nkernel {{ .kernel }}\n",
+			"boot.txt":    "#!ipxe\nboot\n",
+		}, "v1.0.0"),
+	}
+	plain := map[string]string{"boot.ipxe": "#!ipxe\nboot\n", "hosts.json/README": "Not a host map\n"}
+	repoLookup["nohostmap"] = newFixtureRepo(t, plain, "v1.0.0")
+	repoLookup["dirhostmap"] = newFixtureRepo(t, plain, "v1.0.0")
+	repoLookup["fixture"].IPXEHostMap = "pxe/hosts.json"
+	repoLookup["dirhostmap"].IPXEHostMap = "hosts.json"
+	serverConfig = &config.Server{}
+	if err := loadRepoOptions(); err != nil {
+		t.Fatalf("loadRepoOptions() error = %v", err)
+	}
+	defer func() { repoOptionsLookup = nil }()
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "MAC",
+			url:        "/ipxe/fixture/v1/boot.ipxe?mac=52-54-00-12-34-56&uuid=&hostname=",
+			wantStatus: http.StatusOK,
+			want:       "#!ipxe\nchain http://boot.example.com/rocky9.ipxe\n",
+		},
+		{
+			name:       "Hostname",
+			url:        "/ipxe/fixture/v1/boot.ipxe?hostname=node01",
+			wantStatus: http.StatusOK,
+			want:       "#!ipxe\nchain http://boot.example.com/rocky9.ipxe\n",
+		},
+		{
+			name:       "Defaults",
+			url:        "/ipxe/fixture/v1/boot.ipxe",
+			wantStatus: http.StatusOK,
+			want:       "#!ipxe\nchain http://boot.example.com/menu.ipxe\n",
+		},
+		{
+			name:       "Unknown host",
+			url:        "/ipxe/fixture/v1/boot.ipxe?mac=52:54:00:00:00:00",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Missing variable",
+			url:        "/ipxe/fixture/v1/broken.ipxe?hostname=node01",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Not a template",
+			url:        "/ipxe/fixture/v1/boot.txt",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Missing template",
+			url:        "/ipxe/fixture/v1/missing.ipxe",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Missing host map",
+			url:        "/ipxe/nohostmap/v1/boot.ipxe",
+			wantStatus: http.StatusOK,
+			want:       "#!ipxe\nboot\n",
+		},
+		{
+			name:       "Unreadable host map",
+			url:        "/ipxe/dirhostmap/v1/boot.ipxe",
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("getRepoIPXE() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			if got := w.Header().Get("Content-Type"); got != mediatype.Text {
+				t.Errorf("getRepoIPXE() Content-Type = %v, want %v", got, mediatype.Text)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("getRepoIPXE() body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
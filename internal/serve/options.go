@@ -0,0 +1,125 @@
+package serve
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/cfg8er/cfg8er/internal/acl"
+	"github.com/cfg8er/cfg8er/internal/ansible"
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/internal/ipxe"
+	"github.com/cfg8er/cfg8er/internal/mediatype"
+	"github.com/cfg8er/cfg8er/internal/merge"
+)
+
+// repoOptions are the settings of a repo parsed when the config is loaded.
+type repoOptions struct {
+	// allowHosts are the networks of the clients allowed to request the repo,
+	// or empty if every client is.
+	allowHosts acl.List
+	// contentTypes are the repo's content_types overrides.
+	contentTypes mediatype.Overrides
+	// merge is how documents are merged over those in parent directories, or
+	// nil if they aren't.
+	merge *merge.Options
+	// shell is how documents are encoded as Shell.
+	shell *format.ShellOptions
+	// ansible is how Ansible variables are merged.
+	ansible *merge.Options
+	// ipxeHostMap is the path of the iPXE host map in the repo.
+	ipxeHostMap string
+}
+
+// defaultRepoOptions are the options of repos without any of the settings.
+var defaultRepoOptions = &repoOptions{
+	shell:       format.DefaultShellOptions,
+	ansible:     &merge.Options{Mode: merge.Override, Lists: merge.ListsReplace},
+	ipxeHostMap: "/" + ipxe.DefaultHostMap,
+}
+
+// lookupRepoOptions returns the options of the named repo, or
+// defaultRepoOptions if they haven't been loaded.
+func lookupRepoOptions(name string) *repoOptions {
+	if opts, ok := repoOptionsLookup[name]; ok {
+		return opts
+	}
+	return defaultRepoOptions
+}
+
+// loadRepoOptions parses the trusted_proxies and the settings of every repo.
+// Repos without allow_hosts fall back to the server allow_hosts.
+func loadRepoOptions() error {
+	var err error
+
+	trustedProxies, err = acl.Parse(serverConfig.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("Server trusted_proxies: %v", err)
+	}
+
+	defaultAllowHosts, err := acl.Parse(serverConfig.AllowHosts)
+	if err != nil {
+		return fmt.Errorf("Server allow_hosts: %v", err)
+	}
+
+	repoOptionsLookup = map[string]*repoOptions{}
+	for n, r := range repoLookup {
+		repoOptionsLookup[n], err = newRepoOptions(r, defaultAllowHosts)
+		if err != nil {
+			return fmt.Errorf("Repo %s %v", n, err)
+		}
+	}
+
+	return nil
+}
+
+// newRepoOptions parses the settings of r. Clients in defaultAllowHosts are
+// allowed if r has no allow_hosts.
+func newRepoOptions(r *config.Repo, defaultAllowHosts acl.List) (*repoOptions, error) {
+	opts := &repoOptions{allowHosts: defaultAllowHosts}
+	var err error
+
+	if len(r.AllowHosts) > 0 {
+		opts.allowHosts, err = acl.Parse(r.AllowHosts)
+		if err != nil {
+			return nil, fmt.Errorf("allow_hosts: %v", err)
+		}
+	}
+
+	opts.contentTypes, err = mediatype.NewOverrides(r.ContentTypes)
+	if err != nil {
+		return nil, fmt.Errorf("content_types: %v", err)
+	}
+
+	switch r.ContentDisposition {
+	case "", dispositionAttachment, dispositionInline, dispositionNone:
+	default:
+		return nil, fmt.Errorf("content_disposition: Invalid disposition %s", r.ContentDisposition)
+	}
+
+	opts.merge, err = r.MergeOptions()
+	if err != nil {
+		return nil, fmt.Errorf("merge: %v", err)
+	}
+
+	opts.shell, err = r.ShellOptions()
+	if err != nil {
+		return nil, fmt.Errorf("shell options: %v", err)
+	}
+
+	opts.ansible, err = ansible.MergeOptions(r.AnsibleHashBehaviour)
+	if err != nil {
+		return nil, fmt.Errorf("ansible_hash_behaviour: %v", err)
+	}
+
+	hostMap := r.IPXEHostMap
+	if hostMap == "" {
+		hostMap = ipxe.DefaultHostMap
+	}
+	if _, ok := format.FromPath(hostMap); !ok {
+		return nil, fmt.Errorf("ipxe_host_map: %s isn't a YAML, JSON or TOML file", hostMap)
+	}
+	opts.ipxeHostMap = path.Clean("/" + hostMap)
+
+	return opts, nil
+}
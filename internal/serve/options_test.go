@@ -0,0 +1,65 @@
+package serve
+
+import (
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/internal/merge"
+)
+
+func Test_newRepoOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		repo    *config.Repo
+		want    *repoOptions
+		wantErr bool
+	}{
+		{
+			name: "Defaults",
+			repo: &config.Repo{},
+			want: defaultRepoOptions,
+		},
+		{
+			name: "Settings",
+			repo: &config.Repo{Merge: merge.Deep, ShellPrefix: "WW", AnsibleHashBehaviour: "merge", IPXEHostMap: "pxe/hosts.json"},
+			want: &repoOptions{
+				merge:       &merge.Options{Mode: merge.Deep, Lists: merge.ListsReplace},
+				shell:       &format.ShellOptions{Prefix: "WW", Separator: "_", Case: format.CaseUpper},
+				ansible:     &merge.Options{Mode: merge.Deep, Lists: merge.ListsReplace},
+				ipxeHostMap: "/pxe/hosts.json",
+			},
+		},
+		{name: "Invalid allow_hosts", repo: &config.Repo{AllowHosts: []string{"10.0.0.0/33"}}, wantErr: true},
+		{name: "Invalid content_disposition", repo: &config.Repo{ContentDisposition: "download"}, wantErr: true},
+		{name: "Invalid merge", repo: &config.Repo{Merge: "shallow"}, wantErr: true},
+		{name: "Invalid shell_case", repo: &config.Repo{ShellCase: "title"}, wantErr: true},
+		{name: "Invalid ansible_hash_behaviour", repo: &config.Repo{AnsibleHashBehaviour: "append"}, wantErr: true},
+		{name: "Invalid ipxe_host_map", repo: &config.Repo{IPXEHostMap: "hosts.ini"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newRepoOptions(tt.repo, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newRepoOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			if (got.merge == nil) != (tt.want.merge == nil) || (got.merge != nil && *got.merge != *tt.want.merge) {
+				t.Errorf("newRepoOptions() merge = %v, want %v", got.merge, tt.want.merge)
+			}
+			if *got.shell != *tt.want.shell {
+				t.Errorf("newRepoOptions() shell = %v, want %v", got.shell, tt.want.shell)
+			}
+			if *got.ansible != *tt.want.ansible {
+				t.Errorf("newRepoOptions() ansible = %v, want %v", got.ansible, tt.want.ansible)
+			}
+			if got.ipxeHostMap != tt.want.ipxeHostMap {
+				t.Errorf("newRepoOptions() ipxeHostMap = %v, want %v", got.ipxeHostMap, tt.want.ipxeHostMap)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package serve
+
+import (
+	"time"
+
+	"github.com/cfg8er/cfg8er/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// Headers telling clients exactly what a version resolved to.
+const (
+	headerRef        = "X-Cfg8er-Ref"
+	headerTag        = "X-Cfg8er-Tag"
+	headerBranch     = "X-Cfg8er-Branch"
+	headerCommit     = "X-Cfg8er-Commit"
+	headerCommitDate = "X-Cfg8er-Commit-Date"
+	headerBlob       = "X-Cfg8er-Blob"
+	headerTree       = "X-Cfg8er-Tree"
+)
+
+// setVersionHeaders sets the headers naming the ref, commit and commit date a
+// version resolved to. The ref headers are omitted for commit hashes.
+func setVersionHeaders(c *gin.Context, v *repository.Version) {
+	if v.Ref != nil {
+		c.Header(headerRef, v.Ref.Name().String())
+		switch {
+		case v.Ref.Name().IsTag():
+			c.Header(headerTag, v.Ref.Name().Short())
+		case v.Ref.Name().IsBranch():
+			c.Header(headerBranch, v.Ref.Name().Short())
+		}
+	}
+
+	c.Header(headerCommit, v.Hash.String())
+	c.Header(headerCommitDate, v.Date.UTC().Format(time.RFC3339))
+}
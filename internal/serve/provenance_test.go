@@ -0,0 +1,107 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+)
+
+func Test_setVersionHeaders(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"fixture": newFixtureRepo(t, map[string]string{
+			"config.yml":         "version: 1.0.0\n",
+			"roles/web/vars.yml": "port: 80\n",
+		}, "v1.0.0"),
+		"redirect": newFixtureRepo(t, map[string]string{"config.yml": "version: 1.0.0\n"}, "v1.0.0"),
+	}
+	repoLookup["redirect"].RedirectFloating = true
+
+	head, err := repoLookup["fixture"].ClonedRepo().Head()
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	sha := head.Hash().String()
+	commitDate := "2018-09-01T12:00:00Z"
+
+	tests := []struct {
+		name   string
+		url    string
+		header http.Header
+		want   map[string]string
+	}{
+		{
+			name: "Semver constraint",
+			url:  "/r/fixture/v1/config.yml",
+			want: map[string]string{
+				headerRef:        "refs/tags/v1.0.0",
+				headerTag:        "v1.0.0",
+				headerBranch:     "",
+				headerCommit:     sha,
+				headerCommitDate: commitDate,
+				headerBlob:       "2ef3d523ab595ae7208afcfe778fbcc4f42f30a2",
+				headerTree:       "",
+			},
+		},
+		{
+			name: "Not modified",
+			url:  "/r/fixture/v1/config.yml",
+			header: http.Header{
+				"If-None-Match": {`"2ef3d523ab595ae7208afcfe778fbcc4f42f30a2"`},
+			},
+			want: map[string]string{
+				headerTag:    "v1.0.0",
+				headerCommit: sha,
+				headerBlob:   "2ef3d523ab595ae7208afcfe778fbcc4f42f30a2",
+			},
+		},
+		{
+			name: "Branch directory",
+			url:  "/r/fixture/master/roles/web",
+			want: map[string]string{
+				headerRef:        "refs/heads/master",
+				headerTag:        "",
+				headerBranch:     "master",
+				headerCommit:     sha,
+				headerCommitDate: commitDate,
+				headerBlob:       "",
+			},
+		},
+		{
+			name: "Commit hash",
+			url:  "/r/fixture/" + sha + "/config.yml",
+			want: map[string]string{
+				headerRef:    "",
+				headerTag:    "",
+				headerBranch: "",
+				headerCommit: sha,
+			},
+		},
+		{
+			name: "Redirect",
+			url:  "/r/redirect/v1/config.yml",
+			want: map[string]string{
+				headerTag:  "v1.0.0",
+				headerBlob: "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			for header, want := range tt.want {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("setVersionHeaders() %s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultRedirectMaxAge is how long, in seconds, a redirect from a
+	// floating version may be cached for a repo without a redirect_max_age.
+	defaultRedirectMaxAge = 60
+	// immutableMaxAge is how long, in seconds, responses for exact versions of
+	// repos with redirect_floating set may be cached.
+	immutableMaxAge = 31536000
+	// cacheControlKey is the context key of the Cache-Control header sent by
+	// checkNotModified with successful and not modified responses.
+	cacheControlKey = "cacheControl"
+)
+
+// redirectFloating redirects a request for a floating version, eg. a semantic
+// version constraint or a branch, of a repo with redirect_floating set to the
+// URL of the exact tag or commit hash it currently resolves to. Successful
+// responses for exact versions are marked as immutable instead. Responses of
+// repos with allow_hosts may only be cached privately. Reports whether the
+// request was redirected.
+func redirectFloating(c *gin.Context, r *config.Repo, v *repository.Version) bool {
+	if !r.RedirectFloating {
+		return false
+	}
+
+	visibility := "public"
+	if len(lookupRepoOptions(c.Param("repo")).allowHosts) > 0 {
+		visibility = "private"
+	}
+
+	if !v.Floating {
+		// Set by checkNotModified so errors aren't cached as immutable
+		c.Set(cacheControlKey, fmt.Sprintf("%s, max-age=%d, immutable", visibility, immutableMaxAge))
+		return false
+	}
+
+	// Tag names containing a slash can't be used as the version in a URL
+	target := v.Hash.String()
+	if v.Ref != nil && v.Ref.Name().IsTag() && !strings.Contains(v.Ref.Name().Short(), "/") {
+		target = v.Ref.Name().Short()
+	}
+
+	// The location is relative to the request path so redirects work when
+	// cfg8er is served under a path prefix by a proxy
+	location := url.URL{
+		Path:     strings.Repeat("../", strings.Count(c.Param("path"), "/")) + target + c.Param("path"),
+		RawQuery: c.Request.URL.RawQuery,
+	}
+
+	maxAge := defaultRedirectMaxAge
+	if r.RedirectMaxAge > 0 {
+		maxAge = r.RedirectMaxAge
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, maxAge))
+	// c.Redirect would make the location absolute
+	c.Header("Location", location.String())
+	c.Status(http.StatusFound)
+	return true
+}
@@ -0,0 +1,132 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+)
+
+func Test_redirectFloating(t *testing.T) {
+	files := map[string]string{"config.yml": "version: 1.0.0\n", "roles/web.yml": "role: web\n"}
+	repoLookup = map[string]*config.Repo{
+		"plain":    newFixtureRepo(t, files, "v1.0.0"),
+		"redirect": newFixtureRepo(t, files, "v1.0.0"),
+		"maxage":   newFixtureRepo(t, files, "v1.0.0"),
+		"private":  newFixtureRepo(t, files, "v1.0.0"),
+	}
+	repoLookup["redirect"].RedirectFloating = true
+	repoLookup["maxage"].RedirectFloating = true
+	repoLookup["maxage"].RedirectMaxAge = 300
+	repoLookup["private"].RedirectFloating = true
+
+	repoLookup["private"].AllowHosts = []string{"192.0.2.0/24"}
+
+	serverConfig = &config.Server{}
+	if err := loadRepoOptions(); err != nil {
+		t.Fatalf("loadRepoOptions() error = %v", err)
+	}
+	defer func() { repoOptionsLookup = nil }()
+
+	head, err := repoLookup["redirect"].ClonedRepo().Head()
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	sha := head.Hash().String()
+
+	tests := []struct {
+		name             string
+		url              string
+		wantStatus       int
+		wantLocation     string
+		wantCacheControl string
+	}{
+		{
+			name:       "Constraint without redirect_floating",
+			url:        "/r/plain/v1/config.yml",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:             "Constraint",
+			url:              "/r/redirect/v1/config.yml?format=text",
+			wantStatus:       http.StatusFound,
+			wantLocation:     "../v1.0.0/config.yml?format=text",
+			wantCacheControl: "public, max-age=60",
+		},
+		{
+			name:             "Constraint with redirect_max_age",
+			url:              "/r/maxage/~1.0/config.yml",
+			wantStatus:       http.StatusFound,
+			wantLocation:     "../v1.0.0/config.yml",
+			wantCacheControl: "public, max-age=300",
+		},
+		{
+			name:             "Branch",
+			url:              "/r/redirect/master/config.yml",
+			wantStatus:       http.StatusFound,
+			wantLocation:     "../" + sha + "/config.yml",
+			wantCacheControl: "public, max-age=60",
+		},
+		{
+			name:             "Constraint of a nested path",
+			url:              "/r/redirect/v1/roles/web.yml",
+			wantStatus:       http.StatusFound,
+			wantLocation:     "../../v1.0.0/roles/web.yml",
+			wantCacheControl: "public, max-age=60",
+		},
+		{
+			name:             "Constraint with allow_hosts",
+			url:              "/r/private/v1/config.yml",
+			wantStatus:       http.StatusFound,
+			wantLocation:     "../v1.0.0/config.yml",
+			wantCacheControl: "private, max-age=60",
+		},
+		{
+			name:             "Exact tag",
+			url:              "/r/redirect/v1.0.0/config.yml",
+			wantStatus:       http.StatusOK,
+			wantCacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			name:             "Commit hash",
+			url:              "/r/redirect/" + sha + "/config.yml",
+			wantStatus:       http.StatusOK,
+			wantCacheControl: "public, max-age=31536000, immutable",
+		},
+		{
+			name:             "Exact tag with allow_hosts",
+			url:              "/r/private/v1.0.0/config.yml",
+			wantStatus:       http.StatusOK,
+			wantCacheControl: "private, max-age=31536000, immutable",
+		},
+		{
+			name:       "Missing file at an exact tag",
+			url:        "/r/redirect/v1.0.0/missing.yml",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Missing version",
+			url:        "/r/redirect/v2/config.yml",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("redirectFloating() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("redirectFloating() Location = %v, want %v", got, tt.wantLocation)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.wantCacheControl {
+				t.Errorf("redirectFloating() Cache-Control = %v, want %v", got, tt.wantCacheControl)
+			}
+		})
+	}
+}
@@ -1,21 +1,75 @@
 package serve
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"mime"
 	"net/http"
 	"path"
+	"strconv"
+	"strings"
 
+	"github.com/cfg8er/cfg8er/internal/acl"
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/internal/mediatype"
+	"github.com/cfg8er/cfg8er/internal/webhook"
+	"github.com/cfg8er/cfg8er/pkg/repository"
 	"github.com/gin-gonic/gin"
 )
 
+// maxWebhookSize is the largest webhook payload read by postUpdateRepo.
+const maxWebhookSize = 25 << 20
+
+// Values of a repo's content_disposition. Files are served as attachments if
+// it isn't set.
+const (
+	dispositionAttachment = "attachment"
+	dispositionInline     = "inline"
+	dispositionNone       = "none"
+)
+
 func newRouter() *gin.Engine {
 	router := gin.Default()
 
-	router.GET("/r/:repo/:version/*path", getRepoVersionPath)
+	// The versions and resolve API share the :version segment with versions
+	// of the repo, as the router doesn't allow a static segment next to a
+	// parameter
+	router.GET("/r/:repo/:version", allowHosts, getRepoVersion)
+	router.GET("/r/:repo/:version/*path", allowHosts, getRepoVersionPath)
+	router.HEAD("/r/:repo/:version/*path", allowHosts, getRepoVersionPath)
+	router.GET("/a/:repo/:version", allowHosts, getRepoArchive)
+	router.GET("/a/:repo/:version/*path", allowHosts, getRepoArchive)
+	router.GET("/ansible/:repo/:version", allowHosts, getRepoInventory)
+	router.GET("/ansible/:repo/:version/*path", allowHosts, getRepoInventory)
+	router.GET("/hiera/:repo/:version", allowHosts, getRepoHiera)
+	router.GET("/hiera/:repo/:version/*path", allowHosts, getRepoHiera)
+	router.POST("/hiera/:repo/:version", allowHosts, getRepoHiera)
+	router.POST("/hiera/:repo/:version/*path", allowHosts, getRepoHiera)
+	router.GET("/ipxe/:repo/:version/*path", allowHosts, getRepoIPXE)
+	router.GET("/cloud-init/:repo/:version/*path", allowHosts, getRepoCloudInit)
+	router.GET("/ignition/:repo/:version/*path", allowHosts, getRepoIgnition)
+	router.GET("/spring/:repo/*path", allowHosts, getRepoSpring)
+	router.POST("/update/:repo", postUpdateRepo)
 
 	return router
 }
 
+// allowHosts aborts with 403 Forbidden if the client address isn't in the
+// allow_hosts of the repo being requested. Repos without an access list are
+// open to every client.
+func allowHosts(c *gin.Context) {
+	allowed := lookupRepoOptions(c.Param("repo")).allowHosts
+	if len(allowed) == 0 {
+		return
+	}
+
+	if !allowed.Contains(acl.ClientIP(c.Request, trustedProxies)) {
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
+
 func getRepoVersionPath(c *gin.Context) {
 	repo := c.Param("repo")
 	version := c.Param("version")
@@ -33,16 +87,200 @@ func getRepoVersionPath(c *gin.Context) {
 		return
 	}
 
-	reader, size, err := r.ClonedRepo.FileOpenAtSemVer(urlPath, version)
+	cloned := r.ClonedRepo()
 
-	if err != nil || reader == nil {
+	v, err := cloned.ResolveSemVer(version)
+	if err != nil && version == resolveVersion && c.Request.Method == http.MethodGet {
+		getRepoResolve(c)
+		return
+	}
+	if err != nil {
 		c.Status(http.StatusNotFound)
 		return
 	}
 
-	extraHeaders := map[string]string{
-		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, path.Base(urlPath)),
+	setVersionHeaders(c, v)
+
+	if redirectFloating(c, r, v) {
+		return
+	}
+
+	// Structured documents are converted to the format the client requests
+	// or accepts, and merged if the repo merges them
+	docFormat, isDoc := format.FromPath(urlPath)
+	if isDoc {
+		c.Header("Vary", "Accept")
+
+		f, ok := requestedFormat(c, docFormat)
+		if !ok {
+			c.Status(http.StatusNotAcceptable)
+			return
+		}
+
+		if f != docFormat || lookupRepoOptions(repo).merge != nil {
+			getRepoDocument(c, r, cloned, f, urlPath, v)
+			return
+		}
+	}
+
+	file, err := cloned.OpenAtVersion(urlPath, v)
+
+	if err == repository.ErrIsDir {
+		listRepoVersionDir(c, cloned, urlPath, v)
+		return
 	}
 
-	c.DataFromReader(http.StatusOK, size, "text/plain", reader, extraHeaders)
+	// Documents missing in the format requested may be converted from
+	// another format
+	if err != nil && isDoc {
+		getRepoDocument(c, r, cloned, docFormat, urlPath, v)
+		return
+	}
+
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	c.Header(headerBlob, file.Hash.String())
+
+	if checkNotModified(c, hashETag(file.Hash), versionModified(v, file.Commit.Committer.When)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// Peek at the start of the file to sniff its media type without
+	// consuming it
+	buffered := bufio.NewReaderSize(file, mediatype.SniffLen)
+	head, _ := buffered.Peek(mediatype.SniffLen)
+
+	contentType, ok := lookupRepoOptions(repo).contentTypes.Lookup(urlPath)
+	if !ok {
+		contentType = mediatype.Detect(urlPath, head)
+	}
+
+	extraHeaders := map[string]string{}
+	if disposition := contentDisposition(r.ContentDisposition, urlPath); disposition != "" {
+		extraHeaders["Content-Disposition"] = disposition
+	}
+
+	c.DataFromReader(http.StatusOK, file.Size, contentType, buffered, extraHeaders)
+}
+
+// contentDisposition returns the Content-Disposition header of the file at
+// filePath for a repo's content_disposition, or an empty string if the header
+// shouldn't be sent.
+func contentDisposition(disposition string, filePath string) string {
+	switch disposition {
+	case dispositionNone:
+		return ""
+	case "":
+		disposition = dispositionAttachment
+	}
+
+	return mime.FormatMediaType(disposition, map[string]string{"filename": path.Base(filePath)})
+}
+
+// listRepoVersionDir responds with the entries of a directory at a version as
+// JSON, or as one name per line with a trailing slash on directories if the
+// format query parameter is text. Subdirectories are listed too if the
+// recursive query parameter is true.
+func listRepoVersionDir(c *gin.Context, cloned *repository.Repository, dirPath string, v *repository.Version) {
+	recursive, _ := strconv.ParseBool(c.Query("recursive"))
+
+	dir, err := cloned.ListTreeAtVersion(dirPath, v, recursive)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.Header(headerTree, dir.Hash.String())
+
+	contentType := "application/json; charset=utf-8"
+	var listing []byte
+
+	if c.Query("format") == "text" {
+		contentType = mediatype.Text
+
+		var text strings.Builder
+		for _, entry := range dir.Entries {
+			text.WriteString(entry.Name)
+			if entry.Type == "tree" {
+				text.WriteString("/")
+			}
+			text.WriteString("\n")
+		}
+		listing = []byte(text.String())
+	} else {
+		entries := dir.Entries
+		if entries == nil {
+			entries = []repository.TreeEntry{}
+		}
+		if listing, err = json.Marshal(entries); err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if checkNotModified(c, contentETag(listing), versionModified(v, dir.Commit.Committer.When)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, listing)
+}
+
+// postUpdateRepo queues an immediate fetch of a repo with enable_update_api
+// set. The request is verified as a GitHub, GitLab or Gitea webhook against the
+// repo's update_api_secret rather than by allow_hosts, as webhooks come from
+// the Git host. Webhooks for events that don't change refs are acknowledged
+// without fetching.
+func postUpdateRepo(c *gin.Context) {
+	repo := c.Param("repo")
+
+	r, ok := repoLookup[repo]
+
+	if !ok || !r.EnableUpdateAPI {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize))
+	if _, tooLarge := err.(*http.MaxBytesError); tooLarge {
+		c.Status(http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	event, err := webhook.Parse(c.Request.Header, body, r.UpdateAPISecret)
+	if err == webhook.ErrNoSecret {
+		fmt.Printf("Error: Webhook for repo %s: %v\n", r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return
+	} else if err == webhook.ErrMissingSignature {
+		c.Status(http.StatusUnauthorized)
+		return
+	} else if err == webhook.ErrInvalidSignature {
+		c.Status(http.StatusForbidden)
+		return
+	} else if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	if !event.IsUpdate() {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+
+	select {
+	case updateRepoChs[repo] <- updateRequest{name: repo}:
+		fmt.Printf("Update of repo %s requested by %s %s webhook %s\n", r.URL, event.Provider, event.Name, event.Ref)
+		c.JSON(http.StatusAccepted, gin.H{"status": "queued"})
+	default:
+		c.Status(http.StatusServiceUnavailable)
+	}
 }
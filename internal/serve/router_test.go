@@ -0,0 +1,505 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func Test_postUpdateRepo(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"disabled": {URL: "https://example.com/disabled.git"},
+		"enabled":  {URL: "https://example.com/enabled.git", EnableUpdateAPI: true, UpdateAPISecret: "s3cret"},
+	}
+	updateRepoChs = map[string]chan updateRequest{"enabled": make(chan updateRequest, 1)}
+
+	// Webhooks are authenticated by the secret, not by the client address
+	repoLookup["enabled"].AllowHosts = []string{"10.0.0.0/8"}
+	serverConfig = &config.Server{}
+	if err := loadRepoOptions(); err != nil {
+		t.Fatalf("loadRepoOptions() error = %v", err)
+	}
+	defer func() { repoOptionsLookup = nil }()
+
+	tests := []struct {
+		name       string
+		repo       string
+		header     http.Header
+		body       string
+		wantStatus int
+		wantQueued bool
+	}{
+		{
+			name:       "Non-existent repo",
+			repo:       "non-existent",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Repo without enable_update_api",
+			repo:       "disabled",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Webhook without token",
+			repo:       "enabled",
+			header:     http.Header{"X-Gitlab-Event": {"Push Hook"}},
+			body:       `{"ref":"refs/heads/master"}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Webhook with the wrong token",
+			repo:       "enabled",
+			header:     http.Header{"X-Gitlab-Event": {"Push Hook"}, "X-Gitlab-Token": {"wrong"}},
+			body:       `{"ref":"refs/heads/master"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Webhook from an unknown provider",
+			repo:       "enabled",
+			body:       `{"ref":"refs/heads/master"}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Webhook too large",
+			repo:       "enabled",
+			header:     http.Header{"X-Gitlab-Event": {"Push Hook"}, "X-Gitlab-Token": {"s3cret"}},
+			body:       strings.Repeat(" ", maxWebhookSize+1),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "Webhook for an ignored event",
+			repo:       "enabled",
+			header:     http.Header{"X-Gitlab-Event": {"Issue Hook"}, "X-Gitlab-Token": {"s3cret"}},
+			body:       `{}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Tag push webhook",
+			repo:       "enabled",
+			header:     http.Header{"X-Gitlab-Event": {"Tag Push Hook"}, "X-Gitlab-Token": {"s3cret"}},
+			body:       `{"ref":"refs/tags/v1.0.0"}`,
+			wantStatus: http.StatusAccepted,
+			wantQueued: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/update/"+tt.repo, strings.NewReader(tt.body))
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("postUpdateRepo() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+
+			select {
+			case got := <-updateRepoChs["enabled"]:
+				if !tt.wantQueued || got.name != tt.repo {
+					t.Errorf("postUpdateRepo() queued %v, wantQueued %v", got.name, tt.wantQueued)
+				}
+			default:
+				if tt.wantQueued {
+					t.Errorf("postUpdateRepo() queued nothing, wantQueued %v", tt.wantQueued)
+				}
+			}
+		})
+	}
+}
+
+func Test_allowHosts(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"open":    {URL: "https://example.com/open.git"},
+		"private": {URL: "https://example.com/private.git", AllowHosts: []string{"10.0.0.0/8", "fd00::/8"}},
+	}
+	serverConfig = &config.Server{TrustedProxies: []string{"127.0.0.1"}}
+	if err := loadRepoOptions(); err != nil {
+		t.Fatalf("loadRepoOptions() error = %v", err)
+	}
+	defer func() { repoOptionsLookup = nil }()
+
+	tests := []struct {
+		name       string
+		repo       string
+		remoteAddr string
+		header     http.Header
+		wantStatus int
+	}{
+		{
+			name:       "Repo without allow_hosts",
+			repo:       "open",
+			remoteAddr: "192.0.2.1:50000",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Allowed IPv4 client",
+			repo:       "private",
+			remoteAddr: "10.1.2.3:50000",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Allowed IPv6 client",
+			repo:       "private",
+			remoteAddr: "[fd00::5]:50000",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Denied client",
+			repo:       "private",
+			remoteAddr: "192.0.2.1:50000",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Denied client spoofing X-Forwarded-For",
+			repo:       "private",
+			remoteAddr: "192.0.2.1:50000",
+			header:     http.Header{"X-Forwarded-For": {"10.1.2.3"}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Allowed client behind a trusted proxy",
+			repo:       "private",
+			remoteAddr: "127.0.0.1:50000",
+			header:     http.Header{"X-Forwarded-For": {"10.1.2.3"}},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Denied client behind a trusted proxy",
+			repo:       "private",
+			remoteAddr: "127.0.0.1:50000",
+			header:     http.Header{"Forwarded": {"for=192.0.2.1"}},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/r/"+tt.repo+"/v1/config.yml", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("allowHosts() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func Test_getRepoVersionPath(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"fixture": newFixtureRepo(t, map[string]string{
+			"config.yml":           "version: 1.0.0\n",
+			"roles/web/vars.yml":   "port: 80\n",
+			"roles/web/nginx.conf": "server {}\n",
+		}, "v1.0.0"),
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "File",
+			url:        "/r/fixture/v1/config.yml",
+			wantStatus: http.StatusOK,
+			wantBody:   "version: 1.0.0\n",
+		},
+		{
+			name:       "Missing file",
+			url:        "/r/fixture/v1/missing.yml",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Directory",
+			url:        "/r/fixture/v1/roles/web",
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"name":"nginx.conf","type":"blob","mode":"100644","size":10,"hash":"2faccfe7ad33fc27555b7bfbc7d925e0d19c1f61"},{"name":"vars.yml","type":"blob","mode":"100644","size":9,"hash":"fac04281a322e11c2c7da4a5a165ec088ac2a171"}]`,
+		},
+		{
+			name:       "Root directory as text",
+			url:        "/r/fixture/v1/?format=text",
+			wantStatus: http.StatusOK,
+			wantBody:   "config.yml\nroles/\n",
+		},
+		{
+			name:       "Recursive directory as text",
+			url:        "/r/fixture/v1/roles?format=text&recursive=true",
+			wantStatus: http.StatusOK,
+			wantBody:   "web/\nweb/nginx.conf\nweb/vars.yml\n",
+		},
+		{
+			name:       "Directory at a missing version",
+			url:        "/r/fixture/v2/roles",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("getRepoVersionPath() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(w.Body.String()) != strings.TrimSpace(tt.wantBody) {
+				t.Errorf("getRepoVersionPath() body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func Test_getRepoVersionPath_contentType(t *testing.T) {
+	files := map[string]string{
+		"config.yml":    "version: 1.0.0\n",
+		"boot":          "#!ipxe\nchain http://boot.invalid/\n",
+		"hosts/web.cfg": "{\"port\": 80}\n",
+		"blob":          "\x00\x01\x02\x03",
+	}
+	repoLookup = map[string]*config.Repo{
+		"default": newFixtureRepo(t, files, "v1.0.0"),
+		"inline":  newFixtureRepo(t, files, "v1.0.0"),
+		"none":    newFixtureRepo(t, files, "v1.0.0"),
+	}
+	repoLookup["inline"].ContentDisposition = "inline"
+	repoLookup["inline"].ContentTypes = map[string]string{"hosts/*.cfg": "application/json"}
+	repoLookup["none"].ContentDisposition = "none"
+
+	serverConfig = &config.Server{}
+	if err := loadRepoOptions(); err != nil {
+		t.Fatalf("loadRepoOptions() error = %v", err)
+	}
+	defer func() { repoOptionsLookup = nil }()
+
+	tests := []struct {
+		name            string
+		repo            string
+		file            string
+		wantType        string
+		wantDisposition string
+	}{
+		{
+			name:            "YAML attachment",
+			repo:            "default",
+			file:            "config.yml",
+			wantType:        "application/yaml",
+			wantDisposition: "attachment; filename=config.yml",
+		},
+		{
+			name:            "Sniffed iPXE script",
+			repo:            "default",
+			file:            "boot",
+			wantType:        "text/plain; charset=utf-8",
+			wantDisposition: "attachment; filename=boot",
+		},
+		{
+			name:            "Binary",
+			repo:            "default",
+			file:            "blob",
+			wantType:        "application/octet-stream",
+			wantDisposition: "attachment; filename=blob",
+		},
+		{
+			name:            "Without override",
+			repo:            "default",
+			file:            "hosts/web.cfg",
+			wantType:        "text/plain; charset=utf-8",
+			wantDisposition: "attachment; filename=web.cfg",
+		},
+		{
+			name:            "Inline with override",
+			repo:            "inline",
+			file:            "hosts/web.cfg",
+			wantType:        "application/json",
+			wantDisposition: "inline; filename=web.cfg",
+		},
+		{
+			name:     "Without disposition",
+			repo:     "none",
+			file:     "config.yml",
+			wantType: "application/yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/r/"+tt.repo+"/v1/"+tt.file, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("getRepoVersionPath() status = %v, want %v", w.Code, http.StatusOK)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("getRepoVersionPath() Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != tt.wantDisposition {
+				t.Errorf("getRepoVersionPath() Content-Disposition = %q, want %q", got, tt.wantDisposition)
+			}
+			if got := w.Body.String(); got != files[tt.file] {
+				t.Errorf("getRepoVersionPath() body = %q, want %q", got, files[tt.file])
+			}
+		})
+	}
+}
+
+func Test_getRepoVersionPath_conditional(t *testing.T) {
+	repoLookup = map[string]*config.Repo{
+		"fixture": newFixtureRepo(t, map[string]string{
+			"config.yml":         "version: 1.0.0\n",
+			"roles/web/vars.yml": "port: 80\n",
+		}, "v1.0.0"),
+	}
+
+	blobETag := `"2ef3d523ab595ae7208afcfe778fbcc4f42f30a2"`
+	lastModified := "Sat, 01 Sep 2018 12:00:00 GMT"
+
+	// The ETag of a listing is only known after fetching it
+	req := httptest.NewRequest(http.MethodGet, "/r/fixture/v1/roles", nil)
+	w := httptest.NewRecorder()
+	newRouter().ServeHTTP(w, req)
+	listingETag := w.Header().Get("ETag")
+	if listingETag == "" {
+		t.Fatalf("getRepoVersionPath() listing ETag is empty")
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		header     http.Header
+		wantStatus int
+		wantETag   string
+		wantBody   bool
+	}{
+		{
+			name:       "Unconditional",
+			method:     http.MethodGet,
+			url:        "/r/fixture/v1/config.yml",
+			wantStatus: http.StatusOK,
+			wantETag:   blobETag,
+			wantBody:   true,
+		},
+		{
+			name:       "Matching If-None-Match",
+			method:     http.MethodGet,
+			url:        "/r/fixture/v1/config.yml",
+			header:     http.Header{"If-None-Match": {`"other", ` + blobETag}},
+			wantStatus: http.StatusNotModified,
+			wantETag:   blobETag,
+		},
+		{
+			name:       "Weak matching If-None-Match",
+			method:     http.MethodGet,
+			url:        "/r/fixture/v1.0.0/config.yml",
+			header:     http.Header{"If-None-Match": {"W/" + blobETag}},
+			wantStatus: http.StatusNotModified,
+			wantETag:   blobETag,
+		},
+		{
+			name:       "Stale If-None-Match wins over If-Modified-Since",
+			method:     http.MethodGet,
+			url:        "/r/fixture/v1/config.yml",
+			header:     http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {lastModified}},
+			wantStatus: http.StatusOK,
+			wantETag:   blobETag,
+			wantBody:   true,
+		},
+		{
+			name:       "Current If-Modified-Since",
+			method:     http.MethodGet,
+			url:        "/r/fixture/v1.0.0/config.yml",
+			header:     http.Header{"If-Modified-Since": {lastModified}},
+			wantStatus: http.StatusNotModified,
+			wantETag:   blobETag,
+		},
+		{
+			name:       "If-Modified-Since of a floating version",
+			method:     http.MethodGet,
+			url:        "/r/fixture/v1/config.yml",
+			header:     http.Header{"If-Modified-Since": {lastModified}},
+			wantStatus: http.StatusOK,
+			wantETag:   blobETag,
+			wantBody:   true,
+		},
+		{
+			name:       "Stale If-Modified-Since",
+			method:     http.MethodGet,
+			url:        "/r/fixture/v1.0.0/config.yml",
+			header:     http.Header{"If-Modified-Since": {"Fri, 31 Aug 2018 12:00:00 GMT"}},
+			wantStatus: http.StatusOK,
+			wantETag:   blobETag,
+			wantBody:   true,
+		},
+		{
+			name:       "HEAD",
+			method:     http.MethodHead,
+			url:        "/r/fixture/v1/config.yml",
+			wantStatus: http.StatusOK,
+			wantETag:   blobETag,
+		},
+		{
+			name:       "Matching listing If-None-Match",
+			method:     http.MethodGet,
+			url:        "/r/fixture/v1/roles",
+			header:     http.Header{"If-None-Match": {listingETag}},
+			wantStatus: http.StatusNotModified,
+			wantETag:   listingETag,
+		},
+		{
+			name:       "Listing in another format",
+			method:     http.MethodGet,
+			url:        "/r/fixture/v1/roles?format=text",
+			header:     http.Header{"If-None-Match": {listingETag}},
+			wantStatus: http.StatusOK,
+			wantBody:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("getRepoVersionPath() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); tt.wantETag != "" && got != tt.wantETag {
+				t.Errorf("getRepoVersionPath() ETag = %v, want %v", got, tt.wantETag)
+			}
+			// Floating versions are only validated by their ETag
+			wantLastModified := ""
+			if strings.Contains(tt.url, "/v1.0.0/") {
+				wantLastModified = lastModified
+			}
+			if got := w.Header().Get("Last-Modified"); got != wantLastModified {
+				t.Errorf("getRepoVersionPath() Last-Modified = %v, want %v", got, wantLastModified)
+			}
+			if gotBody := w.Body.Len() > 0; gotBody != tt.wantBody && tt.method != http.MethodHead {
+				t.Errorf("getRepoVersionPath() has body = %v, want %v", gotBody, tt.wantBody)
+			}
+		})
+	}
+}
@@ -3,17 +3,22 @@ package serve
 import (
 	"fmt"
 
+	"github.com/cfg8er/cfg8er/internal/acl"
 	"github.com/cfg8er/cfg8er/internal/config"
 	"github.com/cfg8er/cfg8er/pkg/repository"
 	"github.com/gin-gonic/gin"
-	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/urfave/cli.v1"
 )
 
 var repoLookup map[string]*config.Repo
+var serverConfig *config.Server
+var updateRepoChs map[string]chan updateRequest
+var trustedProxies acl.List
+var repoOptionsLookup map[string]*repoOptions
 
 // Run is the cli action for the serve sub-command. It loads the config, clones
-// the repos on startup, and starts the go-gin based listener.
+// the repos on startup, schedules their updates, and starts the go-gin based
+// listener.
 func Run(c *cli.Context) error {
 	if !c.Bool("debug") {
 		gin.SetMode(gin.ReleaseMode)
@@ -27,38 +32,36 @@ func Run(c *cli.Context) error {
 		return err
 	}
 
-	// Clone all the repos
-	updateRepoCh := make(chan *config.Repo, 100)
-	defer close(updateRepoCh)
+	serverConfig, err = config.LoadServerConfig(configPath)
+	if err != nil {
+		return err
+	}
 
-	go cloneRepos(updateRepoCh)
+	// Fail early on invalid settings rather than on every clone attempt. The
+	// settings are kept until the repo is cloned so keyrings are read and SSH
+	// agents dialled once
+	for n, r := range repoLookup {
+		var configured repository.Repository
+		if err := r.Configure(&configured); err != nil {
+			return fmt.Errorf("Repo %s: %v", n, err)
+		}
+		r.SetClonedRepo(&configured)
+	}
 
+	if err := loadRepoOptions(); err != nil {
+		return err
+	}
+
+	// Clone all the repos and keep fetching them every update_frequency
+	updateRepoChs = map[string]chan updateRequest{}
 	for n := range repoLookup {
-		updateRepoCh <- repoLookup[n]
+		updateRepoChs[n] = make(chan updateRequest, updateQueueSize)
+
+		go cloneRepos(updateRepoChs[n])
+		go scheduleUpdates(n, updateRepoChs[n])
 	}
 
 	router := newRouter()
 
 	return router.Run(c.String("listen"))
 }
-
-// cloneRepos iterates over over the repoLookup global cloning repos or fetching the latest objects
-// if the repo has already been cloned. Ignores NoErrAlreadyUpToDate error on fetch.
-func cloneRepos(updateRepoCh chan *config.Repo) {
-	for r := range updateRepoCh {
-		if r.ClonedRepo == (repository.Repository{}) {
-			fmt.Printf("Cloning repo %s\n", r.URL)
-			clonedRepo, err := repository.CloneBare(r.URL)
-			if err != nil {
-				fmt.Printf("Error: Cloning repo %s: %v\n", r.URL, err)
-			}
-			r.ClonedRepo = clonedRepo
-		} else {
-			fmt.Printf("Fetch latest objects from repo %s\n", r.URL)
-			err := r.ClonedRepo.Fetch(&git.FetchOptions{})
-			if err != nil && err != git.NoErrAlreadyUpToDate {
-				fmt.Printf("Error: Fetching objects from repo %s: %v\n", r.URL, err)
-			}
-		}
-	}
-}
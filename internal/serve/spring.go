@@ -0,0 +1,198 @@
+package serve
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/internal/mediatype"
+	"github.com/cfg8er/cfg8er/internal/spring"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultSpringLabel is the label of Spring requests without one in repos that
+// don't set spring_default_label: the latest semantic version tag.
+const defaultSpringLabel = "*"
+
+// springLabelSlash is how Spring clients write slashes in labels, as a label
+// is a single path segment.
+const springLabelSlash = "(_)"
+
+// springFormats maps the extensions of rendered Spring configs to the formats
+// they're encoded in. .properties files aren't a format.
+var springFormats = map[string]format.Format{
+	".yml":        format.YAML,
+	".yaml":       format.YAML,
+	".json":       format.JSON,
+	".properties": "",
+}
+
+// getRepoSpring serves a repo as a Spring Cloud Config Server, whose URI is
+// /spring/repo. The label of requests is a version of the repo, or the repo's
+// spring_default_label if they don't have one. The environment of an
+// application with a comma separated list of profiles is served as JSON at
+// /{application}/{profile}/{label}, and its merged properties rendered as
+// YAML, JSON or .properties at /{label}/{application}-{profile}.yml. Rendered
+// properties have their placeholders resolved unless the resolvePlaceholders
+// query parameter is false.
+func getRepoSpring(c *gin.Context) {
+	repo := c.Param("repo")
+
+	r, ok := repoLookup[repo]
+
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(c.Param("path"), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	ext := path.Ext(c.Param("path"))
+	if _, rendered := springFormats[ext]; rendered && len(segments) <= 2 {
+		name := strings.TrimSuffix(segments[len(segments)-1], ext)
+		i := strings.LastIndex(name, "-")
+		if i <= 0 || i == len(name)-1 {
+			c.Status(http.StatusNotFound)
+			return
+		}
+
+		label := ""
+		if len(segments) == 2 {
+			label = segments[0]
+		}
+		getSpringRendered(c, r, name[:i], name[i+1:], label, ext)
+		return
+	}
+
+	if len(segments) != 2 && len(segments) != 3 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	label := ""
+	if len(segments) == 3 {
+		label = segments[2]
+	}
+	getSpringEnvironment(c, r, segments[0], segments[1], label)
+}
+
+// getSpringEnvironment serves the environment of an application with profiles
+// at a label as JSON.
+func getSpringEnvironment(c *gin.Context, r *config.Repo, application string, profiles string, label string) {
+	env, v, ok := loadSpringEnvironment(c, r, application, profiles, label)
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	respondSpring(c, v, "application/json; charset=utf-8", data)
+}
+
+// getSpringRendered serves the merged properties of an application with
+// profiles at a label in the format of an extension.
+func getSpringRendered(c *gin.Context, r *config.Repo, application string, profiles string, label string, ext string) {
+	env, v, ok := loadSpringEnvironment(c, r, application, profiles, label)
+	if !ok {
+		return
+	}
+
+	props := spring.Merge(env.PropertySources)
+	if resolve, err := strconv.ParseBool(c.DefaultQuery("resolvePlaceholders", "true")); err != nil || resolve {
+		props = spring.ResolvePlaceholders(props)
+	}
+
+	if ext == ".properties" {
+		respondSpring(c, v, mediatype.Text, spring.EncodeProperties(props))
+		return
+	}
+
+	f := springFormats[ext]
+	data, err := format.Encode(f, spring.Unflatten(props))
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	contentType := mediatype.Text
+	if f == format.JSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	respondSpring(c, v, contentType, data)
+}
+
+// respondSpring responds with a Spring config at a version, or with 304 Not
+// Modified if the client has it.
+func respondSpring(c *gin.Context, v *repository.Version, contentType string, data []byte) {
+	if checkNotModified(c, contentETag(data), versionModified(v, v.Date)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// loadSpringEnvironment loads the environment of an application with profiles
+// at a label and the version it resolves to, responding with an error and
+// returning false if it can't.
+func loadSpringEnvironment(c *gin.Context, r *config.Repo, application string, profiles string, label string) (*spring.Environment, *repository.Version, bool) {
+	if label == "" {
+		label = r.SpringDefaultLabel
+		if label == "" {
+			label = defaultSpringLabel
+		}
+	}
+
+	profileList := strings.Split(profiles, ",")
+	if profiles == "" {
+		profileList = []string{spring.DefaultProfile}
+	}
+
+	searchPaths := r.SpringSearchPaths
+	if len(searchPaths) == 0 {
+		searchPaths = []string{""}
+	}
+
+	cloned := r.ClonedRepo()
+
+	v, err := cloned.ResolveSemVer(strings.Replace(label, springLabelSlash, "/", -1))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	setVersionHeaders(c, v)
+
+	sources, err := spring.Load(cloned, v, searchPaths, application, profileList, r.URL)
+	if err != nil {
+		fmt.Printf("Error: Loading Spring config of %s at %s in repo %s: %v\n", application, label, r.URL, err)
+		c.Status(http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if sources == nil {
+		sources = []spring.PropertySource{}
+	}
+
+	return &spring.Environment{
+		Name:            application,
+		Profiles:        profileList,
+		Label:           label,
+		Version:         v.Hash.String(),
+		PropertySources: sources,
+	}, v, true
+}
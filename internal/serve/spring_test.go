@@ -0,0 +1,145 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+)
+
+func Test_getRepoSpring(t *testing.T) {
+	r := newFixtureRepo(t, map[string]string{
+		"application.yml":        "logging:\n  level: INFO\nserver:\n  port: 8080\n",
+		"orders.yml":             "server:\n  port: 8081\ndb:\n  url: jdbc:postgresql://${db.host}/orders\n  host: localhost\n",
+		"orders-prod.properties": "db.host = db.example.com\nfeatures[0] = audit\n",
+		"application-prod.yml":   "logging:\n  level: WARN\n",
+		"orders-dev.yml/notes":   "Not a properties file\n",
+	}, "v1.0.0", "v1.1.0")
+	r.URL = "https://git.example.com/config.git"
+	repoLookup = map[string]*config.Repo{"fixture": r}
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantJSON   string
+		want       string
+	}{
+		{
+			name:       "Environment",
+			url:        "/spring/fixture/orders/prod/v1.0.0",
+			wantStatus: http.StatusOK,
+			wantJSON: `{
+				"name": "orders",
+				"profiles": ["prod"],
+				"label": "v1.0.0",
+				"version": "` + mustResolve(t, r, "v1.0.0") + `",
+				"state": null,
+				"propertySources": [
+					{"name": "https://git.example.com/config.git/orders-prod.properties", "source": {"db.host": "db.example.com", "features[0]": "audit"}},
+					{"name": "https://git.example.com/config.git/application-prod.yml", "source": {"logging.level": "WARN"}},
+					{"name": "https://git.example.com/config.git/orders.yml", "source": {"server.port": 8081, "db.url": "jdbc:postgresql://${db.host}/orders", "db.host": "localhost"}},
+					{"name": "https://git.example.com/config.git/application.yml", "source": {"logging.level": "INFO", "server.port": 8080}}
+				]
+			}`,
+		},
+		{
+			name:       "Default label",
+			url:        "/spring/fixture/billing/default",
+			wantStatus: http.StatusOK,
+			wantJSON: `{
+				"name": "billing",
+				"profiles": ["default"],
+				"label": "*",
+				"version": "` + mustResolve(t, r, "v1.1.0") + `",
+				"state": null,
+				"propertySources": [
+					{"name": "https://git.example.com/config.git/application.yml", "source": {"logging.level": "INFO", "server.port": 8080}}
+				]
+			}`,
+		},
+		{
+			name:       "Rendered YAML",
+			url:        "/spring/fixture/v1/orders-prod.yml",
+			wantStatus: http.StatusOK,
+			want:       "db:\n  host: db.example.com\n  url: jdbc:postgresql://db.example.com/orders\nfeatures:\n- audit\nlogging:\n  level: WARN\nserver:\n  port: 8081\n",
+		},
+		{
+			name:       "Rendered properties without resolving placeholders",
+			url:        "/spring/fixture/orders-prod.properties?resolvePlaceholders=false",
+			wantStatus: http.StatusOK,
+			want:       "db.host: db.example.com\ndb.url: jdbc:postgresql://${db.host}/orders\nfeatures[0]: audit\nlogging.level: WARN\nserver.port: 8081\n",
+		},
+		{
+			name:       "Rendered JSON",
+			url:        "/spring/fixture/v1.0.0/orders-default.json",
+			wantStatus: http.StatusOK,
+			wantJSON:   `{"db": {"host": "localhost", "url": "jdbc:postgresql://localhost/orders"}, "logging": {"level": "INFO"}, "server": {"port": 8081}}`,
+		},
+		{
+			name:       "Unknown label",
+			url:        "/spring/fixture/orders/prod/v2",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Rendered without a profile",
+			url:        "/spring/fixture/v1/orders.yml",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Properties file is a directory",
+			url:        "/spring/fixture/orders/dev/v1.0.0",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Application only",
+			url:        "/spring/fixture/orders",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("getRepoSpring() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			if tt.wantJSON == "" {
+				if got := w.Body.String(); got != tt.want {
+					t.Errorf("getRepoSpring() body = %q, want %q", got, tt.want)
+				}
+				return
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("getRepoSpring() body = %s, error = %v", w.Body, err)
+			}
+			if err := json.Unmarshal([]byte(tt.wantJSON), &want); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("getRepoSpring() body = %s, want %s", w.Body, tt.wantJSON)
+			}
+		})
+	}
+}
+
+// mustResolve returns the commit hash a version of a repo resolves to.
+func mustResolve(t *testing.T, r *config.Repo, version string) string {
+	v, err := r.ClonedRepo().ResolveSemVer(version)
+	if err != nil {
+		t.Fatalf("ResolveSemVer() error = %v", err)
+	}
+	return v.Hash.String()
+}
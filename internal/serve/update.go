@@ -0,0 +1,182 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"time"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+const (
+	// defaultUpdateTimeout bounds a clone or fetch of a repo without an update_timeout.
+	defaultUpdateTimeout = 5 * time.Minute
+	// updateRetryDelay is the first delay before retrying a failed update of a repo
+	// without an update_frequency.
+	updateRetryDelay = 30 * time.Second
+	// maxUpdateBackoff caps the delay between retries of a repo that keeps failing,
+	// unless its update_frequency is longer.
+	maxUpdateBackoff = time.Hour
+	// updateJitter is the fraction of the delay that is randomly added or removed
+	// so repos sharing a Git host aren't all fetched at once.
+	updateJitter = 0.1
+	// updateQueueSize is how many updates of a repo can be queued, eg. by
+	// webhooks, while it's being updated.
+	updateQueueSize = 10
+)
+
+// updateRequest asks the cloneRepos worker of a repo to clone or fetch it.
+// The result of the update is sent on done, if done isn't nil.
+type updateRequest struct {
+	name string
+	done chan<- error
+}
+
+// scheduleUpdates queues the named repo onto updateRepoCh right away and then
+// every update_frequency seconds. After a failed update the delay is doubled
+// for every consecutive failure. Repos without an update_frequency are only
+// queued again until the first successful update.
+func scheduleUpdates(name string, updateRepoCh chan<- updateRequest) {
+	r := repoLookup[name]
+	frequency := time.Duration(r.UpdateFrequency) * time.Second
+	failures := 0
+
+	for {
+		done := make(chan error, 1)
+		updateRepoCh <- updateRequest{name: name, done: done}
+
+		if err := <-done; err != nil {
+			failures++
+		} else {
+			failures = 0
+		}
+
+		if frequency <= 0 && failures == 0 {
+			return
+		}
+
+		time.Sleep(nextUpdateDelay(frequency, failures, rand.Float64()))
+	}
+}
+
+// nextUpdateDelay returns how long to wait before the next update of a repo
+// updated every frequency that has failed failures times in a row. random is
+// a value in [0, 1) used to apply the jitter.
+func nextUpdateDelay(frequency time.Duration, failures int, random float64) time.Duration {
+	delay := frequency
+
+	if failures > 0 {
+		if delay <= 0 {
+			delay = updateRetryDelay
+			failures--
+		}
+
+		maxDelay := maxUpdateBackoff
+		if frequency > maxDelay {
+			maxDelay = frequency
+		}
+
+		for i := 0; i < failures && delay < maxDelay; i++ {
+			delay *= 2
+		}
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+
+	jitter := time.Duration(float64(delay) * updateJitter * (2*random - 1))
+
+	return delay + jitter
+}
+
+// cloneRepos receives repos from updateRepoCh cloning them or fetching the latest objects
+// if the repo has already been cloned. When a storage dir is configured a repo cloned by a previous
+// run is opened and fetched rather than cloned again. Ignores NoErrAlreadyUpToDate error on fetch.
+// Every repo has its own worker so a remote that hangs doesn't delay the updates of other repos.
+func cloneRepos(updateRepoCh chan updateRequest) {
+	for req := range updateRepoCh {
+		err := updateRepo(req.name)
+		if req.done != nil {
+			req.done <- err
+		}
+	}
+}
+
+// updateRepo clones or fetches the named repo, giving up after its update_timeout.
+// The repo's clone is only replaced once the clone or fetch completes, so it keeps
+// being served while the remote is slow or down.
+func updateRepo(name string) error {
+	r := repoLookup[name]
+
+	timeout := defaultUpdateTimeout
+	if r.UpdateTimeout > 0 {
+		timeout = time.Duration(r.UpdateTimeout) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cloned := r.ClonedRepo()
+
+	if cloned.Repository == nil {
+		// The settings applied by Run by configuring the repo
+		configured := *cloned
+
+		if serverConfig.StorageDir != "" {
+			storedRepo, err := repository.PlainOpenBare(repoStorageDir(name))
+			if err == nil {
+				fmt.Printf("Opened stored repo %s from %s\n", r.URL, repoStorageDir(name))
+				configured.Repository = storedRepo.Repository
+				cloned = &configured
+				r.SetClonedRepo(cloned)
+			} else if err != git.ErrRepositoryNotExists {
+				// Cloning into the dir would fail as it isn't empty
+				fmt.Printf("Error: Opening stored repo %s: %v\n", repoStorageDir(name), err)
+				return err
+			}
+		}
+
+		if cloned.Repository == nil {
+			fmt.Printf("Cloning repo %s\n", r.URL)
+			clonedRepo, err := cloneRepo(ctx, name, r, configured.Auth)
+			if err != nil {
+				fmt.Printf("Error: Cloning repo %s: %v\n", r.URL, err)
+				return err
+			}
+			configured.Repository = clonedRepo.Repository
+			r.SetClonedRepo(&configured)
+			return nil
+		}
+	}
+
+	fmt.Printf("Fetch latest objects from repo %s\n", r.URL)
+	fetched, err := cloned.Fetch(ctx)
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	} else if err != nil {
+		fmt.Printf("Error: Fetching objects from repo %s: %v\n", r.URL, err)
+		return err
+	}
+
+	r.SetClonedRepo(&fetched)
+	return nil
+}
+
+// cloneRepo clones the named repo into its storage dir, or into memory if no
+// storage dir is configured. auth is used to authenticate with the remote.
+func cloneRepo(ctx context.Context, name string, r *config.Repo, auth transport.AuthMethod) (repository.Repository, error) {
+	if serverConfig.StorageDir == "" {
+		return repository.CloneBareContext(ctx, r.URL, auth)
+	}
+	return repository.PlainCloneBareContext(ctx, r.URL, repoStorageDir(name), auth)
+}
+
+// repoStorageDir returns the directory the named repo is stored in.
+func repoStorageDir(name string) string {
+	return filepath.Join(serverConfig.StorageDir, name)
+}
@@ -0,0 +1,67 @@
+package serve
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_nextUpdateDelay(t *testing.T) {
+	type args struct {
+		frequency time.Duration
+		failures  int
+		random    float64
+	}
+	tests := []struct {
+		name string
+		args args
+		want time.Duration
+	}{
+		{
+			name: "No failures",
+			args: args{frequency: 600 * time.Second, failures: 0, random: 0.5},
+			want: 600 * time.Second,
+		},
+		{
+			name: "No failures with the most jitter removed",
+			args: args{frequency: 600 * time.Second, failures: 0, random: 0},
+			want: 540 * time.Second,
+		},
+		{
+			name: "No failures with the most jitter added",
+			args: args{frequency: 600 * time.Second, failures: 0, random: 1},
+			want: 660 * time.Second,
+		},
+		{
+			name: "One failure",
+			args: args{frequency: 600 * time.Second, failures: 1, random: 0.5},
+			want: 1200 * time.Second,
+		},
+		{
+			name: "Many failures are capped",
+			args: args{frequency: 600 * time.Second, failures: 10, random: 0.5},
+			want: maxUpdateBackoff,
+		},
+		{
+			name: "Failures with a frequency longer than the cap",
+			args: args{frequency: 2 * time.Hour, failures: 3, random: 0.5},
+			want: 2 * time.Hour,
+		},
+		{
+			name: "First failure without a frequency",
+			args: args{frequency: 0, failures: 1, random: 0.5},
+			want: updateRetryDelay,
+		},
+		{
+			name: "Third failure without a frequency",
+			args: args{frequency: 0, failures: 3, random: 0.5},
+			want: 4 * updateRetryDelay,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextUpdateDelay(tt.args.frequency, tt.args.failures, tt.args.random); got != tt.want {
+				t.Errorf("nextUpdateDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package serve
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/cfg8er/cfg8er/internal/config"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// tagVersion is a semver tag in the responses of the versions and resolve API.
+type tagVersion struct {
+	Tag     string `json:"tag"`
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"date"`
+}
+
+// Versions of the repo that the versions and resolve API are served at. A ref
+// named resolve takes precedence over the resolve API, while versions without
+// a path are never served, so the versions API doesn't hide a ref.
+const (
+	versionsVersion = "versions"
+	resolveVersion  = "resolve"
+)
+
+// getRepoVersion serves GET /r/:repo/versions, responding with the semver tags
+// of the repo from the lowest to the highest version. Other versions requested
+// without a path are redirected to the listing of their root directory.
+func getRepoVersion(c *gin.Context) {
+	if c.Param("version") != versionsVersion {
+		location := url.URL{Path: c.Request.URL.Path + "/", RawQuery: c.Request.URL.RawQuery}
+		c.Redirect(http.StatusMovedPermanently, location.String())
+		return
+	}
+
+	r, ok := semverRepo(c.Param("repo"))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	cloned := r.ClonedRepo()
+
+	tags, err := cloned.SemverTags()
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	versions := []tagVersion{}
+	for _, sr := range tags {
+		v, err := newTagVersion(cloned, sr.Ref, sr.Ver)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		versions = append(versions, *v)
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// getRepoResolve serves GET /r/:repo/resolve/:constraint, responding with the
+// semver tag the constraint currently resolves to.
+func getRepoResolve(c *gin.Context) {
+	r, ok := semverRepo(c.Param("repo"))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	constraint, err := semver.NewConstraint(strings.TrimPrefix(c.Param("path"), "/"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cloned := r.ClonedRepo()
+
+	ref, err := cloned.FindSemverTag(constraint)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	ver, err := semver.NewVersion(ref.Name().Short())
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	v, err := newTagVersion(cloned, ref, ver)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+// semverRepo looks up a repo with enable_semvers_tags set.
+func semverRepo(name string) (*config.Repo, bool) {
+	r, ok := repoLookup[name]
+	if !ok || !r.EnableSemversTags {
+		return nil, false
+	}
+	return r, true
+}
+
+// newTagVersion describes the semver tag ref with version ver.
+func newTagVersion(cloned *repository.Repository, ref *plumbing.Reference, ver *semver.Version) (*tagVersion, error) {
+	info, err := cloned.TagInfo(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tagVersion{
+		Tag:     info.Name,
+		Version: ver.String(),
+		Commit:  info.Commit.String(),
+		Date:    info.CommitDate.UTC().Format(time.RFC3339),
+	}, nil
+}
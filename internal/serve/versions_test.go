@@ -0,0 +1,110 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/config"
+)
+
+func Test_getRepoVersion(t *testing.T) {
+	files := map[string]string{"config.yml": "version: 1.0.0\n"}
+	repoLookup = map[string]*config.Repo{
+		"fixture":  newFixtureRepo(t, files, "v1.2.0", "v1.10.0", "v0.1.0", "stable"),
+		"disabled": newFixtureRepo(t, files, "v1.0.0"),
+		"named":    newFixtureRepo(t, files, "resolve"),
+	}
+	repoLookup["disabled"].EnableSemversTags = false
+
+	head, err := repoLookup["fixture"].ClonedRepo().Head()
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	sha := head.Hash().String()
+
+	tests := []struct {
+		name         string
+		url          string
+		wantStatus   int
+		wantBody     string
+		wantLocation string
+	}{
+		{
+			name:       "Versions",
+			url:        "/r/fixture/versions",
+			wantStatus: http.StatusOK,
+			wantBody: `[{"tag":"v0.1.0","version":"0.1.0","commit":"` + sha + `","date":"2018-09-01T12:00:00Z"},` +
+				`{"tag":"v1.2.0","version":"1.2.0","commit":"` + sha + `","date":"2018-09-01T12:00:00Z"},` +
+				`{"tag":"v1.10.0","version":"1.10.0","commit":"` + sha + `","date":"2018-09-01T12:00:00Z"}]`,
+		},
+		{
+			name:       "Versions without enable_semvers_tags",
+			url:        "/r/disabled/versions",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Versions of a non-existent repo",
+			url:        "/r/non-existent/versions",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Resolve",
+			url:        "/r/fixture/resolve/v1",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"tag":"v1.10.0","version":"1.10.0","commit":"` + sha + `","date":"2018-09-01T12:00:00Z"}`,
+		},
+		{
+			name:       "Resolve a range",
+			url:        "/r/fixture/resolve/%3E=1.0,%20%3C1.5",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"tag":"v1.2.0","version":"1.2.0","commit":"` + sha + `","date":"2018-09-01T12:00:00Z"}`,
+		},
+		{
+			name:       "Resolve an unmatched constraint",
+			url:        "/r/fixture/resolve/v2",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Resolve an invalid constraint",
+			url:        "/r/fixture/resolve/stable",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Resolve without enable_semvers_tags",
+			url:        "/r/disabled/resolve/v1",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Ref named resolve",
+			url:        "/r/named/resolve/config.yml",
+			wantStatus: http.StatusOK,
+			wantBody:   "version: 1.0.0",
+		},
+		{
+			name:         "Version without a path",
+			url:          "/r/fixture/v1?format=text",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/r/fixture/v1/?format=text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			newRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("getRepoVersion() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && strings.TrimSpace(w.Body.String()) != tt.wantBody {
+				t.Errorf("getRepoVersion() body = %v, want %v", w.Body.String(), tt.wantBody)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("getRepoVersion() Location = %v, want %v", got, tt.wantLocation)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package spring
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keySegment matches a segment of a flattened key: a name, or an index in
+// brackets.
+var keySegment = regexp.MustCompile(`\[([0-9]+)\]|[^.\[]+`)
+
+// Flatten flattens a decoded document into properties as Spring does, joining
+// the keys of nested mappings with dots and adding the indexes of list items
+// in brackets, eg. server.ports[0]. Empty mappings and lists are left out.
+func Flatten(doc interface{}) map[string]interface{} {
+	props := map[string]interface{}{}
+	flatten(props, "", doc)
+	return props
+}
+
+// flatten adds the properties of a value at a key prefix.
+func flatten(props map[string]interface{}, prefix string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flatten(props, key, value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			flatten(props, fmt.Sprintf("%s[%d]", prefix, i), value)
+		}
+	default:
+		if prefix != "" {
+			props[prefix] = v
+		}
+	}
+}
+
+// Unflatten nests flattened properties back into a document of mappings and
+// lists, the inverse of Flatten. Where keys conflict, eg. a and a.b, the
+// longer key wins.
+func Unflatten(props map[string]interface{}) map[string]interface{} {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var doc interface{} = map[string]interface{}{}
+	for _, key := range keys {
+		doc = setPath(doc, keySegment.FindAllStringSubmatch(key, -1), props[key])
+	}
+	return doc.(map[string]interface{})
+}
+
+// setPath returns a container with the value at a path of key segments set,
+// replacing the container if it's of the wrong kind.
+func setPath(container interface{}, segments [][]string, value interface{}) interface{} {
+	if len(segments) == 0 {
+		return value
+	}
+
+	segment := segments[0]
+	if segment[1] == "" {
+		m, ok := container.(map[string]interface{})
+		if !ok {
+			m = map[string]interface{}{}
+		}
+		m[segment[0]] = setPath(m[segment[0]], segments[1:], value)
+		return m
+	}
+
+	i, _ := strconv.Atoi(segment[1])
+	list, _ := container.([]interface{})
+	for len(list) <= i {
+		list = append(list, nil)
+	}
+	list[i] = setPath(list[i], segments[1:], value)
+	return list
+}
+
+// listRoot returns the key of the first list in a flattened key, eg. a.b for
+// a.b[0].c, or an empty string if it has no list.
+func listRoot(key string) string {
+	if i := strings.Index(key, "["); i >= 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// scalarString formats a property value as a string.
+func scalarString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
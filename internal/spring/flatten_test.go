@@ -0,0 +1,46 @@
+package spring
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	doc := map[string]interface{}{
+		"server": map[string]interface{}{"port": 8080, "ssl": map[string]interface{}{"enabled": true}},
+		"hosts":  []interface{}{"a", map[string]interface{}{"name": "b"}},
+		"empty":  map[string]interface{}{},
+	}
+	want := map[string]interface{}{
+		"server.port":        8080,
+		"server.ssl.enabled": true,
+		"hosts[0]":           "a",
+		"hosts[1].name":      "b",
+	}
+
+	got := Flatten(doc)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Flatten() = %v, want %v", got, want)
+	}
+
+	delete(doc, "empty")
+	if got := Unflatten(got); !reflect.DeepEqual(got, doc) {
+		t.Errorf("Unflatten() = %v, want %v", got, doc)
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	props := map[string]interface{}{
+		"a":       "scalar",
+		"a.b":     "nested",
+		"list[1]": "second",
+	}
+	want := map[string]interface{}{
+		"a":    map[string]interface{}{"b": "nested"},
+		"list": []interface{}{nil, "second"},
+	}
+
+	if got := Unflatten(props); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unflatten() = %v, want %v", got, want)
+	}
+}
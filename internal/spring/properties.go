@@ -0,0 +1,170 @@
+package spring
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseProperties parses a Java .properties file into a map of keys to string
+// values. Keys are separated from values by =, : or whitespace, lines starting
+// with # or ! are comments and lines ending with an odd number of backslashes
+// continue on the next line. Escapes such as \n, \t and \uXXXX are decoded.
+func ParseProperties(data []byte) (map[string]interface{}, error) {
+	props := map[string]interface{}{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var logical strings.Builder
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical.Len() == 0 && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+
+		if continues(line) {
+			logical.WriteString(line[:len(line)-1])
+			continue
+		}
+		logical.WriteString(line)
+
+		key, value, err := splitProperty(logical.String())
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %v", n, err)
+		}
+		props[key] = value
+		logical.Reset()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if logical.Len() > 0 {
+		key, value, err := splitProperty(logical.String())
+		if err != nil {
+			return nil, err
+		}
+		props[key] = value
+	}
+
+	return props, nil
+}
+
+// continues reports whether a line ends with an odd number of backslashes,
+// continuing the property on the next line.
+func continues(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// splitProperty splits a logical line into its unescaped key and value.
+func splitProperty(line string) (string, string, error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+
+	key, rest := line[:end], strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	key, err := unescape(key)
+	if err != nil {
+		return "", "", err
+	}
+	value, err := unescape(rest)
+	if err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// unescape decodes the escapes in a key or value.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i == len(s)-1 {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("Malformed \\u escape in %s", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("Malformed \\u escape in %s", s)
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// EncodeProperties encodes flattened properties as a .properties file, one
+// key: value line per property in the order of the keys, as Spring Cloud
+// Config Server renders them.
+func EncodeProperties(props map[string]interface{}) []byte {
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, key := range keys {
+		value := ""
+		if props[key] != nil {
+			value = scalarString(props[key])
+		}
+		fmt.Fprintf(&b, "%s: %s\n", escapeKey(key), escapeValue(value))
+	}
+	return b.Bytes()
+}
+
+// escapeKey escapes the characters of a key that would end it.
+func escapeKey(key string) string {
+	return strings.NewReplacer(`\`, `\\`, "=", `\=`, ":", `\:`, " ", `\ `, "\t", `\t`, "\n", `\n`).Replace(key)
+}
+
+// escapeValue escapes the characters of a value that would end or change it.
+func escapeValue(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(value)
+	if strings.HasPrefix(value, " ") {
+		value = `\` + value
+	}
+	return value
+}
@@ -0,0 +1,70 @@
+package spring
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "Separators",
+			data: "# Comment\n! Comment\na=1\nb: 2\nc 3\nd = 4\ne\n",
+			want: map[string]interface{}{"a": "1", "b": "2", "c": "3", "d": "4", "e": ""},
+		},
+		{
+			name: "Continuation",
+			data: "fruits = apple, \\\n    banana, \\\n    pear\n",
+			want: map[string]interface{}{"fruits": "apple, banana, pear"},
+		},
+		{
+			name: "Escapes",
+			data: "key\\ with\\=separators = tab\\there\\u00e9\nbackslash = C:\\\\\n",
+			want: map[string]interface{}{"key with=separators": "tab\there\u00e9", "backslash": `C:\`},
+		},
+		{
+			name:    "Malformed unicode escape",
+			data:    "a = \\u00\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProperties([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProperties() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) && !tt.wantErr {
+				t.Errorf("ParseProperties() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeProperties(t *testing.T) {
+	props := map[string]interface{}{
+		"server.port":   8080,
+		"greeting":      " hello\nworld",
+		"key=with:sep":  true,
+		"eureka.client": nil,
+	}
+	want := "eureka.client: \ngreeting: \\ hello\\nworld\nkey\\=with\\:sep: true\nserver.port: 8080\n"
+
+	got := EncodeProperties(props)
+	if string(got) != want {
+		t.Errorf("EncodeProperties() = %q, want %q", got, want)
+	}
+
+	parsed, err := ParseProperties(got)
+	if err != nil {
+		t.Fatalf("ParseProperties() error = %v", err)
+	}
+	if parsed["greeting"] != props["greeting"] || parsed["key=with:sep"] != "true" {
+		t.Errorf("ParseProperties(EncodeProperties()) = %v, want %v", parsed, props)
+	}
+}
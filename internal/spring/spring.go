@@ -0,0 +1,184 @@
+// Package spring reads configuration from a repo as a Spring Cloud Config
+// Server reads it from its Git backend, so Spring applications can use cfg8er
+// as their config server.
+package spring
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/cfg8er/cfg8er/internal/format"
+	"github.com/cfg8er/cfg8er/pkg/repository"
+)
+
+// DefaultProfile is the profile of requests that don't name one.
+const DefaultProfile = "default"
+
+// sharedName is the name of the files shared by every application.
+const sharedName = "application"
+
+// applicationPlaceholder is replaced by the application name in search paths.
+const applicationPlaceholder = "{application}"
+
+// extensions are the extensions of property files, in order of precedence.
+var extensions = []string{".properties", ".yml", ".yaml", ".json"}
+
+// placeholder matches a placeholder in a property value, eg. ${server.port}
+// or ${server.port:8080} with a default.
+var placeholder = regexp.MustCompile(`\$\{([^}:]+)(?::([^}]*))?\}`)
+
+// maxPlaceholderDepth is the most placeholders resolved within each other.
+const maxPlaceholderDepth = 10
+
+// Environment is the configuration of an application, as returned by a Spring
+// Cloud Config Server.
+type Environment struct {
+	Name            string           `json:"name"`
+	Profiles        []string         `json:"profiles"`
+	Label           string           `json:"label"`
+	Version         string           `json:"version"`
+	State           *string          `json:"state"`
+	PropertySources []PropertySource `json:"propertySources"`
+}
+
+// PropertySource is the flattened properties of a file.
+type PropertySource struct {
+	Name   string                 `json:"name"`
+	Source map[string]interface{} `json:"source"`
+}
+
+// Load returns the property sources of an application with profiles at a
+// version, from the highest to the lowest precedence as Spring orders them.
+// For each profile, from the last to the first, they are {application}-
+// {profile} and application-{profile}, and then {application} and application,
+// each in every search path. Each of those may be a .properties, .yml, .yaml or
+// .json file. {application} in search paths is replaced by the application
+// name. The name of each source is its path prefixed by namePrefix.
+func Load(cloned *repository.Repository, v *repository.Version, searchPaths []string, application string, profiles []string, namePrefix string) ([]PropertySource, error) {
+	var names []string
+	for i := len(profiles) - 1; i >= 0; i-- {
+		names = append(names, application+"-"+profiles[i], sharedName+"-"+profiles[i])
+	}
+	names = append(names, application, sharedName)
+
+	var sources []PropertySource
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		for _, searchPath := range searchPaths {
+			dir := strings.Replace(searchPath, applicationPlaceholder, application, -1)
+			for _, ext := range extensions {
+				filePath := path.Join("/", dir, name+ext)
+				if seen[filePath] {
+					continue
+				}
+				seen[filePath] = true
+
+				props, ok, err := readProperties(cloned, filePath, v)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					sources = append(sources, PropertySource{Name: namePrefix + filePath, Source: props})
+				}
+			}
+		}
+	}
+
+	return sources, nil
+}
+
+// readProperties returns the flattened properties of the file at filePath at a
+// version. Returns false if there's no such file.
+func readProperties(cloned *repository.Repository, filePath string, v *repository.Version) (map[string]interface{}, bool, error) {
+	data, err := cloned.ReadAtVersion(filePath, v)
+	if err == repository.ErrNotExist {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	if path.Ext(filePath) == ".properties" {
+		props, err := ParseProperties(data)
+		if err != nil {
+			return nil, false, fmt.Errorf("Parse %s: %v", filePath, err)
+		}
+		return props, true, nil
+	}
+
+	f, _ := format.FromPath(filePath)
+	doc, err := format.Decode(f, data)
+	if err != nil {
+		return nil, false, fmt.Errorf("Parse %s: %v", filePath, err)
+	}
+	return Flatten(doc), true, nil
+}
+
+// Merge merges property sources, ordered from the highest to the lowest
+// precedence, into one set of properties. A source that sets any item of a
+// list replaces the whole list of lower precedence sources, as Spring binds
+// lists.
+func Merge(sources []PropertySource) map[string]interface{} {
+	merged := map[string]interface{}{}
+	lists := map[string]bool{}
+
+	for _, source := range sources {
+		sourceLists := map[string]bool{}
+		for key, value := range source.Source {
+			if _, ok := merged[key]; ok {
+				continue
+			}
+
+			root := listRoot(key)
+			if root != "" {
+				if lists[root] {
+					continue
+				}
+				sourceLists[root] = true
+			}
+			merged[key] = value
+		}
+
+		for root := range sourceLists {
+			lists[root] = true
+		}
+	}
+
+	return merged
+}
+
+// ResolvePlaceholders replaces the ${key} and ${key:default} placeholders in
+// the string values of properties with the values of other properties, or
+// their default if there's no such property. Placeholders of properties
+// without a default that aren't set are left as is.
+func ResolvePlaceholders(props map[string]interface{}) map[string]interface{} {
+	resolved := make(map[string]interface{}, len(props))
+	for key, value := range props {
+		s, ok := value.(string)
+		if !ok {
+			resolved[key] = value
+			continue
+		}
+
+		for depth := 0; depth < maxPlaceholderDepth && placeholder.MatchString(s); depth++ {
+			next := placeholder.ReplaceAllStringFunc(s, func(m string) string {
+				parts := placeholder.FindStringSubmatch(m)
+				if value, ok := props[parts[1]]; ok && value != nil {
+					return scalarString(value)
+				}
+				if strings.Contains(m, ":") {
+					return parts[2]
+				}
+				return m
+			})
+			if next == s {
+				break
+			}
+			s = next
+		}
+		resolved[key] = s
+	}
+	return resolved
+}
@@ -0,0 +1,41 @@
+package spring
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	sources := []PropertySource{
+		{Name: "app-dev.yml", Source: map[string]interface{}{"server.port": 9090, "hosts[0]": "dev"}},
+		{Name: "app.yml", Source: map[string]interface{}{"server.port": 8080, "hosts[0]": "a", "hosts[1]": "b", "name": "app"}},
+	}
+	want := map[string]interface{}{"server.port": 9090, "hosts[0]": "dev", "name": "app"}
+
+	if got := Merge(sources); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %v, want %v", got, want)
+	}
+}
+
+func TestResolvePlaceholders(t *testing.T) {
+	props := map[string]interface{}{
+		"host":    "db.example.com",
+		"port":    5432,
+		"url":     "jdbc:postgresql://${host}:${port}/${name:app}",
+		"nested":  "${url}?ssl=true",
+		"missing": "${undefined}",
+		"enabled": true,
+	}
+	want := map[string]interface{}{
+		"host":    "db.example.com",
+		"port":    5432,
+		"url":     "jdbc:postgresql://db.example.com:5432/app",
+		"nested":  "jdbc:postgresql://db.example.com:5432/app?ssl=true",
+		"missing": "${undefined}",
+		"enabled": true,
+	}
+
+	if got := ResolvePlaceholders(props); !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolvePlaceholders() = %v, want %v", got, want)
+	}
+}
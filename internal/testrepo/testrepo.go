@@ -0,0 +1,153 @@
+// Package testrepo builds local Git repos for tests so they don't depend on
+// network access.
+package testrepo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// Signature is the author and committer of every commit and the tagger of
+// every annotated tag.
+var Signature = object.Signature{
+	Name:  "Cfg8er Fixture",
+	Email: "fixture@cfg8er.invalid",
+	When:  time.Date(2018, 9, 1, 12, 0, 0, 0, time.UTC),
+}
+
+// Repo is a local, non-bare repo built by a test.
+type Repo struct {
+	t    *testing.T
+	Dir  string
+	Repo *git.Repository
+	// SignKey, if not nil, signs the commits and annotated tags created.
+	SignKey *openpgp.Entity
+}
+
+// New initializes an empty repo in a temporary dir that is removed when the
+// test completes.
+func New(t *testing.T) *Repo {
+	dir, err := ioutil.TempDir("", "cfg8er-fixture")
+	if err != nil {
+		t.Fatalf("testrepo.New() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("testrepo.New() error = %v", err)
+	}
+
+	return &Repo{t: t, Dir: dir, Repo: repo}
+}
+
+// Commit writes files, a map of path to contents, into the worktree and
+// commits them on the current branch. Returns the commit hash.
+func (f *Repo) Commit(msg string, files map[string]string) plumbing.Hash {
+	w, err := f.Repo.Worktree()
+	if err != nil {
+		f.t.Fatalf("Repo.Commit() error = %v", err)
+	}
+
+	for p, contents := range files {
+		fullPath := filepath.Join(f.Dir, p)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			f.t.Fatalf("Repo.Commit() error = %v", err)
+		}
+		if err := ioutil.WriteFile(fullPath, []byte(contents), 0644); err != nil {
+			f.t.Fatalf("Repo.Commit() error = %v", err)
+		}
+		if _, err := w.Add(p); err != nil {
+			f.t.Fatalf("Repo.Commit() error = %v", err)
+		}
+	}
+
+	sig := Signature
+	hash, err := w.Commit(msg, &git.CommitOptions{Author: &sig, Committer: &sig, SignKey: f.SignKey})
+	if err != nil {
+		f.t.Fatalf("Repo.Commit() error = %v", err)
+	}
+	return hash
+}
+
+// Tag creates a lightweight tag name pointing at hash.
+func (f *Repo) Tag(name string, hash plumbing.Hash) {
+	ref := plumbing.NewHashReference(plumbing.ReferenceName("refs/tags/"+name), hash)
+	if err := f.Repo.Storer.SetReference(ref); err != nil {
+		f.t.Fatalf("Repo.Tag() error = %v", err)
+	}
+}
+
+// AnnotatedTag creates an annotated tag object name pointing at the target
+// object of type targetType, and a tag reference to it. Returns the tag object
+// hash.
+func (f *Repo) AnnotatedTag(name string, target plumbing.Hash, targetType plumbing.ObjectType, msg string) plumbing.Hash {
+	tag := &object.Tag{
+		Name:       name,
+		Tagger:     Signature,
+		Message:    msg,
+		TargetType: targetType,
+		Target:     target,
+	}
+
+	if f.SignKey != nil {
+		unsigned := &plumbing.MemoryObject{}
+		if err := tag.Encode(unsigned); err != nil {
+			f.t.Fatalf("Repo.AnnotatedTag() error = %v", err)
+		}
+		reader, _ := unsigned.Reader()
+		sig := &bytes.Buffer{}
+		if err := openpgp.ArmoredDetachSign(sig, f.SignKey, reader, nil); err != nil {
+			f.t.Fatalf("Repo.AnnotatedTag() error = %v", err)
+		}
+		tag.PGPSignature = sig.String()
+	}
+
+	obj := f.Repo.Storer.NewEncodedObject()
+	if err := tag.Encode(obj); err != nil {
+		f.t.Fatalf("Repo.AnnotatedTag() error = %v", err)
+	}
+	hash, err := f.Repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		f.t.Fatalf("Repo.AnnotatedTag() error = %v", err)
+	}
+
+	f.Tag(name, hash)
+	return hash
+}
+
+// NewKey generates a PGP key to sign commits and tags with.
+func NewKey(t *testing.T, name string) *openpgp.Entity {
+	e, err := openpgp.NewEntity(name, "", name+"@cfg8er.invalid", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatalf("NewKey() error = %v", err)
+	}
+	return e
+}
+
+// ArmoredKeyRing returns the armored public keys of entities.
+func ArmoredKeyRing(t *testing.T, entities ...*openpgp.Entity) string {
+	buf := &bytes.Buffer{}
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("ArmoredKeyRing() error = %v", err)
+	}
+	for _, e := range entities {
+		if err := e.Serialize(w); err != nil {
+			t.Fatalf("ArmoredKeyRing() error = %v", err)
+		}
+	}
+	w.Close()
+	return buf.String()
+}
@@ -0,0 +1,174 @@
+// Package webhook verifies and parses the push and tag webhooks sent by
+// GitHub, GitLab and Gitea.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Providers of webhooks.
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+	Gitea  = "gitea"
+)
+
+var (
+	// ErrNoSecret is returned when there's no secret to verify a webhook
+	// against.
+	ErrNoSecret = errors.New("webhook secret isn't set")
+	// ErrMissingSignature is returned when the webhook carries no signature
+	// or token to check the secret against, eg. if it isn't from a known
+	// provider.
+	ErrMissingSignature = errors.New("webhook has no signature or token")
+	// ErrInvalidSignature is returned when the webhook signature or token
+	// doesn't match the configured secret.
+	ErrInvalidSignature = errors.New("webhook signature or token mismatch")
+)
+
+// Event is a verified webhook.
+type Event struct {
+	// Provider is GitHub, GitLab or Gitea.
+	Provider string
+	// Name is the event name as sent by the provider, eg. push or Tag Push Hook.
+	Name string
+	// Ref is the full name of the pushed or created reference if the payload
+	// has one, eg. refs/tags/v1.0.0.
+	Ref string
+}
+
+// payload holds the fields of the push and tag event payloads that are used.
+type payload struct {
+	Ref     string `json:"ref"`
+	RefType string `json:"ref_type"`
+}
+
+// Parse verifies the webhook with header and body against secret and returns
+// the Event it describes. GitHub and Gitea webhooks are verified by the
+// HMAC signature of the body, GitLab webhooks by their token. Webhooks from
+// other providers can't be verified. The payload is the body, or the payload
+// field of a form encoded body as GitHub can send.
+func Parse(header http.Header, body []byte, secret string) (*Event, error) {
+	if secret == "" {
+		return nil, ErrNoSecret
+	}
+
+	event := &Event{}
+
+	switch {
+	case header.Get("X-Gitea-Event") != "":
+		event.Provider = Gitea
+		event.Name = header.Get("X-Gitea-Event")
+		if err := verifyHMAC(sha256.New, secret, body, header.Get("X-Gitea-Signature")); err != nil {
+			return nil, err
+		}
+	case header.Get("X-Gitlab-Event") != "":
+		event.Provider = GitLab
+		event.Name = header.Get("X-Gitlab-Event")
+		if err := verifyToken(secret, header.Get("X-Gitlab-Token")); err != nil {
+			return nil, err
+		}
+	case header.Get("X-GitHub-Event") != "":
+		event.Provider = GitHub
+		event.Name = header.Get("X-GitHub-Event")
+		if err := verifyGitHub(header, body, secret); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrMissingSignature
+	}
+
+	data := body
+	if mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type")); mediaType == "application/x-www-form-urlencoded" {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(form.Get("payload"))
+	}
+
+	if len(data) > 0 {
+		p := payload{}
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, err
+		}
+		event.Ref = p.Ref
+		// GitHub and Gitea create events have a short ref with its type on the side
+		if p.RefType == "tag" && !strings.HasPrefix(p.Ref, "refs/") {
+			event.Ref = "refs/tags/" + p.Ref
+		} else if p.RefType == "branch" && !strings.HasPrefix(p.Ref, "refs/") {
+			event.Ref = "refs/heads/" + p.Ref
+		}
+	}
+
+	return event, nil
+}
+
+// IsUpdate reports whether the event means references in the repo changed.
+func (e *Event) IsUpdate() bool {
+	switch e.Provider {
+	case GitHub, Gitea:
+		return e.Name == "push" || e.Name == "create"
+	case GitLab:
+		return e.Name == "Push Hook" || e.Name == "Tag Push Hook"
+	}
+	return false
+}
+
+// verifyGitHub checks the sha256 signature of a GitHub webhook, falling back
+// to the legacy sha1 signature.
+func verifyGitHub(header http.Header, body []byte, secret string) error {
+	if sig := header.Get("X-Hub-Signature-256"); sig != "" {
+		if !strings.HasPrefix(sig, "sha256=") {
+			return ErrInvalidSignature
+		}
+		return verifyHMAC(sha256.New, secret, body, strings.TrimPrefix(sig, "sha256="))
+	}
+
+	sig := header.Get("X-Hub-Signature")
+	if sig != "" && !strings.HasPrefix(sig, "sha1=") {
+		return ErrInvalidSignature
+	}
+	return verifyHMAC(sha1.New, secret, body, strings.TrimPrefix(sig, "sha1="))
+}
+
+// verifyHMAC checks the hex encoded signature is the HMAC of body keyed with secret.
+func verifyHMAC(h func() hash.Hash, secret string, body []byte, signature string) error {
+	if signature == "" {
+		return ErrMissingSignature
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// verifyToken checks the token sent with the webhook is the secret.
+func verifyToken(secret string, token string) error {
+	if token == "" {
+		return ErrMissingSignature
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(token)) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
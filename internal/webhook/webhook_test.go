@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func sign(h func() hash.Hash, secret string, body []byte) string {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParse(t *testing.T) {
+	secret := "s3cret"
+	pushBody := []byte(`{"ref":"refs/tags/v1.0.0","before":"0000","after":"1111"}`)
+	createBody := []byte(`{"ref":"v1.1.0","ref_type":"tag"}`)
+	formBody := []byte(url.Values{"payload": {string(pushBody)}}.Encode())
+
+	type args struct {
+		header http.Header
+		body   []byte
+		secret string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Event
+		wantErr error
+	}{
+		{
+			name: "GitHub push with sha256 signature",
+			args: args{
+				header: http.Header{
+					"X-Github-Event":      {"push"},
+					"X-Hub-Signature-256": {"sha256=" + sign(sha256.New, secret, pushBody)},
+				},
+				body:   pushBody,
+				secret: secret,
+			},
+			want: &Event{Provider: GitHub, Name: "push", Ref: "refs/tags/v1.0.0"},
+		},
+		{
+			name: "GitHub form encoded push",
+			args: args{
+				header: http.Header{
+					"Content-Type":        {"application/x-www-form-urlencoded"},
+					"X-Github-Event":      {"push"},
+					"X-Hub-Signature-256": {"sha256=" + sign(sha256.New, secret, formBody)},
+				},
+				body:   formBody,
+				secret: secret,
+			},
+			want: &Event{Provider: GitHub, Name: "push", Ref: "refs/tags/v1.0.0"},
+		},
+		{
+			name: "GitHub create with sha1 signature",
+			args: args{
+				header: http.Header{
+					"X-Github-Event":  {"create"},
+					"X-Hub-Signature": {"sha1=" + sign(sha1.New, secret, createBody)},
+				},
+				body:   createBody,
+				secret: secret,
+			},
+			want: &Event{Provider: GitHub, Name: "create", Ref: "refs/tags/v1.1.0"},
+		},
+		{
+			name: "GitHub push with the wrong secret",
+			args: args{
+				header: http.Header{
+					"X-Github-Event":      {"push"},
+					"X-Hub-Signature-256": {"sha256=" + sign(sha256.New, "wrong", pushBody)},
+				},
+				body:   pushBody,
+				secret: secret,
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "GitHub push without a signature",
+			args: args{
+				header: http.Header{"X-Github-Event": {"push"}},
+				body:   pushBody,
+				secret: secret,
+			},
+			wantErr: ErrMissingSignature,
+		},
+		{
+			name: "GitLab tag push with token",
+			args: args{
+				header: http.Header{
+					"X-Gitlab-Event": {"Tag Push Hook"},
+					"X-Gitlab-Token": {secret},
+				},
+				body:   pushBody,
+				secret: secret,
+			},
+			want: &Event{Provider: GitLab, Name: "Tag Push Hook", Ref: "refs/tags/v1.0.0"},
+		},
+		{
+			name: "GitLab push with the wrong token",
+			args: args{
+				header: http.Header{
+					"X-Gitlab-Event": {"Push Hook"},
+					"X-Gitlab-Token": {"wrong"},
+				},
+				body:   pushBody,
+				secret: secret,
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name: "Gitea push with signature",
+			args: args{
+				header: http.Header{
+					"X-Gitea-Event":     {"push"},
+					"X-Github-Event":    {"push"},
+					"X-Gitea-Signature": {sign(sha256.New, secret, pushBody)},
+				},
+				body:   pushBody,
+				secret: secret,
+			},
+			want: &Event{Provider: Gitea, Name: "push", Ref: "refs/tags/v1.0.0"},
+		},
+		{
+			name: "Webhook without a secret",
+			args: args{
+				header: http.Header{"X-Gitlab-Event": {"Push Hook"}},
+				body:   pushBody,
+			},
+			wantErr: ErrNoSecret,
+		},
+		{
+			name:    "Unknown provider with a secret",
+			args:    args{header: http.Header{}, secret: secret},
+			wantErr: ErrMissingSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.args.header, tt.args.body, tt.args.secret)
+			if err != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvent_IsUpdate(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  bool
+	}{
+		{name: "GitHub push", event: Event{Provider: GitHub, Name: "push"}, want: true},
+		{name: "GitHub ping", event: Event{Provider: GitHub, Name: "ping"}, want: false},
+		{name: "GitLab tag push", event: Event{Provider: GitLab, Name: "Tag Push Hook"}, want: true},
+		{name: "GitLab issue", event: Event{Provider: GitLab, Name: "Issue Hook"}, want: false},
+		{name: "Gitea create", event: Event{Provider: Gitea, Name: "create"}, want: true},
+		{name: "Unknown provider", event: Event{}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.IsUpdate(); got != tt.want {
+				t.Errorf("Event.IsUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
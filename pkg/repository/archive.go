@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/gitignore"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// gitAttributesFile is the name of the files setting export-ignore.
+const gitAttributesFile = ".gitattributes"
+
+// Archive is a reproducible archive of a directory at a version. Entries are
+// sorted by name, keep their Git file mode and have the commit date as their
+// modification time so archiving the same directory at the same commit always
+// gives identical bytes. Paths with the export-ignore attribute set in a
+// .gitattributes file are left out, as by git archive.
+type Archive struct {
+	r       *Repository
+	entries []archiveEntry
+	modTime time.Time
+}
+
+// archiveEntry is a file, symlink or directory in an Archive.
+type archiveEntry struct {
+	name string
+	mode filemode.FileMode
+	hash plumbing.Hash
+}
+
+// NewArchive prepares an archive of the directory at a given path at a version
+// resolved by ResolveSemVer. Entries are named relative to the directory.
+func (r *Repository) NewArchive(dirPath string, v *Version) (*Archive, error) {
+	commit, err := r.commitAtHash(v.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("Tree of commit %v: %s", commit.TreeHash, err)
+	}
+
+	dirPath = strings.Trim(path.Clean("/"+dirPath), "/")
+
+	var target []string
+	if dirPath != "" {
+		if _, err := tree.Tree(dirPath); err != nil {
+			return nil, fmt.Errorf("Directory in tree %s: %s", dirPath, err)
+		}
+		target = strings.Split(dirPath, "/")
+	}
+
+	a := &Archive{r: r, modTime: commit.Committer.When}
+	if err := a.walk(tree, nil, target, nil); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(a.entries, func(i, j int) bool { return a.entries[i].name < a.entries[j].name })
+	return a, nil
+}
+
+// walk adds the entries of tree, at dir, that are within the target directory
+// to the Archive. rules are the export-ignore rules of the parent directories.
+func (a *Archive) walk(tree *object.Tree, dir []string, target []string, rules []exportIgnoreRule) error {
+	if f, err := tree.File(gitAttributesFile); err == nil {
+		contents, err := f.Contents()
+		if err != nil {
+			return fmt.Errorf("Read %s: %s", path.Join(append(dir, gitAttributesFile)...), err)
+		}
+		rules = append(rules[:len(rules):len(rules)], parseExportIgnore(contents, dir)...)
+	}
+
+	for _, e := range tree.Entries {
+		if e.Mode == filemode.Submodule {
+			continue
+		}
+
+		parts := append(dir[:len(dir):len(dir)], e.Name)
+		isDir := e.Mode == filemode.Dir
+		inTarget := len(parts) > len(target) && hasPathPrefix(parts, target)
+
+		if !inTarget && !(isDir && hasPathPrefix(target, parts)) {
+			continue
+		}
+		if exportIgnored(rules, parts, isDir) {
+			continue
+		}
+
+		if inTarget {
+			a.entries = append(a.entries, archiveEntry{
+				name: path.Join(parts[len(target):]...),
+				mode: e.Mode,
+				hash: e.Hash,
+			})
+		}
+
+		if isDir {
+			subtree, err := a.r.TreeObject(e.Hash)
+			if err != nil {
+				return fmt.Errorf("Tree object of %v: %s", e.Hash, err)
+			}
+			if err := a.walk(subtree, parts, target, rules); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteTarGz writes the Archive to w as a gzip compressed tar archive.
+func (a *Archive) WriteTarGz(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, e := range a.entries {
+		hdr := &tar.Header{
+			Name:    e.name,
+			Mode:    0644,
+			ModTime: a.modTime,
+		}
+
+		switch e.mode {
+		case filemode.Dir:
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			hdr.Mode = 0755
+		case filemode.Symlink:
+			target, err := a.blobContents(e.hash)
+			if err != nil {
+				return err
+			}
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = string(target)
+			hdr.Mode = 0777
+		default:
+			hdr.Typeflag = tar.TypeReg
+			if e.mode == filemode.Executable {
+				hdr.Mode = 0755
+			}
+			blob, err := a.r.BlobObject(e.hash)
+			if err != nil {
+				return fmt.Errorf("Blob object of %v: %s", e.hash, err)
+			}
+			hdr.Size = blob.Size
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if err := a.copyBlob(tw, e.hash); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// WriteZip writes the Archive to w as a zip archive.
+func (a *Archive) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	for _, e := range a.entries {
+		hdr := &zip.FileHeader{
+			Name:     e.name,
+			Method:   zip.Deflate,
+			Modified: a.modTime.UTC(),
+		}
+
+		switch e.mode {
+		case filemode.Dir:
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+			hdr.SetMode(os.ModeDir | 0755)
+		case filemode.Symlink:
+			hdr.SetMode(os.ModeSymlink | 0777)
+		case filemode.Executable:
+			hdr.SetMode(0755)
+		default:
+			hdr.SetMode(0644)
+		}
+
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		// Symlinks are stored with their target as contents
+		if e.mode != filemode.Dir {
+			if err := a.copyBlob(fw, e.hash); err != nil {
+				return err
+			}
+		}
+	}
+
+	return zw.Close()
+}
+
+// copyBlob copies the contents of the blob with hash to w.
+func (a *Archive) copyBlob(w io.Writer, hash plumbing.Hash) error {
+	blob, err := a.r.BlobObject(hash)
+	if err != nil {
+		return fmt.Errorf("Blob object of %v: %s", hash, err)
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// blobContents reads the contents of the blob with hash.
+func (a *Archive) blobContents(hash plumbing.Hash) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := a.copyBlob(&buf, hash); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exportIgnoreRule is a .gitattributes line setting or unsetting export-ignore
+// for the paths matching its pattern.
+type exportIgnoreRule struct {
+	pattern gitignore.Pattern
+	ignore  bool
+}
+
+// parseExportIgnore parses the export-ignore rules of the .gitattributes file
+// in dir. Rules for other attributes are skipped.
+func parseExportIgnore(contents string, dir []string) []exportIgnoreRule {
+	var rules []exportIgnoreRule
+
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+
+		for _, attr := range fields[1:] {
+			switch attr {
+			case "export-ignore":
+				rules = append(rules, exportIgnoreRule{gitignore.ParsePattern(fields[0], dir), true})
+			case "-export-ignore", "!export-ignore":
+				rules = append(rules, exportIgnoreRule{gitignore.ParsePattern(fields[0], dir), false})
+			}
+		}
+	}
+
+	return rules
+}
+
+// exportIgnored reports whether the last of rules matching the path parts sets
+// export-ignore.
+func exportIgnored(rules []exportIgnoreRule, parts []string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.pattern.Match(parts, isDir) != gitignore.NoMatch {
+			ignored = rule.ignore
+		}
+	}
+	return ignored
+}
+
+// hasPathPrefix reports whether the path parts start with prefix.
+func hasPathPrefix(parts []string, prefix []string) bool {
+	if len(parts) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if parts[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
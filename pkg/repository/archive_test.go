@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cfg8er/cfg8er/internal/testrepo"
+)
+
+// newArchiveFixture returns a repo with an executable, a symlink and
+// .gitattributes files setting export-ignore at two levels.
+func newArchiveFixture(t *testing.T) (*Repository, *Version) {
+	fixture := testrepo.New(t)
+
+	if err := os.MkdirAll(filepath.Join(fixture.Dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(fixture.Dir, "bin/setup.sh"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("setup.sh", filepath.Join(fixture.Dir, "bin/setup")); err != nil {
+		t.Fatal(err)
+	}
+	w, err := fixture.Repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add("bin/setup"); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := fixture.Commit("First", map[string]string{
+		".gitattributes":        "docs export-ignore\n*.secret export-ignore\n*.yml text\n",
+		"config.yml":            "version: 1.0.0\n",
+		"bin/setup.sh":          "#!/bin/sh\n",
+		"docs/README.md":        "# Docs\n",
+		"roles/.gitattributes":  "web/tmp.yml export-ignore\nkeep.secret -export-ignore\n",
+		"roles/web/vars.yml":    "port: 80\n",
+		"roles/web/tmp.yml":     "tmp: true\n",
+		"roles/web/key.secret":  "s3cret\n",
+		"roles/web/keep.secret": "public\n",
+	})
+	fixture.Tag("v1.0.0", hash)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("CloneBare() error = %v", err)
+	}
+
+	v, err := r.ResolveSemVer("v1")
+	if err != nil {
+		t.Fatalf("Repository.ResolveSemVer() error = %v", err)
+	}
+
+	return &r, v
+}
+
+func TestRepository_NewArchive(t *testing.T) {
+	r, v := newArchiveFixture(t)
+
+	tests := []struct {
+		name    string
+		dirPath string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "Root",
+			dirPath: "/",
+			want: []string{
+				".gitattributes", "bin", "bin/setup", "bin/setup.sh", "config.yml",
+				"roles", "roles/.gitattributes", "roles/web", "roles/web/keep.secret", "roles/web/vars.yml",
+			},
+		},
+		{
+			name:    "Subdirectory with rules from parents",
+			dirPath: "/roles/web",
+			want:    []string{"keep.secret", "vars.yml"},
+		},
+		{
+			name:    "Ignored directory",
+			dirPath: "docs",
+			want:    nil,
+		},
+		{
+			name:    "Missing directory",
+			dirPath: "/missing",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.NewArchive(tt.dirPath, v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.NewArchive() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			var gotNames []string
+			for _, e := range got.entries {
+				gotNames = append(gotNames, e.name)
+			}
+			if !reflect.DeepEqual(gotNames, tt.want) {
+				t.Errorf("Repository.NewArchive() = %v, want %v", gotNames, tt.want)
+			}
+		})
+	}
+}
+
+func TestArchive_WriteTarGz(t *testing.T) {
+	r, v := newArchiveFixture(t)
+
+	a, err := r.NewArchive("/bin", v)
+	if err != nil {
+		t.Fatalf("Repository.NewArchive() error = %v", err)
+	}
+
+	var first, second bytes.Buffer
+	if err := a.WriteTarGz(&first); err != nil {
+		t.Fatalf("Archive.WriteTarGz() error = %v", err)
+	}
+	if err := a.WriteTarGz(&second); err != nil {
+		t.Fatalf("Archive.WriteTarGz() error = %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("Archive.WriteTarGz() isn't reproducible")
+	}
+
+	gz, err := gzip.NewReader(&first)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	want := []tar.Header{
+		{Name: "setup", Typeflag: tar.TypeSymlink, Linkname: "setup.sh", Mode: 0777},
+		{Name: "setup.sh", Typeflag: tar.TypeReg, Mode: 0755, Size: 10},
+	}
+	for _, w := range want {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatalf("Archive.WriteTarGz() error = %v", err)
+		}
+		if hdr.Name != w.Name || hdr.Typeflag != w.Typeflag || hdr.Linkname != w.Linkname || hdr.Mode != w.Mode || hdr.Size != w.Size {
+			t.Errorf("Archive.WriteTarGz() header = %+v, want %+v", hdr, w)
+		}
+		if !hdr.ModTime.Equal(testrepo.Signature.When) {
+			t.Errorf("Archive.WriteTarGz() %s mtime = %v, want %v", hdr.Name, hdr.ModTime, testrepo.Signature.When)
+		}
+	}
+	if contents, _ := ioutil.ReadAll(tr); string(contents) != "#!/bin/sh\n" {
+		t.Errorf("Archive.WriteTarGz() setup.sh = %q, want %q", contents, "#!/bin/sh\n")
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Errorf("Archive.WriteTarGz() has extra entries, error = %v", err)
+	}
+}
+
+func TestArchive_WriteZip(t *testing.T) {
+	r, v := newArchiveFixture(t)
+
+	a, err := r.NewArchive("/", v)
+	if err != nil {
+		t.Fatalf("Repository.NewArchive() error = %v", err)
+	}
+
+	var first, second bytes.Buffer
+	if err := a.WriteZip(&first); err != nil {
+		t.Fatalf("Archive.WriteZip() error = %v", err)
+	}
+	if err := a.WriteZip(&second); err != nil {
+		t.Fatalf("Archive.WriteZip() error = %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("Archive.WriteZip() isn't reproducible")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(first.Bytes()), int64(first.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+
+	want := map[string]os.FileMode{
+		"bin/":         os.ModeDir | 0755,
+		"bin/setup":    os.ModeSymlink | 0777,
+		"bin/setup.sh": 0755,
+		"config.yml":   0644,
+	}
+	for _, f := range zr.File {
+		mode, ok := want[f.Name]
+		if !ok {
+			continue
+		}
+		if f.Mode() != mode {
+			t.Errorf("Archive.WriteZip() %s mode = %v, want %v", f.Name, f.Mode(), mode)
+		}
+		if !f.Modified.Equal(testrepo.Signature.When.Truncate(time.Second)) {
+			t.Errorf("Archive.WriteZip() %s modified = %v, want %v", f.Name, f.Modified, testrepo.Signature.When)
+		}
+		delete(want, f.Name)
+	}
+	if len(want) != 0 {
+		t.Errorf("Archive.WriteZip() is missing %v", want)
+	}
+}
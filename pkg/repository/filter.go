@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// RefFilter decides which tags and branches of a Repository can be used by
+// matching glob patterns, eg. v1.0.*, against their short names. The pattern
+// syntax is that of path.Match.
+type RefFilter struct {
+	whitelist []string
+	blacklist []string
+}
+
+// NewRefFilter returns a RefFilter allowing refs that match a whitelist
+// pattern, or any ref if whitelist is empty, unless they also match a
+// blacklist pattern. Returns an error if a pattern is malformed.
+func NewRefFilter(whitelist []string, blacklist []string) (*RefFilter, error) {
+	for _, pattern := range append(append([]string{}, whitelist...), blacklist...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("Ref pattern %s: %s", pattern, err)
+		}
+	}
+
+	return &RefFilter{whitelist: whitelist, blacklist: blacklist}, nil
+}
+
+// Allowed reports whether the tag or branch short name, eg. v1.0.0 or master,
+// passes the filter. The blacklist wins over the whitelist.
+func (f *RefFilter) Allowed(name string) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, pattern := range f.blacklist {
+		if match, _ := path.Match(pattern, name); match {
+			return false
+		}
+	}
+
+	if len(f.whitelist) == 0 {
+		return true
+	}
+
+	for _, pattern := range f.whitelist {
+		if match, _ := path.Match(pattern, name); match {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedRef reports whether the tag or branch ref passes the filter. Remote
+// tracking branches, eg. refs/remotes/origin/master, are matched by their
+// branch name.
+func (f *RefFilter) AllowedRef(ref *plumbing.Reference) bool {
+	return f.Allowed(refShortName(ref.Name()))
+}
+
+// refShortName returns the name of a tag or branch without the refs/tags/,
+// refs/heads/ or refs/remotes/<remote>/ prefix.
+func refShortName(name plumbing.ReferenceName) string {
+	n := string(name)
+
+	switch {
+	case strings.HasPrefix(n, "refs/tags/"):
+		return strings.TrimPrefix(n, "refs/tags/")
+	case strings.HasPrefix(n, "refs/heads/"):
+		return strings.TrimPrefix(n, "refs/heads/")
+	case strings.HasPrefix(n, "refs/remotes/"):
+		remoteBranch := strings.TrimPrefix(n, "refs/remotes/")
+		if i := strings.Index(remoteBranch, "/"); i >= 0 {
+			return remoteBranch[i+1:]
+		}
+		return remoteBranch
+	}
+	return name.Short()
+}
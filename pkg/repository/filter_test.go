@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/cfg8er/cfg8er/internal/testrepo"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestNewRefFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		whitelist []string
+		blacklist []string
+		wantErr   bool
+	}{
+		{name: "Valid patterns", whitelist: []string{"v1.0.*"}, blacklist: []string{"v0.*"}, wantErr: false},
+		{name: "Malformed whitelist pattern", whitelist: []string{"v1.[0"}, wantErr: true},
+		{name: "Malformed blacklist pattern", blacklist: []string{"v1.[0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRefFilter(tt.whitelist, tt.blacklist)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRefFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRefFilter_AllowedRef(t *testing.T) {
+	filter, _ := NewRefFilter([]string{"v1.0.*", "v0.*", "master", "release/*"}, []string{"v0.*", "*-rc*"})
+	blacklistOnly, _ := NewRefFilter(nil, []string{"experimental/*"})
+
+	tests := []struct {
+		name   string
+		filter *RefFilter
+		ref    string
+		want   bool
+	}{
+		{name: "Whitelisted tag", filter: filter, ref: "refs/tags/v1.0.3", want: true},
+		{name: "Tag not whitelisted", filter: filter, ref: "refs/tags/v1.1.0", want: false},
+		{name: "Blacklist wins over whitelist", filter: filter, ref: "refs/tags/v0.9.0", want: false},
+		{name: "Blacklisted pre-release", filter: filter, ref: "refs/tags/v1.0.4-rc1", want: false},
+		{name: "Whitelisted branch", filter: filter, ref: "refs/heads/master", want: true},
+		{name: "Whitelisted remote branch", filter: filter, ref: "refs/remotes/origin/release/1.0", want: true},
+		{name: "Branch not whitelisted", filter: filter, ref: "refs/heads/feature", want: false},
+		{name: "Blacklist only allows other refs", filter: blacklistOnly, ref: "refs/heads/master", want: true},
+		{name: "Blacklist only", filter: blacklistOnly, ref: "refs/heads/experimental/foo", want: false},
+		{name: "Nil filter allows everything", filter: nil, ref: "refs/heads/experimental/foo", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := plumbing.NewHashReference(plumbing.ReferenceName(tt.ref), plumbing.ZeroHash)
+			if got := tt.filter.AllowedRef(ref); got != tt.want {
+				t.Errorf("RefFilter.AllowedRef() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_filtered(t *testing.T) {
+	fixture := testrepo.New(t)
+	first := fixture.Commit("First", map[string]string{"config.yml": "version: 0\n"})
+	second := fixture.Commit("Second", map[string]string{"config.yml": "version: 1\n"})
+	third := fixture.Commit("Third", map[string]string{"config.yml": "version: 2\n"})
+	fixture.Tag("v0.9.0", first)
+	fixture.Tag("v1.0.0", second)
+	fixture.Tag("v1.1.0", third)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.FindSemverTag() error = %v", err)
+	}
+	r.RefFilter, _ = NewRefFilter([]string{"v1.0.*", "v0.*"}, []string{"v0.*"})
+
+	t.Run("FindSemverTag", func(t *testing.T) {
+		tests := []struct {
+			constraint string
+			wantHash   plumbing.Hash
+			wantErr    bool
+		}{
+			{constraint: ">=0", wantHash: second},
+			{constraint: "~0.9", wantErr: true},
+			{constraint: "~1.1", wantErr: true},
+		}
+		for _, tt := range tests {
+			c, _ := semver.NewConstraint(tt.constraint)
+			got, err := r.FindSemverTag(c)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("Repository.FindSemverTag(%s) error = %v, wantErr %v", tt.constraint, err, tt.wantErr)
+				}
+				continue
+			}
+			if tt.wantErr || got.Hash() != tt.wantHash {
+				t.Errorf("Repository.FindSemverTag(%s) = %v, want %v", tt.constraint, got, tt.wantHash)
+			}
+		}
+	})
+
+	t.Run("FileOpenAtRev", func(t *testing.T) {
+		tests := []struct {
+			rev     string
+			wantErr bool
+		}{
+			{rev: "v1.0.0", wantErr: false},
+			{rev: "v1.0.0~1", wantErr: false},
+			{rev: "v1.1.0", wantErr: true},
+			{rev: "v0.9.0", wantErr: true},
+			{rev: "master", wantErr: true},
+			{rev: "HEAD", wantErr: true},
+			{rev: first.String(), wantErr: false},
+		}
+		for _, tt := range tests {
+			got, _, err := r.FileOpenAtRev("config.yml", plumbing.Revision(tt.rev))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.FileOpenAtRev(%s) error = %v, wantErr %v", tt.rev, err, tt.wantErr)
+			}
+			if got != nil {
+				got.Close()
+			}
+		}
+	})
+}
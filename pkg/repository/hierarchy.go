@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+)
+
+// OpenHierarchyAtVersion opens the file with the name of the file at filePath in
+// every directory from the root of the tree down to the directory of filePath,
+// at a version resolved by ResolveSemVer. Files are returned from the root
+// down and directories without the file are skipped. Returns ErrIsDir if
+// filePath is a directory, and an error if its directory doesn't exist or none
+// of the directories has the file. The caller must close every File.
+func (r *Repository) OpenHierarchyAtVersion(filePath string, v *Version) ([]*File, error) {
+	commit, err := r.commitAtHash(v.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("Tree of commit %v: %s", commit.TreeHash, err)
+	}
+
+	filePath = strings.Trim(path.Clean("/"+filePath), "/")
+	if filePath == "" {
+		return nil, ErrIsDir
+	}
+
+	dirPath, name := path.Split(filePath)
+	dirPath = strings.TrimSuffix(dirPath, "/")
+
+	var dirs []string
+	if dirPath != "" {
+		if _, err := tree.Tree(dirPath); err != nil {
+			return nil, fmt.Errorf("Directory in tree %s: %s", dirPath, err)
+		}
+		dirs = strings.Split(dirPath, "/")
+	}
+
+	var files []*File
+	for i := 0; i <= len(dirs); i++ {
+		levelPath := path.Join(append(dirs[:i:i], name)...)
+
+		entry, err := tree.FindEntry(levelPath)
+		if err != nil {
+			continue
+		}
+
+		if entry.Mode == filemode.Dir {
+			if i < len(dirs) {
+				continue
+			}
+			closeFiles(files)
+			return nil, ErrIsDir
+		}
+
+		object, err := r.BlobObject(entry.Hash)
+		if err != nil {
+			closeFiles(files)
+			return nil, fmt.Errorf("Blob object of %v: %s", entry.Hash, err)
+		}
+
+		reader, err := object.Reader()
+		if err != nil {
+			closeFiles(files)
+			return nil, err
+		}
+
+		files = append(files, &File{ReadCloser: reader, Size: object.Size, Hash: entry.Hash, Commit: commit, Version: v})
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("File %s not found in any directory of %s", name, filePath)
+	}
+
+	return files, nil
+}
+
+// closeFiles closes every file in files.
+func closeFiles(files []*File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/testrepo"
+)
+
+func TestRepository_OpenHierarchyAtVersion(t *testing.T) {
+	fixture := testrepo.New(t)
+	hash := fixture.Commit("First", map[string]string{
+		"config.yml":         "level: root\n",
+		"a/config.yml":       "level: a\n",
+		"a/b/c/config.yml":   "level: c\n",
+		"a/b/other.yml":      "other: true\n",
+		"a/b/d/config.yml/x": "dir: true\n",
+	})
+	fixture.Tag("v1.0.0", hash)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("CloneBare() error = %v", err)
+	}
+
+	v, err := r.ResolveSemVer("v1")
+	if err != nil {
+		t.Fatalf("Repository.ResolveSemVer() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filePath string
+		want     []string
+		wantErr  error
+	}{
+		{name: "Every level", filePath: "/a/b/c/config.yml", want: []string{"level: root\n", "level: a\n", "level: c\n"}},
+		{name: "Missing leaf", filePath: "/a/b/config.yml", want: []string{"level: root\n", "level: a\n"}},
+		{name: "Root", filePath: "/config.yml", want: []string{"level: root\n"}},
+		{name: "Only leaf", filePath: "/a/b/other.yml", want: []string{"other: true\n"}},
+		{name: "Missing directory", filePath: "/a/x/config.yml"},
+		{name: "Missing at every level", filePath: "/a/b/c/missing.yml"},
+		{name: "Directory", filePath: "/a/b/d/config.yml", wantErr: ErrIsDir},
+		{name: "Tree root", filePath: "/", wantErr: ErrIsDir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := r.OpenHierarchyAtVersion(tt.filePath, v)
+			if tt.want == nil {
+				if err == nil || (tt.wantErr != nil && err != tt.wantErr) {
+					t.Errorf("Repository.OpenHierarchyAtVersion() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Repository.OpenHierarchyAtVersion() error = %v", err)
+			}
+
+			var got []string
+			for _, f := range files {
+				contents, err := ioutil.ReadAll(f)
+				f.Close()
+				if err != nil {
+					t.Fatalf("ReadAll() error = %v", err)
+				}
+				if f.Commit.Hash != hash || f.Version != v {
+					t.Errorf("Repository.OpenHierarchyAtVersion() commit = %v, version = %v", f.Commit.Hash, f.Version)
+				}
+				got = append(got, string(contents))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repository.OpenHierarchyAtVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
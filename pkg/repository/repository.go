@@ -1,76 +1,320 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"path"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/cfg8er/cfg8er/pkg/repository/semverref"
 	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
 	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
 	"gopkg.in/src-d/go-git.v4/storage/memory"
 )
 
 // Repository is an extended go-git Repository
-type Repository struct{ *git.Repository }
+type Repository struct {
+	*git.Repository
+	// Verifier, if not nil, checks the signatures of tags and commits before
+	// they are used. Tags failing verification are ignored.
+	Verifier *Verifier
+	// RefFilter, if not nil, limits the tags and branches that can be used.
+	RefFilter *RefFilter
+	// RefTypes, if not nil, limits the kinds of versions FileOpenAtSemVer
+	// resolves.
+	RefTypes *RefTypes
+	// Auth, if not nil, is used to authenticate with the origin remote when
+	// fetching.
+	Auth transport.AuthMethod
+}
+
+// RefTypes are the kinds of versions that can be resolved.
+type RefTypes struct {
+	// SemverTags allows semantic version constraints matched against tags, eg. ~1.0.
+	SemverTags bool
+	// Tags allows exact tag names, eg. v1.0.0.
+	Tags bool
+	// Commits allows branches, commit hashes and revisions relative to them,
+	// eg. master, HEAD~3 or 6ecf0ef2c2dffb796033e5a02219af86ec6584e5.
+	Commits bool
+}
+
+// ErrRefTypeDisabled is returned when resolving a kind of version that isn't
+// allowed by the Repository's RefTypes.
+var ErrRefTypeDisabled = errors.New("Version type is disabled")
+
+// ErrIsDir is returned when opening a path that is a directory as a file.
+var ErrIsDir = errors.New("Path is a directory")
+
+// ErrNotExist is returned when opening a path that doesn't exist at a version.
+var ErrNotExist = errors.New("Path does not exist")
+
+// ErrNotDir is returned when listing a path that isn't a directory.
+var ErrNotDir = errors.New("Path is not a directory")
 
-// CloneBare downloads the repository as a bare repo including all tags
+// CloneBare downloads the repository as a bare repo including all tags. The Git
+// objects are stored in memory.
 func CloneBare(URL string) (Repository, error) {
+	return CloneBareContext(context.Background(), URL, nil)
+}
+
+// CloneBareContext is like CloneBare but the clone is aborted if ctx expires
+// before it completes. If auth is not nil it's used to authenticate with the
+// remote and kept as the returned Repository's Auth for later fetches.
+func CloneBareContext(ctx context.Context, URL string, auth transport.AuthMethod) (Repository, error) {
 	// Git objects storer based on memory
 	storer := memory.NewStorage()
 
-	repo, err := git.Clone(storer, nil, &git.CloneOptions{
+	repo, err := git.CloneContext(ctx, storer, nil, &git.CloneOptions{
 		URL:  URL,
+		Auth: auth,
 		Tags: git.TagMode(2),
 	})
 	if err != nil {
 		return Repository{}, err
 	}
-	return Repository{repo}, nil
+	return Repository{Repository: repo, Auth: auth}, nil
+}
+
+// PlainCloneBare downloads the repository as a bare repo including all tags
+// into dir on the filesystem. If the clone fails dir is removed, unless it
+// already existed, so a later PlainOpenBare doesn't find a half cloned repo.
+func PlainCloneBare(URL string, dir string) (Repository, error) {
+	return PlainCloneBareContext(context.Background(), URL, dir, nil)
+}
+
+// PlainCloneBareContext is like PlainCloneBare but the clone is aborted if ctx
+// expires before it completes. If auth is not nil it's used to authenticate
+// with the remote and kept as the returned Repository's Auth for later fetches.
+func PlainCloneBareContext(ctx context.Context, URL string, dir string, auth transport.AuthMethod) (Repository, error) {
+	_, statErr := os.Stat(dir)
+
+	repo, err := git.PlainCloneContext(ctx, dir, true, &git.CloneOptions{
+		URL:  URL,
+		Auth: auth,
+		Tags: git.TagMode(2),
+	})
+	if err != nil {
+		if os.IsNotExist(statErr) {
+			os.RemoveAll(dir)
+		}
+		return Repository{}, err
+	}
+
+	repo, err = openDiskStorage(repo)
+	if err != nil {
+		return Repository{}, err
+	}
+	return Repository{Repository: repo, Auth: auth}, nil
+}
+
+// PlainOpenBare opens a bare repo previously cloned into dir by PlainCloneBare.
+// Returns git.ErrRepositoryNotExists if dir doesn't contain a repo.
+func PlainOpenBare(dir string) (Repository, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return Repository{}, err
+	}
+
+	repo, err = openDiskStorage(repo)
+	if err != nil {
+		return Repository{}, err
+	}
+	return Repository{Repository: repo}, nil
+}
+
+// openDiskStorage reopens a repo on disk with a diskStorage, so it can be
+// fetched into by Fetch.
+func openDiskStorage(repo *git.Repository) (*git.Repository, error) {
+	disk, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return nil, fmt.Errorf("Storage %T isn't on disk", repo.Storer)
+	}
+
+	s, err := newDiskStorage(disk, disk)
+	if err != nil {
+		return nil, err
+	}
+	return git.Open(s, nil)
+}
+
+// FetchAll downloads the latest objects, branches and tags from the origin
+// remote. Branches are updated under refs/heads as well as refs/remotes/origin
+// because a bare clone only creates refs/heads for the default branch and it
+// would otherwise never move. Returns git.NoErrAlreadyUpToDate if there was
+// nothing new to fetch. The fetch is aborted if ctx expires before it completes.
+// The Repository's Auth, if any, is used to authenticate with the remote.
+func (r *Repository) FetchAll(ctx context.Context) error {
+	// Check if Repository is nil to avoid a panic if this function is called
+	// before repo has been cloned
+	if r.Repository == nil {
+		return errors.New("Repository is nil")
+	}
+
+	return r.Repository.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{
+			"+refs/heads/*:refs/remotes/origin/*",
+			"+refs/heads/*:refs/heads/*",
+		},
+		Auth: r.Auth,
+		Tags: git.TagMode(2),
+	})
+}
+
+// Fetch is like FetchAll but fetches into a copy of the Repository, leaving it
+// untouched so it can keep being read while the fetch is in progress. Returns
+// the copy, with the same settings, to be used in place of the Repository.
+// Objects are shared with the copy rather than copied. The references of a
+// repo stored on disk are saved once the fetch completes.
+func (r *Repository) Fetch(ctx context.Context) (Repository, error) {
+	// Check if Repository is nil to avoid a panic if this function is called
+	// before repo has been cloned
+	if r.Repository == nil {
+		return Repository{}, errors.New("Repository is nil")
+	}
+
+	s, err := copyStorage(r.Storer)
+	if err != nil {
+		return Repository{}, err
+	}
+
+	repo, err := git.Open(s, nil)
+	if err != nil {
+		return Repository{}, err
+	}
+
+	fetched := *r
+	fetched.Repository = repo
+	if err := fetched.FetchAll(ctx); err != nil {
+		return Repository{}, err
+	}
+
+	if disk, ok := s.(*diskStorage); ok {
+		if err := disk.save(); err != nil {
+			return Repository{}, fmt.Errorf("Save references: %v", err)
+		}
+	}
+
+	return fetched, nil
 }
 
 // FileOpenAtRev opens a file at a given path at a given Git revision, eg.
 // https://kernel.org/pub/software/scm/git/docs/gitrevisions.html. Returns an
 // open io.ReadCloser, file size, and error.
 func (r *Repository) FileOpenAtRev(filePath string, rev plumbing.Revision) (io.ReadCloser, int64, error) {
+	v, err := r.resolveRev(rev)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r.fileOpenAtHash(filePath, v.Hash)
+}
+
+// resolveRev resolves a Git revision to a commit. Tags and branches must pass
+// the same filtering and verification as the tags considered by FindSemverTag.
+// Revisions starting with anything but a tag or a commit hash are floating.
+func (r *Repository) resolveRev(rev plumbing.Revision) (*Version, error) {
 	// Check if Repository is nil to avoid a panic if this function is called
 	// before repo has been cloned
 	if r.Repository == nil {
-		return nil, 0, errors.New("Repository is nil")
+		return nil, errors.New("Repository is nil")
+	}
+
+	namedRef := r.revisionRef(rev)
+	if namedRef != nil {
+		if !r.RefFilter.AllowedRef(namedRef) {
+			return nil, fmt.Errorf("Revision %s: %s is filtered", rev, namedRef.Name())
+		}
+		if namedRef.Name().IsTag() {
+			if err := r.verifyTagRef(namedRef); err != nil {
+				return nil, fmt.Errorf("Verify tag %s: %s", namedRef.Name(), err)
+			}
+
+			// ResolveRevision can't resolve annotated tags so swap the tag
+			// name for the hash of the commit it points at
+			commit, err := r.peelToCommit(namedRef.Hash())
+			if err != nil {
+				return nil, fmt.Errorf("Peel tag %s: %s", namedRef.Name(), err)
+			}
+			_, suffix := splitRevision(rev)
+			rev = plumbing.Revision(commit.Hash.String() + suffix)
+		}
 	}
 
-	ref, err := r.ResolveRevision(rev)
+	hash, err := r.ResolveRevision(rev)
 	if err != nil {
-		return nil, 0, fmt.Errorf("Revision resolve of %s: %s", ref, err)
+		return nil, fmt.Errorf("Revision resolve of %s: %s", rev, err)
 	}
-	return r.fileOpenAtHash(filePath, *ref)
+
+	commit, err := r.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("Commit object of %v: %s", hash, err)
+	}
+
+	return &Version{
+		Ref:      namedRef,
+		Hash:     commit.Hash,
+		Date:     commit.Committer.When,
+		Floating: namedRef != nil && !namedRef.Name().IsTag(),
+	}, nil
 }
 
-// FileOpenAtRef opens a file at a given path at given reference. Returns an open io.ReadCloser,
+// FileOpenAtRef opens a file at a given path at given reference. References to
+// annotated tags are peeled to the tagged commit. Returns an open io.ReadCloser,
 // file size, and error.
 func (r *Repository) FileOpenAtRef(filePath string, ref plumbing.Reference) (io.ReadCloser, int64, error) {
 	return r.fileOpenAtHash(filePath, ref.Hash())
 }
 
-// fileOpenAtHash opens a file at a given path at a given hash. Returns an open io.ReadCloser,
-// file size, and error.
+// File is a file opened at a version.
+type File struct {
+	io.ReadCloser
+	// Size is the size of the file in bytes.
+	Size int64
+	// Hash is the hash of the file's blob.
+	Hash plumbing.Hash
+	// Commit is the commit the file was opened at.
+	Commit *object.Commit
+	// Version is the version the file was opened at. Nil if the file wasn't
+	// opened by resolving a version.
+	Version *Version
+}
+
+// fileOpenAtHash opens a file at a given path at a given commit or annotated tag hash.
+// Returns an open io.ReadCloser, file size, and error. Returns ErrIsDir if the path
+// is a directory.
 func (r *Repository) fileOpenAtHash(filePath string, hash plumbing.Hash) (io.ReadCloser, int64, error) {
-	// Check if Repository is nil to avoid a panic if this function is called
-	// before repo has been cloned
-	if r.Repository == nil {
-		return nil, 0, errors.New("Repository is nil")
+	f, err := r.openAtHash(filePath, hash)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	commit, err := r.CommitObject(hash)
+	return f.ReadCloser, f.Size, nil
+}
+
+// openAtHash is like fileOpenAtHash but returns the File with its blob hash and
+// commit.
+func (r *Repository) openAtHash(filePath string, hash plumbing.Hash) (*File, error) {
+	commit, err := r.commitAtHash(hash)
 	if err != nil {
-		return nil, 0, fmt.Errorf("Commit object of %v: %s", hash, err)
+		return nil, err
 	}
 
 	tree, err := commit.Tree()
 	if err != nil {
-		return nil, 0, fmt.Errorf("Tree of commit %v: %s", commit.TreeHash, err)
+		return nil, fmt.Errorf("Tree of commit %v: %s", commit.TreeHash, err)
 	}
 
 	// If filePath has a leading slash remove it as tree entries don't have a leading slash.
@@ -78,26 +322,75 @@ func (r *Repository) fileOpenAtHash(filePath string, hash plumbing.Hash) (io.Rea
 		filePath = filePath[1:]
 	}
 
+	if filePath == "" {
+		return nil, ErrIsDir
+	}
+
 	entry, err := tree.FindEntry(filePath)
-	if err != nil {
-		return nil, 0, fmt.Errorf("Path in tree %s: %s", filePath, err)
+	if err == object.ErrEntryNotFound || err == object.ErrDirectoryNotFound {
+		return nil, ErrNotExist
+	} else if err != nil {
+		return nil, fmt.Errorf("Path in tree %s: %s", filePath, err)
+	}
+
+	if entry.Mode == filemode.Dir {
+		return nil, ErrIsDir
 	}
 
 	object, err := r.BlobObject(entry.Hash)
 	if err != nil {
-		return nil, 0, fmt.Errorf("Blob object of %v: %s", entry.Hash, err)
+		return nil, fmt.Errorf("Blob object of %v: %s", entry.Hash, err)
 	}
 
 	reader, err := object.Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{ReadCloser: reader, Size: object.Size, Hash: entry.Hash, Commit: commit}, nil
+}
+
+// commitAtHash returns the commit of a given commit or annotated tag hash. The
+// commit is verified if the Repository's Verifier checks commits.
+func (r *Repository) commitAtHash(hash plumbing.Hash) (*object.Commit, error) {
+	// Check if Repository is nil to avoid a panic if this function is called
+	// before repo has been cloned
+	if r.Repository == nil {
+		return nil, errors.New("Repository is nil")
+	}
 
-	return reader, object.Size, err
+	commit, err := r.peelToCommit(hash)
+	if err != nil {
+		return nil, fmt.Errorf("Commit object of %v: %s", hash, err)
+	}
+
+	if r.Verifier != nil && r.Verifier.Commits {
+		if err := r.verifyCommit(commit.Hash); err != nil {
+			return nil, fmt.Errorf("Verify commit %v: %s", hash, err)
+		}
+	}
+
+	return commit, nil
 }
 
 // FindSemverTag iterates through the repository's tags looking for tags that
 // follow semantic versioning (https://semver.org). Returns the highest version
 // tag that meets the supplied contraint. Silently ignores tags that aren't
-// parsable as a semantic version.
+// parsable as a semantic version, tags filtered by the Repository's RefFilter
+// and tags that fail verification when the Repository has a Verifier.
 func (r *Repository) FindSemverTag(c *semver.Constraints) (*plumbing.Reference, error) {
+	coll, err := r.SemverTags()
+	if err != nil {
+		return nil, err
+	}
+
+	return coll.HighestMatch(c)
+}
+
+// SemverTags returns the repository's tags that follow semantic versioning
+// sorted from the lowest to the highest version. Tags are ignored as by
+// FindSemverTag.
+func (r *Repository) SemverTags() (semverref.Collection, error) {
 	// Check if Repository is nil to avoid a panic if this function is called
 	// before repo has been cloned
 	if r.Repository == nil {
@@ -117,6 +410,14 @@ func (r *Repository) FindSemverTag(c *semver.Constraints) (*plumbing.Reference,
 			return nil // Ignore errors and thus tags that aren't parsable as a semver
 		}
 
+		if !r.RefFilter.AllowedRef(t) {
+			return nil // Ignore tags that are filtered
+		}
+
+		if err := r.verifyTagRef(t); err != nil {
+			return nil // Ignore unsigned and untrusted tags
+		}
+
 		// No way to a priori find the length of tagsIter so append to the collection.
 		coll = append(coll, semverref.SemverRef{Ver: v, Ref: t})
 		return nil
@@ -124,26 +425,242 @@ func (r *Repository) FindSemverTag(c *semver.Constraints) (*plumbing.Reference,
 		return nil, err
 	}
 
-	return coll.HighestMatch(c)
+	sort.Sort(coll)
+	return coll, nil
 }
 
 // FileOpenAtSemVer opens a file at a given path at a given sementic version matching tag or git revision.
-// If no match is found for the version or path returns nil values.
-// TODO: write test
+// The kinds of versions resolved are limited by the Repository's RefTypes. A version that
+// parses as a semantic version constraint but matches no tag is tried as a revision.
+// Returns ErrIsDir if the path is a directory.
 func (r *Repository) FileOpenAtSemVer(filePath string, version string) (io.ReadCloser, int64, error) {
+	f, err := r.OpenAtSemVer(filePath, version)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	var reader io.ReadCloser
-	var size int64
-	var fOpenErr error
+	return f.ReadCloser, f.Size, nil
+}
+
+// OpenAtSemVer is like FileOpenAtSemVer but returns the File with its blob hash,
+// the commit it was opened at and the version that resolved to.
+func (r *Repository) OpenAtSemVer(filePath string, version string) (*File, error) {
+	v, err := r.ResolveSemVer(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.OpenAtVersion(filePath, v)
+}
+
+// OpenAtVersion opens a file at a given path at a version resolved by
+// ResolveSemVer. Returns ErrIsDir if the path is a directory and ErrNotExist
+// if there's nothing at the path.
+func (r *Repository) OpenAtVersion(filePath string, v *Version) (*File, error) {
+	f, err := r.openAtHash(filePath, v.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Version = v
+	return f, nil
+}
 
-	constraint, err := semver.NewConstraint(version)
-	if err != nil || constraint == nil {
-		reader, size, fOpenErr = r.FileOpenAtRev(filePath, plumbing.Revision(version))
-	} else {
-		if ref, fOpenErr := r.FindSemverTag(constraint); fOpenErr == nil {
-			reader, size, fOpenErr = r.FileOpenAtRef(filePath, *ref)
+// ReadAtVersion reads the contents of the file at filePath at a version
+// resolved by ResolveSemVer. Returns ErrIsDir if the path is a directory and
+// ErrNotExist if there's nothing at the path.
+func (r *Repository) ReadAtVersion(filePath string, v *Version) ([]byte, error) {
+	f, err := r.OpenAtVersion(filePath, v)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// Version is a version resolved to a commit.
+type Version struct {
+	// Ref is the tag or branch the version was resolved through. Nil if the
+	// version is a commit hash or a revision relative to one.
+	Ref *plumbing.Reference
+	// Hash is the hash of the resolved commit.
+	Hash plumbing.Hash
+	// Date is the committer date of the resolved commit.
+	Date time.Time
+	// Floating is true if the version can resolve to another commit as the
+	// repository is updated, eg. semantic version constraints and branches.
+	Floating bool
+}
+
+// ResolveSemVer resolves a sementic version constraint or git revision to a
+// commit. The kinds of versions resolved are limited by the Repository's
+// RefTypes. A version that parses as a semantic version constraint but matches
+// no tag is tried as a revision. A constraint that is exactly the name of the
+// tag it resolves to isn't floating.
+func (r *Repository) ResolveSemVer(version string) (*Version, error) {
+	// Check if Repository is nil to avoid a panic if this function is called
+	// before repo has been cloned
+	if r.Repository == nil {
+		return nil, errors.New("Repository is nil")
+	}
+
+	types := r.refTypes()
+
+	if types.SemverTags {
+		if constraint, err := semver.NewConstraint(version); err == nil {
+			ref, err := r.FindSemverTag(constraint)
+			if err == nil {
+				commit, err := r.peelToCommit(ref.Hash())
+				if err != nil {
+					return nil, fmt.Errorf("Peel tag %s: %s", ref.Name(), err)
+				}
+				return &Version{
+					Ref:      ref,
+					Hash:     commit.Hash,
+					Date:     commit.Committer.When,
+					Floating: version != ref.Name().Short(),
+				}, nil
+			}
+			if !types.Tags && !types.Commits {
+				return nil, err
+			}
+		}
+	}
+
+	rev := plumbing.Revision(version)
+
+	if !types.allowsRevision(r.revisionRef(rev), rev) {
+		return nil, fmt.Errorf("Version %s: %s", version, ErrRefTypeDisabled)
+	}
+
+	return r.resolveRev(rev)
+}
+
+// refTypes returns the RefTypes of the Repository, allowing every kind of
+// version if none are set.
+func (r *Repository) refTypes() *RefTypes {
+	if r.RefTypes == nil {
+		return &RefTypes{SemverTags: true, Tags: true, Commits: true}
+	}
+	return r.RefTypes
+}
+
+// allowsRevision reports whether rev, starting with namedRef, can be resolved.
+// A revision that is exactly a tag name needs Tags, anything else, including
+// revisions relative to a tag such as v1.0.0~1, needs Commits.
+func (t *RefTypes) allowsRevision(namedRef *plumbing.Reference, rev plumbing.Revision) bool {
+	if namedRef != nil && namedRef.Name().IsTag() && !strings.ContainsAny(string(rev), "~^@:") {
+		return t.Tags
+	}
+	return t.Commits
+}
+
+// revisionRef returns the tag or branch named at the start of rev, eg. the
+// tag v1.0.0 for v1.0.0~2, looked up with the same rules as ResolveRevision.
+// Symbolic references such as HEAD are resolved to the branch they point at.
+// Returns nil if rev starts with a commit hash rather than a reference name.
+func (r *Repository) revisionRef(rev plumbing.Revision) *plumbing.Reference {
+	name, _ := splitRevision(rev)
+	if name == "" {
+		name = "HEAD"
+	}
+
+	for _, rule := range append([]string{"%s"}, plumbing.RefRevParseRules...) {
+		ref, err := r.Reference(plumbing.ReferenceName(fmt.Sprintf(rule, name)), true)
+		if err == nil {
+			return ref
+		}
+	}
+	return nil
+}
+
+// splitRevision splits rev into the reference name or hash it starts with and
+// the suffix selecting a commit relative to it, eg. v1.0.0 and ~2 for v1.0.0~2.
+func splitRevision(rev plumbing.Revision) (string, string) {
+	s := string(rev)
+	if i := strings.IndexAny(s, "~^@:"); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// peelToCommit returns the commit with hash, or the commit an annotated tag with
+// hash points at. Tags of tags are followed until a commit is reached.
+func (r *Repository) peelToCommit(hash plumbing.Hash) (*object.Commit, error) {
+	for {
+		obj, err := r.Object(plumbing.AnyObject, hash)
+		if err != nil {
+			return nil, err
 		}
+
+		switch o := obj.(type) {
+		case *object.Commit:
+			return o, nil
+		case *object.Tag:
+			hash = o.Target
+		default:
+			return nil, fmt.Errorf("%s object %v is not a commit", obj.Type(), hash)
+		}
+	}
+}
+
+// verifyTagRef checks the signature of the annotated tag ref points at, and of
+// the tagged commit, as required by the Repository's Verifier. Lightweight tags
+// fail tag verification as they can't be signed. Every tag of a tag of a tag is
+// checked, down to the tagged commit.
+func (r *Repository) verifyTagRef(ref *plumbing.Reference) error {
+	if r.Verifier == nil {
+		return nil
+	}
+
+	if r.Verifier.Tags {
+		if err := r.verifyTags(ref.Hash()); err != nil {
+			return err
+		}
+	}
+
+	if r.Verifier.Commits {
+		commit, err := r.peelToCommit(ref.Hash())
+		if err != nil {
+			return err
+		}
+		return r.verifyCommit(commit.Hash)
 	}
+	return nil
+}
+
+// verifyTags checks the signature of the annotated tag with hash and of every
+// tag it's a tag of. Returns ErrUnsigned if hash isn't an annotated tag.
+func (r *Repository) verifyTags(hash plumbing.Hash) error {
+	for {
+		obj, err := r.Storer.EncodedObject(plumbing.TagObject, hash)
+		if err == plumbing.ErrObjectNotFound {
+			return ErrUnsigned
+		} else if err != nil {
+			return err
+		}
 
-	return reader, size, fOpenErr
+		if err := r.Verifier.VerifyObject(obj); err != nil {
+			return err
+		}
+
+		tag, err := object.DecodeTag(r.Storer, obj)
+		if err != nil {
+			return err
+		}
+		if tag.TargetType != plumbing.TagObject {
+			return nil
+		}
+		hash = tag.Target
+	}
+}
+
+// verifyCommit checks the signature of the commit with hash.
+func (r *Repository) verifyCommit(hash plumbing.Hash) error {
+	commit, err := r.Storer.EncodedObject(plumbing.CommitObject, hash)
+	if err != nil {
+		return err
+	}
+	return r.Verifier.VerifyObject(commit)
 }
@@ -2,10 +2,16 @@ package repository
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/Masterminds/semver"
+	"github.com/cfg8er/cfg8er/internal/testrepo"
+	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 )
 
@@ -310,3 +316,436 @@ func TestRepository_FindSemverTag(t *testing.T) {
 		})
 	}
 }
+
+func TestPlainCloneBare(t *testing.T) {
+	fixture := testrepo.New(t)
+	fixture.Commit("Initial commit", map[string]string{"config.yml": "key: value\n"})
+
+	storageDir, err := ioutil.TempDir("", "cfg8er-storage")
+	if err != nil {
+		t.Fatalf("PlainCloneBare() error = %v", err)
+	}
+	defer os.RemoveAll(storageDir)
+
+	type args struct {
+		URL string
+		dir string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+		wantDir bool
+	}{
+		{
+			name:    "Clone a repo",
+			args:    args{URL: fixture.Dir, dir: filepath.Join(storageDir, "fixture")},
+			wantErr: false,
+			wantDir: true,
+		},
+		{
+			name:    "Clone into an existing repo",
+			args:    args{URL: fixture.Dir, dir: filepath.Join(storageDir, "fixture")},
+			wantErr: true,
+			wantDir: true,
+		},
+		{
+			name:    "Clone a non-existent repo",
+			args:    args{URL: filepath.Join(storageDir, "non-existent"), dir: filepath.Join(storageDir, "non-existent-clone")},
+			wantErr: true,
+			wantDir: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PlainCloneBare(tt.args.URL, tt.args.dir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PlainCloneBare() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if _, err := os.Stat(tt.args.dir); (err == nil) != tt.wantDir {
+				t.Errorf("PlainCloneBare() dir exists = %v, wantDir %v", err == nil, tt.wantDir)
+			}
+		})
+	}
+}
+
+func TestPlainOpenBare(t *testing.T) {
+	fixture := testrepo.New(t)
+	fixture.Commit("Initial commit", map[string]string{"config.yml": "key: value\n"})
+
+	storageDir, err := ioutil.TempDir("", "cfg8er-storage")
+	if err != nil {
+		t.Fatalf("PlainOpenBare() error = %v", err)
+	}
+	defer os.RemoveAll(storageDir)
+
+	if _, err := PlainCloneBare(fixture.Dir, filepath.Join(storageDir, "fixture")); err != nil {
+		t.Fatalf("PlainOpenBare() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		want    []byte
+		wantErr error
+	}{
+		{
+			name: "Open a stored repo and read config.yml",
+			dir:  filepath.Join(storageDir, "fixture"),
+			want: []byte("key: value\n"),
+		},
+		{
+			name:    "Open a non-existent repo",
+			dir:     filepath.Join(storageDir, "non-existent"),
+			wantErr: git.ErrRepositoryNotExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := PlainOpenBare(tt.dir)
+			if err != tt.wantErr {
+				t.Errorf("PlainOpenBare() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			got, _, err := r.FileOpenAtRev("config.yml", plumbing.Revision("master"))
+			if err != nil {
+				t.Errorf("PlainOpenBare() error = %v", err)
+				return
+			}
+			defer got.Close()
+
+			gotContents, _ := ioutil.ReadAll(got)
+			if !bytes.Equal(gotContents, tt.want) {
+				t.Errorf("PlainOpenBare() = %v, want %v", gotContents, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_FetchAll(t *testing.T) {
+	fixture := testrepo.New(t)
+	fixture.Commit("Initial commit", map[string]string{"config.yml": "key: value\n"})
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.FetchAll() error = %v", err)
+	}
+
+	if err := r.FetchAll(context.Background()); err != git.NoErrAlreadyUpToDate {
+		t.Errorf("Repository.FetchAll() error = %v, want %v", err, git.NoErrAlreadyUpToDate)
+	}
+
+	hash := fixture.Commit("Update config", map[string]string{"config.yml": "key: updated\n"})
+	fixture.Tag("v1.0.0", hash)
+
+	if err := r.FetchAll(context.Background()); err != nil {
+		t.Fatalf("Repository.FetchAll() error = %v", err)
+	}
+
+	for _, rev := range []string{"master", "refs/remotes/origin/master", "v1.0.0"} {
+		got, err := r.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			t.Errorf("Repository.FetchAll() %s error = %v", rev, err)
+			continue
+		}
+		if *got != hash {
+			t.Errorf("Repository.FetchAll() %s = %v, want %v", rev, got, hash)
+		}
+	}
+
+	if err := (&Repository{}).FetchAll(context.Background()); err == nil {
+		t.Errorf("Repository.FetchAll() of an empty repository struct error = nil, wantErr true")
+	}
+}
+
+func TestRepository_Fetch(t *testing.T) {
+	fixture := testrepo.New(t)
+	initial := fixture.Commit("Initial commit", map[string]string{"config.yml": "key: value\n"})
+
+	storageDir, err := ioutil.TempDir("", "cfg8er-storage")
+	if err != nil {
+		t.Fatalf("Repository.Fetch() error = %v", err)
+	}
+	defer os.RemoveAll(storageDir)
+
+	inMemory, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.Fetch() error = %v", err)
+	}
+	onDisk, err := PlainCloneBare(fixture.Dir, filepath.Join(storageDir, "fixture"))
+	if err != nil {
+		t.Fatalf("Repository.Fetch() error = %v", err)
+	}
+
+	hash := fixture.Commit("Update config", map[string]string{"config.yml": "key: updated\n"})
+
+	tests := []struct {
+		name string
+		repo Repository
+	}{
+		{name: "Repo in memory", repo: inMemory},
+		{name: "Repo on disk", repo: onDisk},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched, err := tt.repo.Fetch(context.Background())
+			if err != nil {
+				t.Fatalf("Repository.Fetch() error = %v", err)
+			}
+
+			if got, _ := fetched.ResolveRevision("master"); got == nil || *got != hash {
+				t.Errorf("Repository.Fetch() master = %v, want %v", got, hash)
+			}
+			if got, _ := tt.repo.ResolveRevision("master"); got == nil || *got != initial {
+				t.Errorf("Repository.Fetch() master of the fetched repo = %v, want %v", got, initial)
+			}
+
+			if _, err := fetched.Fetch(context.Background()); err != git.NoErrAlreadyUpToDate {
+				t.Errorf("Repository.Fetch() error = %v, want %v", err, git.NoErrAlreadyUpToDate)
+			}
+		})
+	}
+
+	reopened, err := PlainOpenBare(filepath.Join(storageDir, "fixture"))
+	if err != nil {
+		t.Fatalf("Repository.Fetch() error = %v", err)
+	}
+	if got, _ := reopened.ResolveRevision("master"); got == nil || *got != hash {
+		t.Errorf("Repository.Fetch() master of the reopened repo = %v, want %v", got, hash)
+	}
+
+	if _, err := (&Repository{}).Fetch(context.Background()); err == nil {
+		t.Errorf("Repository.Fetch() of an empty repository struct error = nil, wantErr true")
+	}
+}
+
+func TestRepository_FileOpenAtSemVer(t *testing.T) {
+	fixture := testrepo.New(t)
+	first := fixture.Commit("First", map[string]string{"config.yml": "version: 1.0.0\n"})
+	second := fixture.Commit("Second", map[string]string{"config.yml": "version: 1.1.0\n"})
+	fixture.Commit("Third", map[string]string{"config.yml": "version: unreleased\n"})
+	fixture.Tag("v1.0.0", first)
+	fixture.Tag("v1.1.0", second)
+	fixture.Tag("stable", first)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.FileOpenAtSemVer() error = %v", err)
+	}
+
+	all := &RefTypes{SemverTags: true, Tags: true, Commits: true}
+	semverOnly := &RefTypes{SemverTags: true}
+	tagsOnly := &RefTypes{Tags: true}
+	commitsOnly := &RefTypes{Commits: true}
+
+	tests := []struct {
+		name     string
+		refTypes *RefTypes
+		version  string
+		want     []byte
+		wantErr  bool
+	}{
+		{name: "Semver constraint", refTypes: all, version: "v1", want: []byte("version: 1.1.0\n")},
+		{name: "Exact semver tag", refTypes: all, version: "v1.0.0", want: []byte("version: 1.0.0\n")},
+		{name: "Non-semver tag", refTypes: all, version: "stable", want: []byte("version: 1.0.0\n")},
+		{name: "Branch", refTypes: all, version: "master", want: []byte("version: unreleased\n")},
+		{name: "Commit hash", refTypes: all, version: second.String(), want: []byte("version: 1.1.0\n")},
+		{name: "Nil RefTypes allows everything", refTypes: nil, version: "master", want: []byte("version: unreleased\n")},
+		{name: "Semver constraint with semver tags only", refTypes: semverOnly, version: "~1.0", want: []byte("version: 1.0.0\n")},
+		{name: "Unmatched constraint with semver tags only", refTypes: semverOnly, version: "v2", wantErr: true},
+		{name: "Non-semver tag with semver tags only", refTypes: semverOnly, version: "stable", wantErr: true},
+		{name: "Branch with semver tags only", refTypes: semverOnly, version: "master", wantErr: true},
+		{name: "Semver constraint with tags only", refTypes: tagsOnly, version: "v1", wantErr: true},
+		{name: "Exact tag with tags only", refTypes: tagsOnly, version: "v1.1.0", want: []byte("version: 1.1.0\n")},
+		{name: "Relative to a tag with tags only", refTypes: tagsOnly, version: "v1.1.0~1", wantErr: true},
+		{name: "Branch with tags only", refTypes: tagsOnly, version: "master", wantErr: true},
+		{name: "Commit hash with tags only", refTypes: tagsOnly, version: second.String(), wantErr: true},
+		{name: "Tag with commits only", refTypes: commitsOnly, version: "stable", wantErr: true},
+		{name: "Relative to a branch with commits only", refTypes: commitsOnly, version: "HEAD~2", want: []byte("version: 1.0.0\n")},
+		{name: "Commit hash with commits only", refTypes: commitsOnly, version: second.String(), want: []byte("version: 1.1.0\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := r
+			repo.RefTypes = tt.refTypes
+
+			got, _, err := repo.FileOpenAtSemVer("config.yml", tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.FileOpenAtSemVer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			defer got.Close()
+
+			gotContents, _ := ioutil.ReadAll(got)
+			if !bytes.Equal(gotContents, tt.want) {
+				t.Errorf("Repository.FileOpenAtSemVer() = %q, want %q", gotContents, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_OpenAtSemVer(t *testing.T) {
+	fixture := testrepo.New(t)
+	first := fixture.Commit("First", map[string]string{"config.yml": "version: 1.0.0\n"})
+	fixture.AnnotatedTag("v1.0.0", first, plumbing.CommitObject, "Release 1.0.0\n")
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.OpenAtSemVer() error = %v", err)
+	}
+
+	got, err := r.OpenAtSemVer("/config.yml", "v1")
+	if err != nil {
+		t.Fatalf("Repository.OpenAtSemVer() error = %v", err)
+	}
+	defer got.Close()
+
+	wantHash := plumbing.ComputeHash(plumbing.BlobObject, []byte("version: 1.0.0\n"))
+	if got.Hash != wantHash || got.Size != 15 {
+		t.Errorf("Repository.OpenAtSemVer() hash = %v, size = %v, want %v, 15", got.Hash, got.Size, wantHash)
+	}
+	if got.Commit.Hash != first {
+		t.Errorf("Repository.OpenAtSemVer() commit = %v, want %v", got.Commit.Hash, first)
+	}
+	if !got.Commit.Committer.When.Equal(testrepo.Signature.When) {
+		t.Errorf("Repository.OpenAtSemVer() commit date = %v, want %v", got.Commit.Committer.When, testrepo.Signature.When)
+	}
+	if got.Version == nil || got.Version.Ref.Name() != "refs/tags/v1.0.0" || got.Version.Hash != first || !got.Version.Date.Equal(testrepo.Signature.When) {
+		t.Errorf("Repository.OpenAtSemVer() version = %+v, want refs/tags/v1.0.0 at %v", got.Version, first)
+	}
+}
+
+func TestRepository_ReadAtVersion(t *testing.T) {
+	fixture := testrepo.New(t)
+	fixture.Commit("First", map[string]string{"config.yml": "version: 1.0.0\n", "roles/web.yml": "role: web\n"})
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.ReadAtVersion() error = %v", err)
+	}
+
+	v, err := r.ResolveSemVer("master")
+	if err != nil {
+		t.Fatalf("Repository.ReadAtVersion() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filePath string
+		want     string
+		wantErr  error
+	}{
+		{name: "File", filePath: "/roles/web.yml", want: "role: web\n"},
+		{name: "Directory", filePath: "/roles", wantErr: ErrIsDir},
+		{name: "Missing file", filePath: "/roles/db.yml", wantErr: ErrNotExist},
+		{name: "Missing directory", filePath: "/hosts/web.yml", wantErr: ErrNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.ReadAtVersion(tt.filePath, v)
+			if err != tt.wantErr {
+				t.Fatalf("Repository.ReadAtVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Repository.ReadAtVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_ResolveSemVer(t *testing.T) {
+	fixture := testrepo.New(t)
+	first := fixture.Commit("First", map[string]string{"config.yml": "version: 1.0.0\n"})
+	second := fixture.Commit("Second", map[string]string{"config.yml": "version: 1.1.0\n"})
+	third := fixture.Commit("Third", map[string]string{"config.yml": "version: unreleased\n"})
+	fixture.Tag("v1.0.0", first)
+	fixture.AnnotatedTag("v1.1.0", second, plumbing.CommitObject, "Release 1.1.0\n")
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.ResolveSemVer() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		version      string
+		wantRef      plumbing.ReferenceName
+		wantHash     plumbing.Hash
+		wantFloating bool
+		wantErr      bool
+	}{
+		{name: "Semver constraint", version: "v1", wantRef: "refs/tags/v1.1.0", wantHash: second, wantFloating: true},
+		{name: "Semver constraint without prefix", version: "1.0.0", wantRef: "refs/tags/v1.0.0", wantHash: first, wantFloating: true},
+		{name: "Exact tag", version: "v1.1.0", wantRef: "refs/tags/v1.1.0", wantHash: second},
+		{name: "Relative to a tag", version: "v1.1.0~1", wantRef: "refs/tags/v1.1.0", wantHash: first},
+		{name: "Branch", version: "master", wantRef: "refs/heads/master", wantHash: third, wantFloating: true},
+		{name: "Relative to HEAD", version: "HEAD~1", wantRef: "refs/heads/master", wantHash: second, wantFloating: true},
+		{name: "Commit hash", version: first.String(), wantHash: first},
+		{name: "Unmatched", version: "v2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.ResolveSemVer(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ResolveSemVer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			var gotRef plumbing.ReferenceName
+			if got.Ref != nil {
+				gotRef = got.Ref.Name()
+			}
+			if gotRef != tt.wantRef || got.Hash != tt.wantHash || got.Floating != tt.wantFloating {
+				t.Errorf("Repository.ResolveSemVer() = %v %v %v, want %v %v %v", gotRef, got.Hash, got.Floating, tt.wantRef, tt.wantHash, tt.wantFloating)
+			}
+			if !got.Date.Equal(testrepo.Signature.When) {
+				t.Errorf("Repository.ResolveSemVer() date = %v, want %v", got.Date, testrepo.Signature.When)
+			}
+		})
+	}
+}
+
+func TestRepository_SemverTags(t *testing.T) {
+	fixture := testrepo.New(t)
+	first := fixture.Commit("First", map[string]string{"config.yml": "version: 1.0.0\n"})
+	second := fixture.Commit("Second", map[string]string{"config.yml": "version: 1.1.0\n"})
+	fixture.Tag("v1.10.0", second)
+	fixture.Tag("v1.2.0", second)
+	fixture.AnnotatedTag("v1.0.0", first, plumbing.CommitObject, "Release 1.0.0\n")
+	fixture.Tag("v0.1.0", first)
+	fixture.Tag("stable", first)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.SemverTags() error = %v", err)
+	}
+	r.RefFilter, err = NewRefFilter(nil, []string{"v0.*"})
+	if err != nil {
+		t.Fatalf("NewRefFilter() error = %v", err)
+	}
+
+	got, err := r.SemverTags()
+	if err != nil {
+		t.Fatalf("Repository.SemverTags() error = %v", err)
+	}
+
+	var gotNames []string
+	for _, sr := range got {
+		gotNames = append(gotNames, sr.Ref.Name().Short())
+	}
+	want := []string{"v1.0.0", "v1.2.0", "v1.10.0"}
+	if !reflect.DeepEqual(gotNames, want) {
+		t.Errorf("Repository.SemverTags() = %v, want %v", gotNames, want)
+	}
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"fmt"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/storage"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// diskStorage stores the objects of a repo on disk, and its references and
+// config in memory. Fetching into a copy of it adds packfiles next to the
+// existing ones on disk but leaves the references read by repos using it
+// untouched. The references are saved to disk after a fetch so they're found
+// by the next PlainOpenBare.
+type diskStorage struct {
+	*filesystem.ObjectStorage
+	memory.ReferenceStorage
+	memory.ConfigStorage
+	memory.ShallowStorage
+	memory.IndexStorage
+	memory.ModuleStorage
+	// disk is the storage of the repo on disk.
+	disk *filesystem.Storage
+}
+
+// newDiskStorage returns a diskStorage of the objects in disk with a copy of
+// the references and config of from.
+func newDiskStorage(disk *filesystem.Storage, from storage.Storer) (*diskStorage, error) {
+	// A new filesystem storage has its own index of the packfiles, so
+	// packfiles fetched into it aren't added to the index of from
+	objects, err := filesystem.NewStorage(disk.Filesystem())
+	if err != nil {
+		return nil, err
+	}
+
+	s := &diskStorage{
+		ObjectStorage:    &objects.ObjectStorage,
+		ReferenceStorage: memory.ReferenceStorage{},
+		ModuleStorage:    memory.ModuleStorage{},
+		disk:             disk,
+	}
+
+	if err := copyReferences(s.ReferenceStorage, from); err != nil {
+		return nil, err
+	}
+
+	cfg, err := from.Config()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// save saves the references to disk, except those that are already saved.
+func (s *diskStorage) save() error {
+	for _, ref := range s.ReferenceStorage {
+		saved, err := s.disk.Reference(ref.Name())
+		if err == nil && saved.Strings() == ref.Strings() {
+			continue
+		}
+
+		if err := s.disk.SetReference(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyStorage returns a copy of s that can be fetched into without changing
+// s. Objects are shared rather than copied as they never change.
+func copyStorage(s storage.Storer) (storage.Storer, error) {
+	switch s := s.(type) {
+	case *memory.Storage:
+		return copyMemoryStorage(s)
+	case *diskStorage:
+		return newDiskStorage(s.disk, s)
+	default:
+		return nil, fmt.Errorf("Can't copy storage %T", s)
+	}
+}
+
+// copyMemoryStorage returns a copy of a memory storage.
+func copyMemoryStorage(s *memory.Storage) (*memory.Storage, error) {
+	c := memory.NewStorage()
+
+	for _, objects := range []struct {
+		from, to map[plumbing.Hash]plumbing.EncodedObject
+	}{
+		{s.Objects, c.Objects},
+		{s.Commits, c.Commits},
+		{s.Trees, c.Trees},
+		{s.Blobs, c.Blobs},
+		{s.Tags, c.Tags},
+	} {
+		for hash, obj := range objects.from {
+			objects.to[hash] = obj
+		}
+	}
+
+	if err := copyReferences(c.ReferenceStorage, s); err != nil {
+		return nil, err
+	}
+
+	cfg, err := s.Config()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// copyReferences copies the references of from into to.
+func copyReferences(to memory.ReferenceStorage, from storer.ReferenceStorer) error {
+	refs, err := from.IterReferences()
+	if err != nil {
+		return err
+	}
+
+	return refs.ForEach(to.SetReference)
+}
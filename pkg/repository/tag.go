@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// TagInfo describes a tag and the commit it points at.
+type TagInfo struct {
+	// Name is the short name of the tag, eg. v1.0.0.
+	Name string
+	// Annotated is true for annotated tags. Lightweight tags have no Tagger or
+	// Message.
+	Annotated bool
+	// Hash is the hash of the annotated tag object, or of the commit for
+	// lightweight tags.
+	Hash plumbing.Hash
+	// Commit is the hash of the tagged commit.
+	Commit plumbing.Hash
+	// CommitDate is the committer date of the tagged commit.
+	CommitDate time.Time
+	// Tagger is who created the annotated tag and when.
+	Tagger object.Signature
+	// Message is the message of the annotated tag.
+	Message string
+}
+
+// TagInfo returns the metadata of the tag ref. Annotated tags, including tags
+// of tags, are peeled to the commit they point at. The Tagger and Message are
+// those of the outermost tag.
+func (r *Repository) TagInfo(ref *plumbing.Reference) (*TagInfo, error) {
+	// Check if Repository is nil to avoid a panic if this function is called
+	// before repo has been cloned
+	if r.Repository == nil {
+		return nil, errors.New("Repository is nil")
+	}
+
+	if !ref.Name().IsTag() {
+		return nil, fmt.Errorf("Reference %s is not a tag", ref.Name())
+	}
+
+	info := &TagInfo{Name: ref.Name().Short(), Hash: ref.Hash()}
+
+	tag, err := r.TagObject(ref.Hash())
+	switch err {
+	case nil:
+		info.Annotated = true
+		info.Tagger = tag.Tagger
+		info.Message = tag.Message
+	case plumbing.ErrObjectNotFound:
+	default:
+		return nil, err
+	}
+
+	commit, err := r.peelToCommit(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("Peel tag %s: %s", ref.Name(), err)
+	}
+	info.Commit = commit.Hash
+	info.CommitDate = commit.Committer.When
+
+	return info, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/testrepo"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// newAnnotatedFixture returns a fixture repo where every release is an
+// annotated tag, v1.1.0 being a tag of a tag.
+func newAnnotatedFixture(t *testing.T) (*testrepo.Repo, plumbing.Hash, plumbing.Hash) {
+	fixture := testrepo.New(t)
+	first := fixture.Commit("First", map[string]string{"config.yml": "version: 1.0.0\n"})
+	second := fixture.Commit("Second", map[string]string{"config.yml": "version: 1.1.0\n"})
+
+	fixture.AnnotatedTag("v1.0.0", first, plumbing.CommitObject, "Release 1.0.0\n")
+	inner := fixture.AnnotatedTag("inner", second, plumbing.CommitObject, "Inner tag\n")
+	fixture.AnnotatedTag("v1.1.0", inner, plumbing.TagObject, "Release 1.1.0\n")
+
+	return fixture, first, second
+}
+
+func TestRepository_annotatedTags(t *testing.T) {
+	fixture, _, _ := newAnnotatedFixture(t)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("CloneBare() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		want    []byte
+	}{
+		{name: "Semver constraint", version: "~1.0", want: []byte("version: 1.0.0\n")},
+		{name: "Semver constraint matching a tag of a tag", version: "v1", want: []byte("version: 1.1.0\n")},
+		{name: "Exact tag name", version: "v1.0.0", want: []byte("version: 1.0.0\n")},
+		{name: "Exact tag of a tag name", version: "v1.1.0", want: []byte("version: 1.1.0\n")},
+		{name: "Relative to a tag of a tag", version: "v1.1.0~1", want: []byte("version: 1.0.0\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := r.FileOpenAtSemVer("config.yml", tt.version)
+			if err != nil {
+				t.Errorf("Repository.FileOpenAtSemVer() error = %v", err)
+				return
+			}
+			defer got.Close()
+
+			gotContents, _ := ioutil.ReadAll(got)
+			if !bytes.Equal(gotContents, tt.want) {
+				t.Errorf("Repository.FileOpenAtSemVer() = %q, want %q", gotContents, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_TagInfo(t *testing.T) {
+	fixture, first, second := newAnnotatedFixture(t)
+	fixture.Tag("lightweight", first)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("CloneBare() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		ref           plumbing.ReferenceName
+		wantAnnotated bool
+		wantCommit    plumbing.Hash
+		wantMessage   string
+		wantErr       bool
+	}{
+		{name: "Annotated tag", ref: "refs/tags/v1.0.0", wantAnnotated: true, wantCommit: first, wantMessage: "Release 1.0.0\n"},
+		{name: "Tag of a tag", ref: "refs/tags/v1.1.0", wantAnnotated: true, wantCommit: second, wantMessage: "Release 1.1.0\n"},
+		{name: "Lightweight tag", ref: "refs/tags/lightweight", wantAnnotated: false, wantCommit: first},
+		{name: "Branch", ref: "refs/heads/master", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := r.Reference(tt.ref, true)
+			if err != nil {
+				t.Fatalf("Repository.TagInfo() error = %v", err)
+			}
+
+			got, err := r.TagInfo(ref)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.TagInfo() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			if got.Annotated != tt.wantAnnotated || got.Commit != tt.wantCommit || got.Message != tt.wantMessage {
+				t.Errorf("Repository.TagInfo() = %+v, want annotated %v, commit %v, message %q", got, tt.wantAnnotated, tt.wantCommit, tt.wantMessage)
+			}
+			if tt.wantAnnotated && !got.Tagger.When.Equal(testrepo.Signature.When) {
+				t.Errorf("Repository.TagInfo() tagger date = %v, want %v", got.Tagger.When, testrepo.Signature.When)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// TreeEntry is a file, directory, symlink or submodule in a directory listing.
+type TreeEntry struct {
+	// Name is the path of the entry relative to the listed directory.
+	Name string `json:"name"`
+	// Type is the Git object type of the entry: blob, tree or commit for
+	// submodules.
+	Type string `json:"type"`
+	// Mode is the Git file mode of the entry in octal, eg. 100644.
+	Mode string `json:"mode"`
+	// Size is the size of blobs in bytes. Zero for trees and submodules.
+	Size int64 `json:"size"`
+	// Hash is the hash of the entry's object.
+	Hash string `json:"hash"`
+}
+
+// Dir is a directory listed at a version.
+type Dir struct {
+	// Entries are the files, directories, symlinks and submodules in the
+	// directory.
+	Entries []TreeEntry
+	// Hash is the hash of the directory's tree.
+	Hash plumbing.Hash
+	// Commit is the commit the directory was listed at.
+	Commit *object.Commit
+	// Version is the version the directory was listed at. Nil if the
+	// directory wasn't listed by resolving a version.
+	Version *Version
+}
+
+// ListTreeAtSemVer lists the directory at a given path at a given sementic
+// version matching tag or git revision, resolved as by FileOpenAtSemVer. If
+// recursive is set the entries of subdirectories are listed too, after the
+// entry of the subdirectory itself.
+func (r *Repository) ListTreeAtSemVer(dirPath string, version string, recursive bool) (*Dir, error) {
+	v, err := r.ResolveSemVer(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.ListTreeAtVersion(dirPath, v, recursive)
+}
+
+// ListTreeAtVersion lists the directory at a given path at a version resolved
+// by ResolveSemVer. Returns ErrNotDir if the path isn't a directory and
+// ErrNotExist if there's nothing at the path.
+func (r *Repository) ListTreeAtVersion(dirPath string, v *Version, recursive bool) (*Dir, error) {
+	dir, err := r.listTreeAtHash(dirPath, v.Hash, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	dir.Version = v
+	return dir, nil
+}
+
+// TreeFile is a blob read from a directory.
+type TreeFile struct {
+	TreeEntry
+	// Data is the contents of the blob.
+	Data []byte
+}
+
+// ReadTreeAtVersion reads the blobs in the directory at a given path and its
+// subdirectories at a version resolved by ResolveSemVer, in the order they're
+// listed by ListTreeAtVersion. Each blob is loaded once, so reading a
+// directory this way is cheaper than listing it and reading every file.
+func (r *Repository) ReadTreeAtVersion(dirPath string, v *Version) ([]TreeFile, error) {
+	_, tree, err := r.treeAtHash(dirPath, v.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	var files []TreeFile
+	for {
+		name, e, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if e.Mode == filemode.Dir || e.Mode == filemode.Submodule {
+			continue
+		}
+
+		blob, err := r.BlobObject(e.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("Blob object of %v: %s", e.Hash, err)
+		}
+
+		data, err := readBlob(blob)
+		if err != nil {
+			return nil, fmt.Errorf("Read blob %v: %s", e.Hash, err)
+		}
+
+		files = append(files, TreeFile{
+			TreeEntry: TreeEntry{
+				Name: name,
+				Type: plumbing.BlobObject.String(),
+				Mode: fmt.Sprintf("%06o", uint32(e.Mode)),
+				Size: blob.Size,
+				Hash: e.Hash.String(),
+			},
+			Data: data,
+		})
+	}
+
+	return files, nil
+}
+
+// readBlob reads the contents of a blob.
+func readBlob(blob *object.Blob) ([]byte, error) {
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+// listTreeAtHash lists the directory at a given path at a given commit or
+// annotated tag hash.
+func (r *Repository) listTreeAtHash(dirPath string, hash plumbing.Hash, recursive bool) (*Dir, error) {
+	commit, tree, err := r.treeAtHash(dirPath, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := &Dir{Hash: tree.Hash, Commit: commit}
+
+	if !recursive {
+		for _, e := range tree.Entries {
+			entry, err := r.treeEntry(e.Name, e)
+			if err != nil {
+				return nil, err
+			}
+			dir.Entries = append(dir.Entries, entry)
+		}
+		return dir, nil
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		name, e, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := r.treeEntry(name, e)
+		if err != nil {
+			return nil, err
+		}
+		dir.Entries = append(dir.Entries, entry)
+	}
+
+	return dir, nil
+}
+
+// treeAtHash returns the tree of the directory at a given path at a given
+// commit or annotated tag hash, and the commit.
+func (r *Repository) treeAtHash(dirPath string, hash plumbing.Hash) (*object.Commit, *object.Tree, error) {
+	commit, err := r.commitAtHash(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Tree of commit %v: %s", commit.TreeHash, err)
+	}
+
+	// If dirPath has a leading slash remove it as tree entries don't have a leading slash.
+	if path.IsAbs(dirPath) {
+		dirPath = dirPath[1:]
+	}
+
+	if dirPath != "" && dirPath != "." {
+		entry, err := tree.FindEntry(dirPath)
+		if err == object.ErrEntryNotFound || err == object.ErrDirectoryNotFound {
+			return nil, nil, ErrNotExist
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("Path in tree %s: %s", dirPath, err)
+		}
+
+		if entry.Mode != filemode.Dir {
+			return nil, nil, ErrNotDir
+		}
+
+		tree, err = r.TreeObject(entry.Hash)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Tree object of %v: %s", entry.Hash, err)
+		}
+	}
+
+	return commit, tree, nil
+}
+
+// treeEntry describes a Git tree entry, looking up the size of blobs.
+func (r *Repository) treeEntry(name string, e object.TreeEntry) (TreeEntry, error) {
+	entry := TreeEntry{
+		Name: name,
+		Mode: fmt.Sprintf("%06o", uint32(e.Mode)),
+		Hash: e.Hash.String(),
+	}
+
+	switch e.Mode {
+	case filemode.Dir:
+		entry.Type = plumbing.TreeObject.String()
+	case filemode.Submodule:
+		entry.Type = plumbing.CommitObject.String()
+	default:
+		entry.Type = plumbing.BlobObject.String()
+
+		blob, err := r.BlobObject(e.Hash)
+		if err != nil {
+			return TreeEntry{}, fmt.Errorf("Blob object of %v: %s", e.Hash, err)
+		}
+		entry.Size = blob.Size
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cfg8er/cfg8er/internal/testrepo"
+)
+
+func TestRepository_ListTreeAtSemVer(t *testing.T) {
+	fixture := testrepo.New(t)
+	hash := fixture.Commit("First", map[string]string{
+		"config.yml":            "version: 1.0.0\n",
+		"roles/web/nginx.conf":  "server {}\n",
+		"roles/web/vars.yml":    "port: 80\n",
+		"roles/db/postgres.yml": "port: 5432\n",
+	})
+	fixture.Tag("v1.0.0", hash)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("CloneBare() error = %v", err)
+	}
+
+	type args struct {
+		dirPath   string
+		version   string
+		recursive bool
+	}
+	tests := []struct {
+		name     string
+		args     args
+		want     []string
+		wantType []string
+		wantErr  bool
+	}{
+		{
+			name:     "Root",
+			args:     args{dirPath: "/", version: "v1"},
+			want:     []string{"config.yml", "roles"},
+			wantType: []string{"blob", "tree"},
+		},
+		{
+			name:     "Subdirectory",
+			args:     args{dirPath: "/roles/web", version: "v1.0.0"},
+			want:     []string{"nginx.conf", "vars.yml"},
+			wantType: []string{"blob", "blob"},
+		},
+		{
+			name:     "Recursive",
+			args:     args{dirPath: "roles", version: "v1", recursive: true},
+			want:     []string{"db", "db/postgres.yml", "web", "web/nginx.conf", "web/vars.yml"},
+			wantType: []string{"tree", "blob", "tree", "blob", "blob"},
+		},
+		{
+			name:    "File",
+			args:    args{dirPath: "/config.yml", version: "v1"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing directory",
+			args:    args{dirPath: "/roles/missing", version: "v1"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing version",
+			args:    args{dirPath: "/", version: "v2"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.ListTreeAtSemVer(tt.args.dirPath, tt.args.version, tt.args.recursive)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.ListTreeAtSemVer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			var gotNames, gotTypes []string
+			for _, entry := range got.Entries {
+				gotNames = append(gotNames, entry.Name)
+				gotTypes = append(gotTypes, entry.Type)
+			}
+			if !reflect.DeepEqual(gotNames, tt.want) || !reflect.DeepEqual(gotTypes, tt.wantType) {
+				t.Errorf("Repository.ListTreeAtSemVer() = %v %v, want %v %v", gotNames, gotTypes, tt.want, tt.wantType)
+			}
+		})
+	}
+
+	got, err := r.ListTreeAtSemVer("/roles/web", "v1", false)
+	if err != nil {
+		t.Fatalf("Repository.ListTreeAtSemVer() error = %v", err)
+	}
+	want := TreeEntry{Name: "vars.yml", Type: "blob", Mode: "100644", Size: 9, Hash: got.Entries[1].Hash}
+	if got.Entries[1] != want || len(want.Hash) != 40 {
+		t.Errorf("Repository.ListTreeAtSemVer() = %+v, want %+v", got.Entries[1], want)
+	}
+	if got.Commit.Hash != hash || got.Hash.IsZero() {
+		t.Errorf("Repository.ListTreeAtSemVer() commit = %v, tree = %v, want commit %v", got.Commit.Hash, got.Hash, hash)
+	}
+
+	if _, err := r.ListTreeAtSemVer("/roles/missing", "v1", false); err != ErrNotExist {
+		t.Errorf("Repository.ListTreeAtSemVer() of missing directory error = %v, want %v", err, ErrNotExist)
+	}
+	if _, err := r.ListTreeAtSemVer("/config.yml", "v1", false); err != ErrNotDir {
+		t.Errorf("Repository.ListTreeAtSemVer() of file error = %v, want %v", err, ErrNotDir)
+	}
+}
+
+func TestRepository_FileOpenAtSemVer_dir(t *testing.T) {
+	fixture := testrepo.New(t)
+	fixture.Tag("v1.0.0", fixture.Commit("First", map[string]string{"roles/web/vars.yml": "port: 80\n"}))
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("CloneBare() error = %v", err)
+	}
+
+	for _, dirPath := range []string{"/", "/roles", "/roles/web"} {
+		if _, _, err := r.FileOpenAtSemVer(dirPath, "v1"); err != ErrIsDir {
+			t.Errorf("Repository.FileOpenAtSemVer(%q) error = %v, want %v", dirPath, err, ErrIsDir)
+		}
+	}
+}
+
+func TestRepository_ReadTreeAtVersion(t *testing.T) {
+	fixture := testrepo.New(t)
+	hash := fixture.Commit("First", map[string]string{
+		"config.yml":            "version: 1.0.0\n",
+		"roles/web/nginx.conf":  "server {}\n",
+		"roles/web/vars.yml":    "port: 80\n",
+		"roles/db/postgres.yml": "port: 5432\n",
+	})
+	fixture.Tag("v1.0.0", hash)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("CloneBare() error = %v", err)
+	}
+	v, err := r.ResolveSemVer("v1")
+	if err != nil {
+		t.Fatalf("Repository.ResolveSemVer() error = %v", err)
+	}
+
+	got, err := r.ReadTreeAtVersion("/roles", v)
+	if err != nil {
+		t.Fatalf("Repository.ReadTreeAtVersion() error = %v", err)
+	}
+
+	want := map[string]string{
+		"db/postgres.yml": "port: 5432\n",
+		"web/nginx.conf":  "server {}\n",
+		"web/vars.yml":    "port: 80\n",
+	}
+	var gotNames []string
+	for _, file := range got {
+		gotNames = append(gotNames, file.Name)
+		if string(file.Data) != want[file.Name] || file.Size != int64(len(file.Data)) || file.Type != "blob" {
+			t.Errorf("Repository.ReadTreeAtVersion() %s = %+v %q", file.Name, file.TreeEntry, file.Data)
+		}
+	}
+	if wantNames := []string{"db/postgres.yml", "web/nginx.conf", "web/vars.yml"}; !reflect.DeepEqual(gotNames, wantNames) {
+		t.Errorf("Repository.ReadTreeAtVersion() = %v, want %v", gotNames, wantNames)
+	}
+
+	if _, err := r.ReadTreeAtVersion("/roles/missing", v); err == nil {
+		t.Errorf("Repository.ReadTreeAtVersion() of missing directory error = nil, want error")
+	}
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+const beginPGPSignature = "-----BEGIN PGP SIGNATURE-----"
+
+var (
+	// ErrUnsigned is returned when verifying a tag or commit without a signature.
+	// Lightweight tags are never signed.
+	ErrUnsigned = errors.New("Object is not signed")
+	// ErrUntrustedKey is returned when a tag or commit has a valid signature by a
+	// key in the keyring that isn't one of the allowed key IDs.
+	ErrUntrustedKey = errors.New("Signing key is not allowed")
+)
+
+// Verifier checks the PGP signatures of annotated tags and commits against a
+// keyring, only accepting signatures made by allowed keys. The result of
+// checking each object is cached by its hash, as objects never change.
+type Verifier struct {
+	// Tags enables verification of annotated tag signatures.
+	Tags bool
+	// Commits enables verification of commit signatures.
+	Commits bool
+
+	keyRing  openpgp.EntityList
+	allowIDs []string
+
+	mu      sync.Mutex
+	results map[plumbing.Hash]error
+}
+
+// NewVerifier reads the armored keyring from r. allowIDs are the hex key IDs or
+// fingerprints, eg. 29DF880B, of the keys whose signatures are accepted. Allowing
+// a primary key also allows its subkeys. If allowIDs is empty every key in the
+// keyring is allowed.
+func NewVerifier(armoredKeyRing io.Reader, allowIDs []string, tags bool, commits bool) (*Verifier, error) {
+	keyRing, err := openpgp.ReadArmoredKeyRing(armoredKeyRing)
+	if err != nil {
+		return nil, fmt.Errorf("Reading armored keyring: %s", err)
+	}
+
+	v := &Verifier{Tags: tags, Commits: commits, keyRing: keyRing, results: map[plumbing.Hash]error{}}
+	for _, id := range allowIDs {
+		id = strings.ToUpper(strings.Replace(strings.TrimPrefix(id, "0x"), " ", "", -1))
+		v.allowIDs = append(v.allowIDs, id)
+	}
+
+	return v, nil
+}
+
+// VerifyObject checks the signature of an encoded annotated tag or commit
+// object. The signed payload is taken from the raw object rather than from
+// re-encoding a decoded object.Tag or object.Commit, so headers and message
+// formatting that go-git doesn't round trip don't invalidate signatures.
+func (v *Verifier) VerifyObject(o plumbing.EncodedObject) error {
+	v.mu.Lock()
+	err, ok := v.results[o.Hash()]
+	v.mu.Unlock()
+	if ok {
+		return err
+	}
+
+	err = v.verifyObject(o)
+
+	v.mu.Lock()
+	v.results[o.Hash()] = err
+	v.mu.Unlock()
+
+	return err
+}
+
+// verifyObject checks the signature of an object as VerifyObject, without
+// caching the result.
+func (v *Verifier) verifyObject(o plumbing.EncodedObject) error {
+	reader, err := o.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	var payload, armoredSignature []byte
+	switch o.Type() {
+	case plumbing.TagObject:
+		payload, armoredSignature = splitTagSignature(data)
+	case plumbing.CommitObject:
+		payload, armoredSignature = splitCommitSignature(data)
+	default:
+		return fmt.Errorf("Unable to verify %s object", o.Type())
+	}
+
+	if len(armoredSignature) == 0 {
+		return ErrUnsigned
+	}
+
+	signer, err := openpgp.CheckArmoredDetachedSignature(v.keyRing, bytes.NewReader(payload), bytes.NewReader(armoredSignature))
+	if err != nil {
+		return err
+	}
+
+	issuer, err := signatureIssuer(armoredSignature)
+	if err != nil {
+		return err
+	}
+
+	if !v.allowed(signer, issuer) {
+		return ErrUntrustedKey
+	}
+	return nil
+}
+
+// splitTagSignature splits a raw tag object into the signed payload and the
+// armored signature appended to the message.
+func splitTagSignature(data []byte) ([]byte, []byte) {
+	i := bytes.Index(data, []byte("\n"+beginPGPSignature))
+	if i < 0 {
+		return data, nil
+	}
+	return data[:i+1], data[i+1:]
+}
+
+// splitCommitSignature splits a raw commit object into the signed payload and
+// the armored signature held in the multi-line gpgsig header.
+func splitCommitSignature(data []byte) ([]byte, []byte) {
+	payload := &bytes.Buffer{}
+	signature := &bytes.Buffer{}
+
+	inHeader, inSignature := true, false
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		switch {
+		case !inHeader:
+			payload.Write(line)
+		case inSignature && bytes.HasPrefix(line, []byte(" ")):
+			signature.Write(line[1:])
+		case bytes.HasPrefix(line, []byte("gpgsig ")):
+			inSignature = true
+			signature.Write(line[len("gpgsig "):])
+		default:
+			inSignature = false
+			if len(bytes.TrimSpace(line)) == 0 {
+				inHeader = false
+			}
+			payload.Write(line)
+		}
+	}
+
+	return payload.Bytes(), signature.Bytes()
+}
+
+// allowed reports whether the signing key with ID issuer, or the primary key of
+// signer, is one of the allowed key IDs.
+func (v *Verifier) allowed(signer *openpgp.Entity, issuer uint64) bool {
+	if len(v.allowIDs) == 0 {
+		return true
+	}
+
+	fingerprints := []string{fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint)}
+	for _, key := range v.keyRing.KeysById(issuer) {
+		fingerprints = append(fingerprints, fmt.Sprintf("%X", key.PublicKey.Fingerprint))
+	}
+
+	for _, id := range v.allowIDs {
+		for _, fp := range fingerprints {
+			if len(id) >= 8 && strings.HasSuffix(fp, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// signatureIssuer returns the ID of the key that made the armored signature.
+func signatureIssuer(armoredSignature []byte) (uint64, error) {
+	block, err := armor.Decode(bytes.NewReader(armoredSignature))
+	if err != nil {
+		return 0, err
+	}
+	defer io.Copy(ioutil.Discard, block.Body)
+
+	p, err := packet.Read(block.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	switch sig := p.(type) {
+	case *packet.Signature:
+		if sig.IssuerKeyId != nil {
+			return *sig.IssuerKeyId, nil
+		}
+	case *packet.SignatureV3:
+		return sig.IssuerKeyId, nil
+	}
+	return 0, errors.New("Signature has no issuer key ID")
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/cfg8er/cfg8er/internal/testrepo"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestNewVerifier(t *testing.T) {
+	trusted := testrepo.NewKey(t, "trusted")
+
+	tests := []struct {
+		name    string
+		keyRing string
+		wantErr bool
+	}{
+		{
+			name:    "Armored keyring",
+			keyRing: testrepo.ArmoredKeyRing(t, trusted),
+			wantErr: false,
+		},
+		{
+			name:    "Not a keyring",
+			keyRing: "not a keyring",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(strings.NewReader(tt.keyRing), nil, true, true)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewVerifier() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_FindSemverTag_verified(t *testing.T) {
+	trusted := testrepo.NewKey(t, "trusted")
+	untrusted := testrepo.NewKey(t, "untrusted")
+	unknown := testrepo.NewKey(t, "unknown")
+
+	fixture := testrepo.New(t)
+	fixture.SignKey = trusted
+	signedCommit := fixture.Commit("Signed commit", map[string]string{"config.yml": "signed: true\n"})
+	fixture.SignKey = nil
+	unsignedCommit := fixture.Commit("Unsigned commit", map[string]string{"config.yml": "signed: false\n"})
+
+	fixture.SignKey = trusted
+	trustedSignedCommit := fixture.AnnotatedTag("v1.0.0", signedCommit, plumbing.CommitObject, "Trusted tag of a signed commit\n")
+	trustedUnsignedCommit := fixture.AnnotatedTag("v1.1.0", unsignedCommit, plumbing.CommitObject, "Trusted tag of an unsigned commit\n")
+	fixture.SignKey = untrusted
+	fixture.AnnotatedTag("v1.2.0", signedCommit, plumbing.CommitObject, "Untrusted tag\n")
+	fixture.SignKey = unknown
+	fixture.AnnotatedTag("v1.3.0", signedCommit, plumbing.CommitObject, "Tag by a key not in the keyring\n")
+	fixture.SignKey = nil
+	fixture.AnnotatedTag("v1.4.0", signedCommit, plumbing.CommitObject, "Unsigned tag\n")
+	fixture.Tag("v1.5.0", signedCommit)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.FindSemverTag() error = %v", err)
+	}
+
+	keyRing := testrepo.ArmoredKeyRing(t, trusted, untrusted)
+	allowIDs := []string{trusted.PrimaryKey.KeyIdShortString()}
+
+	oneMajor, _ := semver.NewConstraint("^1")
+	oneOne, _ := semver.NewConstraint("1.1.0")
+
+	tests := []struct {
+		name       string
+		verifyTag  bool
+		verifyComm bool
+		constraint *semver.Constraints
+		wantHash   plumbing.Hash
+		wantErr    bool
+	}{
+		{
+			name:       "No verification uses the highest tag",
+			constraint: oneMajor,
+			wantHash:   signedCommit,
+		},
+		{
+			name:       "Tag verification drops lightweight, unsigned and untrusted tags",
+			verifyTag:  true,
+			constraint: oneMajor,
+			wantHash:   trustedUnsignedCommit,
+		},
+		{
+			name:       "Tag and commit verification drops tags of unsigned commits",
+			verifyTag:  true,
+			verifyComm: true,
+			constraint: oneMajor,
+			wantHash:   trustedSignedCommit,
+		},
+		{
+			name:       "Commit verification only keeps the lightweight tag of a signed commit",
+			verifyComm: true,
+			constraint: oneMajor,
+			wantHash:   signedCommit,
+		},
+		{
+			name:       "No verified tag matches",
+			verifyTag:  true,
+			verifyComm: true,
+			constraint: oneOne,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := r
+			if tt.verifyTag || tt.verifyComm {
+				repo.Verifier, err = NewVerifier(strings.NewReader(keyRing), allowIDs, tt.verifyTag, tt.verifyComm)
+				if err != nil {
+					t.Fatalf("Repository.FindSemverTag() error = %v", err)
+				}
+			}
+
+			got, err := repo.FindSemverTag(tt.constraint)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("Repository.FindSemverTag() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if got.Hash() != tt.wantHash {
+				t.Errorf("Repository.FindSemverTag() = %v, want %v", got, tt.wantHash)
+			}
+		})
+	}
+}
+
+func TestRepository_FindSemverTag_verifiedTagOfTag(t *testing.T) {
+	trusted := testrepo.NewKey(t, "trusted")
+	untrusted := testrepo.NewKey(t, "untrusted")
+
+	fixture := testrepo.New(t)
+	commit := fixture.Commit("Commit", map[string]string{"config.yml": "tagged: true\n"})
+
+	fixture.SignKey = trusted
+	trustedTag := fixture.AnnotatedTag("trusted", commit, plumbing.CommitObject, "Trusted tag\n")
+	fixture.SignKey = untrusted
+	untrustedTag := fixture.AnnotatedTag("untrusted", commit, plumbing.CommitObject, "Untrusted tag\n")
+	fixture.SignKey = trusted
+	trustedOfTrusted := fixture.AnnotatedTag("v1.0.0", trustedTag, plumbing.TagObject, "Trusted tag of a trusted tag\n")
+	fixture.AnnotatedTag("v1.1.0", untrustedTag, plumbing.TagObject, "Trusted tag of an untrusted tag\n")
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.FindSemverTag() error = %v", err)
+	}
+
+	r.Verifier, err = NewVerifier(strings.NewReader(testrepo.ArmoredKeyRing(t, trusted, untrusted)), []string{trusted.PrimaryKey.KeyIdShortString()}, true, false)
+	if err != nil {
+		t.Fatalf("Repository.FindSemverTag() error = %v", err)
+	}
+
+	oneMajor, _ := semver.NewConstraint("^1")
+	got, err := r.FindSemverTag(oneMajor)
+	if err != nil {
+		t.Fatalf("Repository.FindSemverTag() error = %v", err)
+	}
+	if got.Hash() != trustedOfTrusted {
+		t.Errorf("Repository.FindSemverTag() = %v, want %v", got, trustedOfTrusted)
+	}
+}
+
+func TestVerifier_VerifyObject_cached(t *testing.T) {
+	trusted := testrepo.NewKey(t, "trusted")
+
+	fixture := testrepo.New(t)
+	commit := fixture.Commit("Commit", map[string]string{"config.yml": "tagged: true\n"})
+	fixture.SignKey = trusted
+	tag := fixture.AnnotatedTag("v1.0.0", commit, plumbing.CommitObject, "Trusted tag\n")
+
+	obj, err := fixture.Repo.Storer.EncodedObject(plumbing.TagObject, tag)
+	if err != nil {
+		t.Fatalf("Verifier.VerifyObject() error = %v", err)
+	}
+
+	v, err := NewVerifier(strings.NewReader(testrepo.ArmoredKeyRing(t, trusted)), nil, true, false)
+	if err != nil {
+		t.Fatalf("Verifier.VerifyObject() error = %v", err)
+	}
+	if err := v.VerifyObject(obj); err != nil {
+		t.Fatalf("Verifier.VerifyObject() error = %v", err)
+	}
+
+	// The signature isn't checked again, so the result doesn't depend on the keyring
+	v.keyRing = nil
+	if err := v.VerifyObject(obj); err != nil {
+		t.Errorf("Verifier.VerifyObject() error = %v, want cached result", err)
+	}
+}
+
+func TestRepository_FileOpenAtRev_verified(t *testing.T) {
+	trusted := testrepo.NewKey(t, "trusted")
+
+	fixture := testrepo.New(t)
+	fixture.SignKey = trusted
+	fixture.Commit("Signed commit", map[string]string{"config.yml": "signed: true\n"})
+	fixture.SignKey = nil
+	unsignedCommit := fixture.Commit("Unsigned commit", map[string]string{"config.yml": "signed: false\n"})
+	fixture.Tag("unsigned", unsignedCommit)
+
+	r, err := CloneBare(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Repository.FileOpenAtRev() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		verifyTag bool
+		rev       string
+		wantErr   bool
+	}{
+		{name: "Signed commit", rev: "master~1", wantErr: false},
+		{name: "Unsigned commit", rev: "master", wantErr: true},
+		{name: "Unsigned lightweight tag", verifyTag: true, rev: "unsigned", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := r
+			repo.Verifier, err = NewVerifier(strings.NewReader(testrepo.ArmoredKeyRing(t, trusted)), nil, tt.verifyTag, !tt.verifyTag)
+			if err != nil {
+				t.Fatalf("Repository.FileOpenAtRev() error = %v", err)
+			}
+
+			got, _, err := repo.FileOpenAtRev("config.yml", plumbing.Revision(tt.rev))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Repository.FileOpenAtRev() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != nil {
+				got.Close()
+			}
+		})
+	}
+}